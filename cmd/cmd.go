@@ -0,0 +1,51 @@
+// commands which run once and exit, instead of starting sync service
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/util/helper"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var (
+	commands = make(map[string]command)
+)
+
+func register(c command) {
+	commands[c.name] = c
+}
+
+// whether name is a registered command
+func Exist(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// run command with it's args, db is opened before command runs and closed after
+func Run(name string, args []string) error {
+	c, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %v", name)
+	}
+
+	store.Start()
+	defer func() {
+		helper.ClosePool()
+		store.Stop()
+	}()
+
+	logger.Info("run command", logger.String("command", c.name), logger.Any("args", args))
+	if err := c.run(args); err != nil {
+		return fmt.Errorf("%v: %v\nusage: %v", c.name, err, c.usage)
+	}
+	return nil
+}
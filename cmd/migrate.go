@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/irisnet/irishub-sync/store/migration"
+)
+
+func init() {
+	register(command{
+		name:  "migrate",
+		usage: "migrate [-to <version>] [-status]",
+		run:   migrate,
+	})
+}
+
+// apply pending migrations, or print status of migrations when -status is set
+func migrate(args []string) error {
+	var (
+		to     int
+		status bool
+	)
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.IntVar(&to, "to", 0, "max version of migrations to apply, 0 means all")
+	fs.BoolVar(&status, "status", false, "print status of migrations instead of applying them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if !status {
+		return migration.Run(to)
+	}
+
+	list, err := migration.Migrations()
+	if err != nil {
+		return err
+	}
+	applied, err := migration.Applied()
+	if err != nil {
+		return err
+	}
+	for _, m := range list {
+		state := "pending"
+		if r, ok := applied[m.Version]; ok {
+			state = "applied at " + time.Unix(r.AppliedAt, 0).Format(time.RFC3339)
+		}
+		fmt.Printf("%4d  %-32s %v\n", m.Version, m.Name, state)
+	}
+	return nil
+}
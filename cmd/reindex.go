@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"flag"
+
+	"github.com/irisnet/irishub-sync/service/task"
+)
+
+func init() {
+	register(command{
+		name:  "reindex",
+		usage: "reindex -from <height> -to <height>",
+		run:   reindex,
+	})
+}
+
+// remove synced documents in [from, to] and create sync tasks to re-sync them
+func reindex(args []string) error {
+	var (
+		from, to int64
+	)
+
+	fs := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	fs.Int64Var(&from, "from", 0, "start height of range, included")
+	fs.Int64Var(&to, "to", 0, "end height of range, included")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	return task.Reindex(task.DefaultEnv(), from, to)
+}
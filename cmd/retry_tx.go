@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/irisnet/irishub-sync/service/task"
+)
+
+func init() {
+	register(command{
+		name:  "retry-tx",
+		usage: "retry-tx [-from <height>] [-to <height>]",
+		run:   retryTx,
+	})
+}
+
+// decode txs which couldn't be decoded during sync again, it's run after newer codec is deployed
+func retryTx(args []string) error {
+	var (
+		from, to int64
+	)
+
+	fs := flag.NewFlagSet("retry-tx", flag.ContinueOnError)
+	fs.Int64Var(&from, "from", 1, "start height of range, included")
+	fs.Int64Var(&to, "to", 0, "end height of range, included, 0 means latest")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if from <= 0 || (to > 0 && to < from) {
+		return fmt.Errorf("invalid range [%v, %v]", from, to)
+	}
+
+	// txs are decoded by codec of protocol version at their heights, which is switched at software upgrades
+	env := task.DefaultEnv()
+	if err := task.LoadCodecSchedule(env); err != nil {
+		return err
+	}
+	decoded, failed, err := task.RetryUndecodedTxs(env, from-1, to)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("decoded: %v, failed: %v\n", decoded, failed)
+	return nil
+}
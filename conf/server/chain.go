@@ -0,0 +1,121 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/util/constant"
+)
+
+var (
+	// file of chain confs, when it's set, every chain in file is synced by it's own sync process
+	ChainConfFile = ""
+)
+
+// conf of one chain which is synced in same deployment with other chains.
+// documents of chain are stored in it's own database, so they are namespaced by chain.
+type ChainConf struct {
+	ChainId  string            `json:"chain_id"`
+	Network  string            `json:"network"`
+	FullNode string            `json:"full_node"` // e.g. tcp://127.0.0.1:26657,tcp://127.0.0.2:26657
+	Database string            `json:"database"`  // default is sync-<chain_id>
+	LogFile  string            `json:"log_file"`  // default is log file of current process with suffix -<chain_id>
+	Env      map[string]string `json:"env"`       // other env vars of chain, e.g. WORKER_NUM_EXECUTE_TASK
+}
+
+func init() {
+	chainConfFile, found := os.LookupEnv(constant.EnvNameChainConfFile)
+	if found {
+		ChainConfFile = chainConfFile
+	}
+	logger.Info("Env Value", logger.String(constant.EnvNameChainConfFile, ChainConfFile))
+}
+
+// load and validate chain confs from chain conf file
+func LoadChainConfs() ([]ChainConf, error) {
+	var chains []ChainConf
+
+	data, err := ioutil.ReadFile(ChainConfFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &chains); err != nil {
+		return nil, err
+	}
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("no chain in chain conf file %v", ChainConfFile)
+	}
+
+	chainIds := make(map[string]bool, len(chains))
+	databases := make(map[string]bool, len(chains))
+	logFiles := make(map[string]bool, len(chains))
+	for i := range chains {
+		chain := &chains[i]
+		if chain.ChainId == "" || chain.FullNode == "" {
+			return nil, fmt.Errorf("chain_id and full_node of chain should not be empty")
+		}
+		if chain.Database == "" {
+			chain.Database = fmt.Sprintf("sync-%v", chain.ChainId)
+		}
+		if chain.LogFile == "" {
+			chain.LogFile = chainLogFile(logger.FileName(), chain.ChainId)
+		}
+		if chainIds[chain.ChainId] {
+			return nil, fmt.Errorf("duplicate chain %v", chain.ChainId)
+		}
+		if databases[chain.Database] {
+			return nil, fmt.Errorf("database %v is used by more than one chain", chain.Database)
+		}
+		if logFiles[chain.LogFile] {
+			return nil, fmt.Errorf("log file %v is used by more than one chain", chain.LogFile)
+		}
+		chainIds[chain.ChainId] = true
+		databases[chain.Database] = true
+		logFiles[chain.LogFile] = true
+	}
+
+	return chains, nil
+}
+
+// env vars of sync process of chain, they override env vars of current process
+func (c ChainConf) Environ() []string {
+	env := map[string]string{
+		constant.EnvNameSerNetworkChainId:  c.ChainId,
+		constant.EnvNameSerNetworkFullNode: c.FullNode,
+		constant.EnvNameDbDataBase:         c.Database,
+	}
+	if c.Network != "" {
+		env[constant.EnvNameNetwork] = c.Network
+	}
+	if c.LogFile != "" {
+		env[constant.EnvLogFileName] = c.LogFile
+	}
+	for k, v := range c.Env {
+		env[k] = v
+	}
+
+	var environ []string
+	for _, kv := range os.Environ() {
+		key := strings.SplitN(kv, "=", 2)[0]
+		// sync process of chain shouldn't start sync processes again
+		if _, ok := env[key]; ok || key == constant.EnvNameChainConfFile {
+			continue
+		}
+		environ = append(environ, kv)
+	}
+	for k, v := range env {
+		environ = append(environ, fmt.Sprintf("%v=%v", k, v))
+	}
+	return environ
+}
+
+// log file of chain is in same dir with log file of current process, e.g. sync_server-irishub.log
+func chainLogFile(fileName, chainId string) string {
+	ext := filepath.Ext(fileName)
+	return fmt.Sprintf("%v-%v%v", strings.TrimSuffix(fileName, ext), chainId, ext)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/irisnet/irishub-sync/util/constant"
+)
+
+func TestLoadChainConfs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chains")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "default database and log file",
+			content: `[{"chain_id": "a", "full_node": "tcp://a:26657"},
+				{"chain_id": "b", "full_node": "tcp://b:26657", "database": "db-b", "log_file": "/var/log/b.log"}]`,
+		},
+		{name: "no chain", content: `[]`, wantErr: true},
+		{name: "duplicate chain", content: `[{"chain_id": "a", "full_node": "n"}, {"chain_id": "a", "full_node": "n"}]`, wantErr: true},
+		{
+			name:    "duplicate log file",
+			content: `[{"chain_id": "a", "full_node": "n", "log_file": "x.log"}, {"chain_id": "b", "full_node": "n", "log_file": "x.log"}]`,
+			wantErr: true,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ChainConfFile = filepath.Join(dir, fmt.Sprintf("%d.json", i))
+			if err := ioutil.WriteFile(ChainConfFile, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			chains, err := LoadChainConfs()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadChainConfs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if chains[0].Database != "sync-a" || !strings.HasSuffix(chains[0].LogFile, "-a.log") {
+				t.Errorf("chain a = %+v", chains[0])
+			}
+			if chains[1].Database != "db-b" || chains[1].LogFile != "/var/log/b.log" {
+				t.Errorf("chain b = %+v", chains[1])
+			}
+		})
+	}
+}
+
+func TestChainConf_Environ(t *testing.T) {
+	os.Setenv(constant.EnvNameChainConfFile, "chains.json")
+	os.Setenv(constant.EnvNameSerNetworkChainId, "parent")
+	os.Setenv(constant.EnvNameWorkerNumExecuteTask, "30")
+	defer func() {
+		os.Unsetenv(constant.EnvNameChainConfFile)
+		os.Unsetenv(constant.EnvNameSerNetworkChainId)
+		os.Unsetenv(constant.EnvNameWorkerNumExecuteTask)
+	}()
+
+	chain := ChainConf{
+		ChainId:  "a",
+		FullNode: "tcp://a:26657",
+		Database: "sync-a",
+		LogFile:  "/var/log/sync-a.log",
+		Env:      map[string]string{constant.EnvNameWorkerNumExecuteTask: "10"},
+	}
+	env := make(map[string][]string)
+	for _, kv := range chain.Environ() {
+		pair := strings.SplitN(kv, "=", 2)
+		env[pair[0]] = append(env[pair[0]], pair[1])
+	}
+
+	want := map[string]string{
+		constant.EnvNameSerNetworkChainId:    "a",
+		constant.EnvNameSerNetworkFullNode:   "tcp://a:26657",
+		constant.EnvNameDbDataBase:           "sync-a",
+		constant.EnvLogFileName:              "/var/log/sync-a.log",
+		constant.EnvNameWorkerNumExecuteTask: "10",
+	}
+	for k, v := range want {
+		if len(env[k]) != 1 || env[k][0] != v {
+			t.Errorf("env %v = %v, want %v", k, env[k], v)
+		}
+	}
+	// sync process of chain shouldn't start sync processes again
+	if _, ok := env[constant.EnvNameChainConfFile]; ok {
+		t.Errorf("env %v is passed to sync process of chain", constant.EnvNameChainConfFile)
+	}
+}
+
+func Test_chainLogFile(t *testing.T) {
+	if got := chainLogFile("/log/sync_server.log", "irishub"); got != "/log/sync_server-irishub.log" {
+		t.Errorf("chainLogFile() = %v", got)
+	}
+	if got := chainLogFile("sync", "irishub"); got != "sync-irishub" {
+		t.Errorf("chainLogFile() = %v", got)
+	}
+}
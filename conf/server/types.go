@@ -24,6 +24,19 @@ var (
 	SyncProposalStatus       = "0 */1 * * * *" // every minute
 	CronSaveValidatorHistory = "@daily"        // every day
 	CronUpdateDelegator      = "0/5 * * * * *" // every ten minute
+	CronAuditSyncGap         = "0 0 */1 * * *" // every hour
+	CronArchiveBlock         = "0 30 * * * *"  // every hour
+
+	// blocks which are older than latest ArchiveKeepBlocks blocks or ArchiveKeepDays days are archived,
+	// 0 means the policy is disabled, blocks are never archived when both of them are 0
+	ArchiveKeepBlocks = int64(0)
+	ArchiveKeepDays   = 0
+
+	// whether precommits of block are stored as signer bitmap of validator set instead of votes
+	CompactPrecommits = false
+
+	// codec of each protocol version and height which it's used from, e.g. "1:latest" or "1:v0"
+	CodecSchedule = ""
 
 	// deprecated
 	SyncMaxGoroutine = 60 // max go routine in server
@@ -92,6 +105,42 @@ func init() {
 	}
 	logger.Info("Env Value", logger.Int(constant.EnvNameWorkerNumExecuteTask, WorkerNumExecuteTask))
 
+	archiveKeepBlocks, found := os.LookupEnv(constant.EnvNameArchiveKeepBlocks)
+	if found {
+		var err error
+		ArchiveKeepBlocks, err = strconv.ParseInt(archiveKeepBlocks, 10, 64)
+		if err != nil {
+			logger.Fatal("Can't convert str to int", logger.String(constant.EnvNameArchiveKeepBlocks, archiveKeepBlocks))
+		}
+	}
+	logger.Info("Env Value", logger.Int64(constant.EnvNameArchiveKeepBlocks, ArchiveKeepBlocks))
+
+	archiveKeepDays, found := os.LookupEnv(constant.EnvNameArchiveKeepDays)
+	if found {
+		var err error
+		ArchiveKeepDays, err = strconv.Atoi(archiveKeepDays)
+		if err != nil {
+			logger.Fatal("Can't convert str to int", logger.String(constant.EnvNameArchiveKeepDays, archiveKeepDays))
+		}
+	}
+	logger.Info("Env Value", logger.Int(constant.EnvNameArchiveKeepDays, ArchiveKeepDays))
+
+	compactPrecommits, found := os.LookupEnv(constant.EnvNameCompactPrecommits)
+	if found {
+		flag, err := strconv.ParseBool(compactPrecommits)
+		if err != nil {
+			logger.Fatal("Env Value", logger.String(constant.EnvNameCompactPrecommits, compactPrecommits))
+		}
+		CompactPrecommits = flag
+	}
+	logger.Info("Env Value", logger.Bool(constant.EnvNameCompactPrecommits, CompactPrecommits))
+
+	codecSchedule, found := os.LookupEnv(constant.EnvNameCodecSchedule)
+	if found {
+		CodecSchedule = codecSchedule
+	}
+	logger.Info("Env Value", logger.String(constant.EnvNameCodecSchedule, CodecSchedule))
+
 	network, found := os.LookupEnv(constant.EnvNameNetwork)
 	if found {
 		Network = network
@@ -24,6 +24,11 @@ var (
 	}
 )
 
+// file name of log of current process
+func FileName() string {
+	return conf.Filename
+}
+
 func init() {
 	fileName, found := os.LookupEnv(constant.EnvLogFileName)
 	if found {
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"github.com/irisnet/irishub-sync/cmd"
+	dbConf "github.com/irisnet/irishub-sync/conf/db"
+	serverConf "github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/service"
+	"github.com/irisnet/irishub-sync/service/task"
 	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/migration"
 	"github.com/irisnet/irishub-sync/util/helper"
 	"os"
 	"os/signal"
@@ -11,12 +16,31 @@ import (
 )
 
 func main() {
-	c := make(chan os.Signal)
-	engine := service.New()
+	// run command and exit, e.g. irishub-sync reindex -from 1 -to 100
+	if len(os.Args) > 1 && cmd.Exist(os.Args[1]) {
+		err := cmd.Run(os.Args[1], os.Args[2:])
+		logger.Sync()
+		if err != nil {
+			logger.Error("run command fail", logger.String("err", err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	// sync several chains, every chain is synced by it's own sync process
+	if serverConf.ChainConfFile != "" {
+		runChains()
+		return
+	}
+
+	c := make(chan os.Signal, 1)
+	var engine *service.SyncEngine
 
 	defer func() {
 		logger.Info("#########################System Exit##########################")
-		engine.Stop()
+		if engine != nil {
+			engine.Stop()
+		}
 		helper.ClosePool()
 		store.Stop()
 		logger.Sync()
@@ -30,9 +54,38 @@ func main() {
 	//#########################开启数据库服务##########################
 	logger.Info("#########################开启数据库服务##########################")
 	store.Start()
+	if dbConf.AutoMigrate {
+		if err := migration.Run(0); err != nil {
+			logger.Error("apply migrations fail", logger.String("err", err.Error()))
+			return
+		}
+	}
 	//#########################开启同步服务##########################
 	logger.Info("#########################开启同步服务##########################")
-	engine.Start()
+	engine = service.New(task.DefaultEnv())
+	if err := engine.Start(); err != nil {
+		logger.Error("start sync engine fail", logger.String("err", err.Error()))
+		return
+	}
 	//阻塞直至有信号传入
 	<-c
 }
+
+func runChains() {
+	defer logger.Sync()
+
+	chains, err := serverConf.LoadChainConfs()
+	if err != nil {
+		logger.Error("load chain confs fail", logger.String("err", err.Error()))
+		os.Exit(1)
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	supervisor := service.NewChainSupervisor(chains)
+	supervisor.Start()
+	<-c
+	logger.Info("#########################System Exit##########################")
+	supervisor.Stop()
+}
@@ -8,6 +8,9 @@
 //     }
 // )
 
+// collections, indexes and init data below are also created by migrations in store/migration when sync starts,
+// run `irishub-sync migrate` instead of this script on existed database
+
 // create collections
 db.createCollection("account");
 db.createCollection("block");
@@ -22,7 +25,12 @@ db.createCollection("tx_msg");
 db.createCollection("power_change");//explorer
 db.createCollection("uptime_change");
 db.createCollection("sync_conf");
+db.createCollection("sync_conf_history");
+db.createCollection("sync_gap");
+db.createCollection("sync_status");
 db.createCollection("validator_history");
+db.createCollection("validator_set");
+db.createCollection("tx_undecoded");
 db.createCollection("mgo_txn");
 db.createCollection("mgo_txn.stash");
 
@@ -39,6 +47,8 @@ db.stake_role_delegator.createIndex({"address": 1});
 db.stake_role_delegator.createIndex({"address": 1, "validator_addr": 1}, {"unique": true});
 
 db.sync_task.createIndex({"start_height": 1, "end_height": 1}, {"unique": true});
+db.sync_conf_history.createIndex({"seq": 1}, {"unique": true});
+db.sync_gap.createIndex({"start_height": 1, "end_height": 1, "type": 1}, {"unique": true});
 
 db.tx_common.createIndex({"height": -1});
 db.tx_common.createIndex({"time": -1});
@@ -47,6 +57,9 @@ db.tx_common.createIndex({"from": 1});
 db.tx_common.createIndex({"to": 1});
 db.tx_common.createIndex({"type": 1});
 db.tx_common.createIndex({"status": 1});
+db.tx_common.createIndex({"msgs.from": 1});
+db.tx_common.createIndex({"msgs.to": 1});
+db.tx_common.createIndex({"msgs.type": 1});
 
 db.power_change.createIndex({"height": 1, "address": 1}, {"unique": true});
 db.uptime_change.createIndex({"time": 1, "address": 1}, {"unique": true});
@@ -55,10 +68,17 @@ db.validator_up_time.createIndex({"val_address": 1}, {"unique": true});
 
 db.tx_gas.createIndex({"tx_type": 1}, {"unique": true});
 db.proposal.createIndex({"proposal_id": 1}, {"unique": true});
-db.tx_msg.createIndex({"hash": 1}, {"unique": true});
+// unique index of hash which is created by previous versions rejects second msg of tx
+db.tx_msg.dropIndex({"hash": 1});
+db.tx_msg.createIndex({"hash": 1, "index": 1}, {"unique": true});
+db.validator_set.createIndex({"hash": 1}, {"unique": true});
+db.tx_undecoded.createIndex({"tx_hash": 1}, {"unique": true});
+db.tx_undecoded.createIndex({"height": 1});
+db.tx_common.createIndex({"signers.address": 1});
+db.account.createIndex({"pub_key_height": 1});
 
 // init data
-db.sync_conf.insert({"block_num_per_worker_handle": 50, "max_worker_sleep_time": 120});
+db.sync_conf.insert({"block_num_per_worker_handle": 50, "max_worker_sleep_time": 120, "start_height": 0, "halt_height": 0});
 
 // drop collection
 // db.account.drop();
@@ -73,6 +93,9 @@ db.sync_conf.insert({"block_num_per_worker_handle": 50, "max_worker_sleep_time":
 // db.tx_gas.drop();
 // db.tx_msg.drop();
 // db.uptime_change.drop();
+// db.sync_conf_history.drop();
+// db.sync_gap.drop();
+// db.sync_status.drop();
 // db.mgo_txn.drop();
 // db.mgo_txn.stash.drop();
 
@@ -89,6 +112,9 @@ db.sync_conf.insert({"block_num_per_worker_handle": 50, "max_worker_sleep_time":
 // db.tx_gas.remove({});
 // db.tx_msg.remove({});
 // db.uptime_change.remove({});
+// db.sync_conf_history.remove({});
+// db.sync_gap.remove({});
+// db.sync_status.remove({});
 // db.mgo_txn.remove({});
 // db.mgo_txn.stash.remove({});
 
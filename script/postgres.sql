@@ -0,0 +1,179 @@
+-- create database and user
+-- CREATE USER iris WITH PASSWORD 'irispassword';
+-- CREATE DATABASE "sync-iris" OWNER iris;
+
+-- every collection is stored in table which has same name with it:
+--   id:   _id of document, object id is stored in hex
+--   doc:  document encoded in bson, sync reads documents from it
+--   data: document encoded in json, object id is encoded in hex and time is encoded in utc like 2006-01-02T15:04:05.000Z
+-- relational columns of block, tx_common, tx_msg, account, stake_role_delegator, stake_role_candidate and proposal
+-- are generated from data, they are read only and used by queries of explorer and reports.
+-- generated columns need postgres 12 or later
+
+-- convert time in data into timestamp, times in data always have offset so it doesn't depend on time zone setting
+CREATE OR REPLACE FUNCTION sync_time(t text) RETURNS timestamptz
+    LANGUAGE sql IMMUTABLE RETURNS NULL ON NULL INPUT
+AS $$ SELECT t::timestamptz $$;
+
+-- create tables
+CREATE TABLE IF NOT EXISTS block (
+    id text PRIMARY KEY,
+    doc bytea NOT NULL,
+    data jsonb NOT NULL,
+    height bigint GENERATED ALWAYS AS ((data ->> 'height')::bigint) STORED,
+    hash text GENERATED ALWAYS AS (data ->> 'hash') STORED,
+    time timestamptz GENERATED ALWAYS AS (sync_time(data ->> 'time')) STORED,
+    num_txs bigint GENERATED ALWAYS AS ((data ->> 'num_txs')::bigint) STORED,
+    chain_id text GENERATED ALWAYS AS (data #>> '{meta,header,chain_id}') STORED
+);
+
+CREATE TABLE IF NOT EXISTS tx_common (
+    id text PRIMARY KEY,
+    doc bytea NOT NULL,
+    data jsonb NOT NULL,
+    tx_hash text GENERATED ALWAYS AS (data ->> 'tx_hash') STORED,
+    height bigint GENERATED ALWAYS AS ((data ->> 'height')::bigint) STORED,
+    time timestamptz GENERATED ALWAYS AS (sync_time(data ->> 'time')) STORED,
+    type text GENERATED ALWAYS AS (data ->> 'type') STORED,
+    "from" text GENERATED ALWAYS AS (data ->> 'from') STORED,
+    "to" text GENERATED ALWAYS AS (data ->> 'to') STORED,
+    status text GENERATED ALWAYS AS (data ->> 'status') STORED,
+    gas_used bigint GENERATED ALWAYS AS ((data ->> 'gas_used')::bigint) STORED,
+    memo text GENERATED ALWAYS AS (data ->> 'memo') STORED,
+    amount jsonb GENERATED ALWAYS AS (data -> 'amount') STORED,
+    actual_fee jsonb GENERATED ALWAYS AS (data -> 'actual_fee') STORED
+);
+
+CREATE TABLE IF NOT EXISTS tx_msg (
+    id text PRIMARY KEY,
+    doc bytea NOT NULL,
+    data jsonb NOT NULL,
+    hash text GENERATED ALWAYS AS (data ->> 'hash') STORED,
+    index int GENERATED ALWAYS AS ((data ->> 'index')::int) STORED,
+    type text GENERATED ALWAYS AS (data ->> 'type') STORED,
+    content text GENERATED ALWAYS AS (data ->> 'content') STORED
+);
+
+CREATE TABLE IF NOT EXISTS account (
+    id text PRIMARY KEY,
+    doc bytea NOT NULL,
+    data jsonb NOT NULL,
+    address text GENERATED ALWAYS AS (data ->> 'address') STORED,
+    amount jsonb GENERATED ALWAYS AS (data -> 'amount') STORED,
+    time timestamptz GENERATED ALWAYS AS (sync_time(data ->> 'time')) STORED,
+    height bigint GENERATED ALWAYS AS ((data ->> 'height')::bigint) STORED
+);
+
+CREATE TABLE IF NOT EXISTS stake_role_delegator (
+    id text PRIMARY KEY,
+    doc bytea NOT NULL,
+    data jsonb NOT NULL,
+    address text GENERATED ALWAYS AS (data ->> 'address') STORED,
+    validator_addr text GENERATED ALWAYS AS (data ->> 'validator_addr') STORED,
+    shares double precision GENERATED ALWAYS AS ((data ->> 'shares')::double precision) STORED,
+    height bigint GENERATED ALWAYS AS ((data ->> 'height')::bigint) STORED
+);
+
+CREATE TABLE IF NOT EXISTS stake_role_candidate (
+    id text PRIMARY KEY,
+    doc bytea NOT NULL,
+    data jsonb NOT NULL,
+    address text GENERATED ALWAYS AS (data ->> 'address') STORED,
+    pub_key text GENERATED ALWAYS AS (data ->> 'pub_key') STORED,
+    jailed boolean GENERATED ALWAYS AS ((data ->> 'jailed')::boolean) STORED,
+    tokens double precision GENERATED ALWAYS AS ((data ->> 'tokens')::double precision) STORED,
+    voting_power double precision GENERATED ALWAYS AS ((data ->> 'voting_power')::double precision) STORED,
+    moniker text GENERATED ALWAYS AS (data #>> '{description,moniker}') STORED,
+    bond_height bigint GENERATED ALWAYS AS ((data ->> 'bond_height')::bigint) STORED,
+    status text GENERATED ALWAYS AS (data ->> 'status') STORED
+);
+
+CREATE TABLE IF NOT EXISTS proposal (
+    id text PRIMARY KEY,
+    doc bytea NOT NULL,
+    data jsonb NOT NULL,
+    proposal_id bigint GENERATED ALWAYS AS ((data ->> 'proposal_id')::bigint) STORED,
+    title text GENERATED ALWAYS AS (data ->> 'title') STORED,
+    type text GENERATED ALWAYS AS (data ->> 'type') STORED,
+    status text GENERATED ALWAYS AS (data ->> 'status') STORED,
+    submit_time timestamptz GENERATED ALWAYS AS (sync_time(data ->> 'submit_time')) STORED,
+    voting_end_time timestamptz GENERATED ALWAYS AS (sync_time(data ->> 'voting_end_time')) STORED,
+    total_deposit jsonb GENERATED ALWAYS AS (data -> 'total_deposit') STORED
+);
+
+CREATE TABLE IF NOT EXISTS sync_task (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS validator_up_time (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS tx_gas (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS power_change (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS uptime_change (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS sync_conf (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS sync_conf_history (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS sync_gap (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS sync_status (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS validator_history (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS validator_set (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+CREATE TABLE IF NOT EXISTS tx_undecoded (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL);
+
+-- votes of proposals, one row per vote
+CREATE OR REPLACE VIEW proposal_vote AS
+SELECT p.proposal_id,
+       v ->> 'voter'             AS voter,
+       v ->> 'option'            AS option,
+       sync_time(v ->> 'time')   AS time
+FROM proposal p
+         CROSS JOIN LATERAL jsonb_array_elements(CASE WHEN jsonb_typeof(p.data -> 'votes') = 'array' THEN p.data -> 'votes' ELSE '[]'::jsonb END) AS v;
+
+-- precommits of blocks, one row per validator which signed the block, it's used by uptime queries.
+-- precommits of blocks which are stored as signer bitmap (COMPACT_PRECOMMITS) aren't in it
+CREATE OR REPLACE VIEW block_precommit AS
+SELECT b.height,
+       v ->> 'validator_address' AS validator_address,
+       sync_time(v ->> 'timestamp') AS time
+FROM block b
+         CROSS JOIN LATERAL jsonb_array_elements(CASE WHEN jsonb_typeof(b.data #> '{block,last_commit,precommits}') = 'array'
+                                              THEN b.data #> '{block,last_commit,precommits}' ELSE '[]'::jsonb END) AS v;
+
+-- create index, unique indexes are same with them of mongodb and documents are queried by data
+CREATE UNIQUE INDEX IF NOT EXISTS account_address ON account ((data #> '{address}'));
+CREATE INDEX IF NOT EXISTS account_pub_key_height ON account ((data #> '{pub_key_height}'));
+CREATE UNIQUE INDEX IF NOT EXISTS block_height ON block ((data #> '{height}'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS stake_role_candidate_address ON stake_role_candidate ((data #> '{address}'));
+CREATE INDEX IF NOT EXISTS stake_role_candidate_pub_key ON stake_role_candidate ((data #> '{pub_key}'));
+
+CREATE INDEX IF NOT EXISTS stake_role_delegator_validator_addr ON stake_role_delegator ((data #> '{validator_addr}'));
+CREATE UNIQUE INDEX IF NOT EXISTS stake_role_delegator_address_validator_addr ON stake_role_delegator ((data #> '{address}'), (data #> '{validator_addr}'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS sync_task_start_height_end_height ON sync_task ((data #> '{start_height}'), (data #> '{end_height}'));
+CREATE UNIQUE INDEX IF NOT EXISTS sync_conf_history_seq ON sync_conf_history ((data #> '{seq}'));
+CREATE UNIQUE INDEX IF NOT EXISTS sync_gap_start_height_end_height_type ON sync_gap ((data #> '{start_height}'), (data #> '{end_height}'), (data #> '{type}'));
+
+CREATE INDEX IF NOT EXISTS tx_common_height ON tx_common ((data #> '{height}'));
+CREATE INDEX IF NOT EXISTS tx_common_time ON tx_common ((data #> '{time}'));
+CREATE UNIQUE INDEX IF NOT EXISTS tx_common_tx_hash ON tx_common ((data #> '{tx_hash}'));
+CREATE INDEX IF NOT EXISTS tx_common_from ON tx_common ((data #> '{from}'));
+CREATE INDEX IF NOT EXISTS tx_common_to ON tx_common ((data #> '{to}'));
+CREATE INDEX IF NOT EXISTS tx_common_type ON tx_common ((data #> '{type}'));
+CREATE INDEX IF NOT EXISTS tx_common_status ON tx_common ((data #> '{status}'));
+CREATE INDEX IF NOT EXISTS tx_common_signers ON tx_common USING gin ((data -> 'signers') jsonb_path_ops);
+
+CREATE UNIQUE INDEX IF NOT EXISTS power_change_height_address ON power_change ((data #> '{height}'), (data #> '{address}'));
+CREATE UNIQUE INDEX IF NOT EXISTS uptime_change_time_address ON uptime_change ((data #> '{time}'), (data #> '{address}'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS validator_up_time_val_address ON validator_up_time ((data #> '{val_address}'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS tx_gas_tx_type ON tx_gas ((data #> '{tx_type}'));
+CREATE UNIQUE INDEX IF NOT EXISTS proposal_proposal_id ON proposal ((data #> '{proposal_id}'));
+CREATE UNIQUE INDEX IF NOT EXISTS tx_msg_hash_index ON tx_msg ((data #> '{hash}'), (data #> '{index}'));
+CREATE UNIQUE INDEX IF NOT EXISTS validator_set_hash ON validator_set ((data #> '{hash}'));
+CREATE UNIQUE INDEX IF NOT EXISTS tx_undecoded_tx_hash ON tx_undecoded ((data #> '{tx_hash}'));
+CREATE INDEX IF NOT EXISTS tx_undecoded_height ON tx_undecoded ((data #> '{height}'));
+
+-- init data, doc is bson of data
+INSERT INTO sync_conf (id, doc, data)
+VALUES ('init',
+        '\x82000000025f69640005000000696e69740012626c6f636b5f6e756d5f7065725f776f726b65725f68616e646c650032000000'
+        '00000000126d61785f776f726b65725f736c6565705f74696d650078000000000000001273746172745f686569676874000000'
+        '0000000000001268616c745f68656967687400000000000000000000',
+        '{"_id": "init", "block_num_per_worker_handle": 50, "max_worker_sleep_time": 120, "start_height": 0, "halt_height": 0}')
+ON CONFLICT DO NOTHING;
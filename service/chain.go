@@ -0,0 +1,144 @@
+package service
+
+import (
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	conf "github.com/irisnet/irishub-sync/conf/server"
+	"github.com/irisnet/irishub-sync/logger"
+)
+
+const (
+	// interval of restarting sync process of chain after it exited unexpectedly,
+	// it's doubled every time process exits before it has run stableChainDuration
+	restartChainInterval    = 10 * time.Second
+	maxRestartChainInterval = 5 * time.Minute
+	stableChainDuration     = time.Minute
+)
+
+// ChainSupervisor syncs several chains in one deployment.
+// every chain is synced by it's own sync process, which has it's own engine, client pool,
+// codec, database and log file. chains aren't scoped in one process because bech32 prefixes
+// of network are process-global in irishub, addresses of mainnet and testnet can't be encoded in one process.
+// sync process is restarted when it exits unexpectedly.
+type ChainSupervisor struct {
+	chains []conf.ChainConf
+	// build command of sync process of chain
+	command         func(chain conf.ChainConf) *exec.Cmd
+	restartInterval time.Duration
+
+	mutex     sync.Mutex
+	processes map[string]*os.Process
+	stopped   bool
+	quit      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func NewChainSupervisor(chains []conf.ChainConf) *ChainSupervisor {
+	return &ChainSupervisor{
+		chains:          chains,
+		command:         chainCommand,
+		restartInterval: restartChainInterval,
+		processes:       make(map[string]*os.Process),
+		quit:            make(chan struct{}),
+	}
+}
+
+// sync process of chain is current executable which runs with env vars of chain
+func chainCommand(chain conf.ChainConf) *exec.Cmd {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = chain.Environ()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
+
+// start sync process of every chain
+func (s *ChainSupervisor) Start() {
+	for _, chain := range s.chains {
+		s.wg.Add(1)
+		go func(chain conf.ChainConf) {
+			defer s.wg.Done()
+			s.run(chain)
+		}(chain)
+	}
+}
+
+// stop sync processes gracefully, it returns after all of them exited
+func (s *ChainSupervisor) Stop() {
+	s.mutex.Lock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.quit)
+	}
+	for chainId, process := range s.processes {
+		logger.Info("stop sync process of chain", logger.String("chain_id", chainId))
+		if err := process.Signal(syscall.SIGTERM); err != nil {
+			logger.Error("stop sync process of chain fail", logger.String("chain_id", chainId),
+				logger.String("err", err.Error()))
+		}
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *ChainSupervisor) run(chain conf.ChainConf) {
+	interval := s.restartInterval
+	for restarts := 0; ; restarts++ {
+		cmd := s.command(chain)
+
+		s.mutex.Lock()
+		if s.stopped {
+			s.mutex.Unlock()
+			return
+		}
+		startTime := time.Now()
+		err := cmd.Start()
+		if err == nil {
+			s.processes[chain.ChainId] = cmd.Process
+		}
+		s.mutex.Unlock()
+
+		if err == nil {
+			logger.Info("sync process of chain started", logger.String("chain_id", chain.ChainId),
+				logger.String("database", chain.Database), logger.String("log_file", chain.LogFile),
+				logger.Int("pid", cmd.Process.Pid), logger.Int("restarts", restarts))
+			err = cmd.Wait()
+		}
+
+		s.mutex.Lock()
+		delete(s.processes, chain.ChainId)
+		stopped := s.stopped
+		s.mutex.Unlock()
+		if stopped {
+			logger.Info("sync process of chain stopped", logger.String("chain_id", chain.ChainId))
+			return
+		}
+
+		// process which exits soon after it started is restarted less and less frequently
+		if time.Since(startTime) >= stableChainDuration {
+			interval = s.restartInterval
+		} else if restarts > 0 {
+			interval *= 2
+			if interval > maxRestartChainInterval {
+				interval = maxRestartChainInterval
+			}
+		}
+		fields := []logger.Field{logger.String("chain_id", chain.ChainId), logger.String("log_file", chain.LogFile),
+			logger.Duration("restart_after", interval)}
+		if err != nil {
+			fields = append(fields, logger.String("err", err.Error()))
+		}
+		logger.Error("sync process of chain exited", fields...)
+
+		select {
+		case <-time.After(interval):
+		case <-s.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	conf "github.com/irisnet/irishub-sync/conf/server"
+)
+
+func TestChainSupervisor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chains")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chains := []conf.ChainConf{
+		{ChainId: "a", FullNode: "tcp://a:26657", Database: "sync-a", LogFile: filepath.Join(dir, "a.log")},
+		{ChainId: "b", FullNode: "tcp://b:26657", Database: "sync-b", LogFile: filepath.Join(dir, "b.log")},
+	}
+	s := NewChainSupervisor(chains)
+	s.restartInterval = 10 * time.Millisecond
+	// sync process of chain records env vars it's started with, then exits unexpectedly
+	s.command = func(chain conf.ChainConf) *exec.Cmd {
+		cmd := exec.Command("sh", "-c", `echo "$SER_BC_CHAIN_ID $DB_DATABASE" >> "$LOG_FILE_NAME"; exit 1`)
+		cmd.Env = chain.Environ()
+		return cmd
+	}
+	s.Start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, chain := range chains {
+		for {
+			data, _ := ioutil.ReadFile(chain.LogFile)
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if len(lines) >= 2 {
+				for _, line := range lines {
+					if line != chain.ChainId+" "+chain.Database {
+						t.Errorf("sync process of chain %v is started with %q", chain.ChainId, line)
+					}
+				}
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("sync process of chain %v isn't restarted, started %v times", chain.ChainId, len(lines))
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor isn't stopped while it's waiting to restart sync process")
+	}
+}
@@ -4,18 +4,14 @@ import (
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
-	"github.com/irisnet/irishub-sync/util/constant"
 	"github.com/irisnet/irishub-sync/util/helper"
-	"sync"
+	"gopkg.in/mgo.v2/bson"
 	"time"
 )
 
 // save account
-func SaveAccount(docTx document.CommonTx, mutex sync.Mutex) {
+func SaveAccount(docTx document.CommonTx, batch *store.Batch) {
 	var (
-		address    string
-		updateTime time.Time
-		height     int64
 		methodName = "SaveAccount: "
 	)
 	logger.Debug("Start", logger.String("method", methodName))
@@ -28,49 +24,35 @@ func SaveAccount(docTx document.CommonTx, mutex sync.Mutex) {
 			Height:  height,
 		}
 
-		err := store.Save(account)
+		err := batch.Save(account)
 
 		if err != nil && err.Error() != "Record exists" {
 			logger.Error("account Record exists", logger.String("address", account.Address))
 		}
 	}
 
-	txType := GetTxType(docTx)
-	if len(txType) == 0 {
+	if GetTxType(docTx) == "" {
 		logger.Error("Tx is valid", logger.Any("Tx", docTx))
 		return
 	}
 
-	switch txType {
-	case constant.TxTypeTransfer, constant.TxTypeStakeDelegate,
-		constant.TxTypeStakeBeginUnbonding, constant.TxTypeStakeCompleteUnbonding:
-		updateTime = docTx.Time
-		height = docTx.Height
-
-		fun(docTx.From, updateTime, height)
-		fun(docTx.To, updateTime, height)
-		break
-	case constant.TxTypeStakeCreateValidator, constant.TxTypeStakeEditValidator:
-		address = docTx.From
-		updateTime = docTx.Time
-		height = docTx.Height
-
-		fun(address, updateTime, height)
-		break
+	// accounts which msg touches are set by decoder of msg
+	for _, address := range docTx.Accounts() {
+		fun(address, docTx.Time, docTx.Height)
 	}
 
 	logger.Debug("End", logger.String("method", methodName))
 }
 
 // update account balance
-func UpdateBalance(docTx document.CommonTx, mutex sync.Mutex) {
+func UpdateBalance(docTx document.CommonTx, batch *store.Batch) {
 	var (
 		methodName = "UpdateBalance: "
 	)
 	logger.Debug("Start", logger.String("method", methodName))
 
 	fun := func(address string) {
-		account, err := document.QueryAccount(address)
+		account, err := document.QueryAccount(batch.Store(), address)
 		if err != nil {
 			logger.Error("QueryAccount failed", logger.String("address", address), logger.String("err", err.Error()))
 			return
@@ -78,27 +60,51 @@ func UpdateBalance(docTx document.CommonTx, mutex sync.Mutex) {
 
 		// query balance of account
 		account.Amount = helper.QueryAccountBalance(address)
-		if err := store.Update(account); err != nil {
+		if err := batch.Update(account); err != nil {
 			logger.Error("updateAccountBalance failed", logger.String("address", account.Address), logger.String("err", err.Error()))
 		}
 	}
 
-	txType := GetTxType(docTx)
-	if txType == "" {
+	if GetTxType(docTx) == "" {
 		logger.Error("Tx is valid", logger.Any("Tx", docTx))
 		return
 	}
 
-	switch txType {
-	case constant.TxTypeTransfer, constant.TxTypeStakeDelegate,
-		constant.TxTypeStakeBeginUnbonding, constant.TxTypeStakeCompleteUnbonding:
-		fun(docTx.From)
-		fun(docTx.To)
-		break
-	case constant.TxTypeStakeCreateValidator, constant.TxTypeStakeEditValidator:
-		fun(docTx.From)
-		break
+	for _, address := range docTx.Accounts() {
+		fun(address)
 	}
 
 	logger.Debug("End", logger.String("method", methodName))
 }
+
+// record pubkey of signers of tx in accounts, pubkey of account is first seen when it signs first tx.
+// accounts which don't exist are created
+func SaveAccountPubKey(docTx document.CommonTx, batch *store.Batch) {
+	for _, signer := range docTx.Signers {
+		if signer.Address == "" || signer.PubKey == "" {
+			continue
+		}
+
+		// blocks may be synced out of order and concurrently, so pubkey is only set when
+		// it isn't recorded or it's recorded at higher height, rollback resets pub_key_height to 0
+		selector := bson.M{
+			document.Account_Field_Addres: signer.Address,
+			"$or": []bson.M{
+				{document.Account_Field_PubKeyHeight: bson.M{"$exists": false}},
+				{document.Account_Field_PubKeyHeight: 0},
+				{document.Account_Field_PubKeyHeight: bson.M{"$gt": docTx.Height}},
+			},
+		}
+		update := bson.M{
+			"$set": bson.M{
+				document.Account_Field_PubKey:       signer.PubKey,
+				document.Account_Field_PubKeyHeight: docTx.Height,
+			},
+			"$setOnInsert": bson.M{
+				document.Account_Field_Time:   docTx.Time,
+				document.Account_Field_Height: docTx.Height,
+			},
+		}
+		batch.AddConditionalOps(document.CollectionNmAccount, store.BulkOp{Selector: selector, Update: update, Upsert: true})
+	}
+}
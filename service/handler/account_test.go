@@ -1,9 +1,9 @@
 package handler
 
 import (
-	"sync"
 	"testing"
 
+	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
 )
 
@@ -11,7 +11,7 @@ func TestSaveAccount(t *testing.T) {
 
 	type args struct {
 		docTx document.CommonTx
-		mutex sync.Mutex
+		batch *store.Batch
 	}
 	tests := []struct {
 		name string
@@ -21,48 +21,51 @@ func TestSaveAccount(t *testing.T) {
 			name: "tx bank",
 			args: args{
 				docTx: buildDocData(BankHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/create",
 			args: args{
 				docTx: buildDocData(StakeCreateHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/edit",
 			args: args{
 				docTx: buildDocData(StakeEditHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/delegate",
 			args: args{
 				docTx: buildDocData(StakeDelegateHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/beginUnbonding",
 			args: args{
 				docTx: buildDocData(StakeBeginUnbondingHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/completeUnbonding",
 			args: args{
 				docTx: buildDocData(StakeCompleteUnbondingHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			SaveAccount(tt.args.docTx, tt.args.mutex)
+			SaveAccount(tt.args.docTx, tt.args.batch)
+			if err := tt.args.batch.Commit(); err != nil {
+				t.Fatal(err)
+			}
 		})
 	}
 }
@@ -70,7 +73,7 @@ func TestSaveAccount(t *testing.T) {
 func TestUpdateBalance(t *testing.T) {
 	type args struct {
 		docTx document.CommonTx
-		mutex sync.Mutex
+		batch *store.Batch
 	}
 	tests := []struct {
 		name string
@@ -80,48 +83,51 @@ func TestUpdateBalance(t *testing.T) {
 			name: "tx bank",
 			args: args{
 				docTx: buildDocData(BankHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/create",
 			args: args{
 				docTx: buildDocData(StakeCreateHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/edit",
 			args: args{
 				docTx: buildDocData(StakeEditHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/delegate",
 			args: args{
 				docTx: buildDocData(StakeDelegateHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/beginUnbonding",
 			args: args{
 				docTx: buildDocData(StakeBeginUnbondingHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 		{
 			name: "tx stake/completeUnbonding",
 			args: args{
 				docTx: buildDocData(StakeCompleteUnbondingHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			UpdateBalance(tt.args.docTx, tt.args.mutex)
+			UpdateBalance(tt.args.docTx, tt.args.batch)
+			if err := tt.args.batch.Commit(); err != nil {
+				t.Fatal(err)
+			}
 		})
 	}
 }
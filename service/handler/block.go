@@ -2,14 +2,19 @@ package handler
 
 import (
 	"encoding/json"
+	conf "github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
 	"github.com/irisnet/irishub-sync/types"
 	"github.com/irisnet/irishub-sync/util/helper"
 )
 
-func ParseBlock(meta *types.BlockMeta, block *types.Block, validators []*types.Validator) document.Block {
-	cdc := types.GetCodec()
+// parse block document, validator set of block is saved into batch once for each validators hash,
+// and precommits are stored as signer bitmap of validator set when conf.CompactPrecommits is set
+func ParseBlock(meta *types.BlockMeta, block *types.Block, validators []*types.Validator,
+	blockResults *types.ResultBlockResults, batch *store.Batch) document.Block {
+	cdc := types.GetCodecAt(meta.Header.Height)
 
 	hexFunc := func(bytes []byte) string {
 		return helper.BuildHex(bytes)
@@ -102,21 +107,34 @@ func ParseBlock(meta *types.BlockMeta, block *types.Block, validators []*types.V
 		}
 	}
 
+	// validator set is referred by validators hash in header, it's saved once
+	valSet := document.ValidatorSet{
+		Hash:       blockMeta.Header.ValidatorsHash,
+		Validators: vals,
+	}
+	if len(vals) > 0 {
+		if err := batch.Save(valSet); err != nil && err.Error() != "Record exists" {
+			logger.Error("save validator set fail", logger.String("hash", valSet.Hash), logger.String("err", err.Error()))
+		}
+		if conf.CompactPrecommits {
+			if signers, ok := valSet.SignerBitmap(preCommits); ok {
+				blockContent.LastCommit.Precommits = nil
+				blockContent.LastCommit.Signers = signers
+			}
+		}
+	}
+
 	docBlock.Meta = blockMeta
 	docBlock.Block = blockContent
-	docBlock.Validators = vals
-	docBlock.Result = parseBlockResult(docBlock.Height)
+	docBlock.Result = parseBlockResult(blockResults)
 
 	return docBlock
 }
 
-func parseBlockResult(height int64) (res document.BlockResults) {
-	client := helper.GetClient()
-	defer client.Release()
-
-	result, err := client.BlockResults(&height)
-	if err != nil {
-		logger.Error("EndBlocker error", logger.Any("err", err))
+func parseBlockResult(result *types.ResultBlockResults) (res document.BlockResults) {
+	if result == nil || result.Results == nil {
+		logger.Error("block results is empty")
+		return res
 	}
 
 	var deliverTxRes []document.ResponseDeliverTx
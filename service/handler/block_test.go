@@ -44,7 +44,15 @@ func TestForEach(t *testing.T) {
 }
 
 func TestParseBlockResult(t *testing.T) {
-	v := parseBlockResult(213637)
+	client := helper.GetClient()
+	defer client.Release()
+
+	height := int64(213637)
+	result, err := client.Client.BlockResults(&height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := parseBlockResult(result)
 	bz, _ := json.Marshal(v)
 	fmt.Println(string(bz))
 }
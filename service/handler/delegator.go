@@ -7,16 +7,19 @@ import (
 	"github.com/irisnet/irishub-sync/types"
 	"github.com/irisnet/irishub-sync/util/constant"
 	"github.com/irisnet/irishub-sync/util/helper"
-	"sync"
 )
 
 // init delegator for genesis validator
-func InitDelegator() {
+func InitDelegator(s store.Store) {
+	batch := store.NewBulkBatch(s)
 	validators := helper.GetValidators()
 	for _, validator := range validators {
 		valAddr := validator.OperatorAddr.String()
 		valAccAddr := helper.ValAddrToAccAddr(valAddr)
-		modifyDelegator(valAccAddr, valAddr)
+		modifyDelegator(valAccAddr, valAddr, batch)
+	}
+	if err := batch.Commit(); err != nil {
+		logger.Error("init delegator fail", logger.String("err", err.Error()))
 	}
 }
 
@@ -42,19 +45,19 @@ func InitDelegator() {
 //TxTypeBeginRedelegate
 //	1:update validator(src,dest) (---> CompareAndUpdateValidators)
 //	2:update delegator(src,dest)
-func SaveOrUpdateDelegator(docTx document.CommonTx, mutex sync.Mutex) {
+func SaveOrUpdateDelegator(docTx document.CommonTx, batch *store.Batch) {
 
 	logger.Debug("Start", logger.String("method", "saveDelegator"))
 
 	switch docTx.Type {
 	case constant.TxTypeStakeCreateValidator:
-		modifyDelegator(docTx.From, docTx.To)
+		modifyDelegator(docTx.From, docTx.To, batch)
 		break
 	case constant.TxTypeStakeEditValidator:
-		updateValidator(docTx.From)
+		updateValidator(docTx.From, batch)
 		break
 	case constant.TxTypeStakeDelegate, constant.TxTypeStakeBeginUnbonding:
-		modifyDelegator(docTx.From, docTx.To)
+		modifyDelegator(docTx.From, docTx.To, batch)
 		break
 	case constant.TxTypeBeginRedelegate:
 		delAddress := docTx.From
@@ -62,15 +65,15 @@ func SaveOrUpdateDelegator(docTx document.CommonTx, mutex sync.Mutex) {
 		valSrcAddr := msg.ValidatorSrcAddr
 		valDstAddr := msg.ValidatorDstAddr
 
-		modifyDelegator(delAddress, valSrcAddr)
-		modifyDelegator(delAddress, valDstAddr)
+		modifyDelegator(delAddress, valSrcAddr, batch)
+		modifyDelegator(delAddress, valDstAddr, batch)
 		break
 	}
 
 	logger.Debug("End", logger.String("method", "saveDelegator"))
 }
 
-func modifyDelegator(delAddress, valAddress string) {
+func modifyDelegator(delAddress, valAddress string, batch *store.Batch) {
 	logger.Info("delegator info has changed", logger.String("delAddress", delAddress), logger.String("valAddress", valAddress))
 	// get delegation
 	delegation := BuildDelegation(delAddress, valAddress)
@@ -96,10 +99,10 @@ func modifyDelegator(delAddress, valAddress string) {
 
 	if delegator.BondedHeight < 0 &&
 		delegator.UnbondingDelegation.CreationHeight < 0 {
-		store.Delete(delegator)
+		batch.Delete(delegator)
 		logger.Info("delete delegator", logger.String("delAddress", delAddress), logger.String("valAddress", valAddress))
 	} else {
-		store.SaveOrUpdate(delegator)
+		batch.SaveOrUpdate(delegator)
 		logger.Info("saveOrUpdate delegator", logger.String("delAddress", delAddress), logger.String("valAddress", valAddress))
 	}
 }
@@ -113,9 +116,8 @@ func BuildDelegation(delAddress, valAddress string) (res tempDelegation) {
 		return res
 	}
 
-	floatShares := helper.ParseFloat(d.Shares.String())
 	res = tempDelegation{
-		Shares:         floatShares,
+		Shares:         helper.ParseDec(d.Shares.String()),
 		OriginalShares: d.Shares.String(),
 		Height:         d.Height,
 	}
@@ -149,7 +151,7 @@ func BuildUnbondingDelegation(delAddress, valAddress string) (res document.Unbon
 // owned by one delegator, and is associated with the voting power of one
 // pubKey.
 type tempDelegation struct {
-	Shares         float64
+	Shares         store.Dec
 	OriginalShares string
 	Height         int64 // Last height bond updated
 }
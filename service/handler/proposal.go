@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
 	"github.com/irisnet/irishub-sync/types"
@@ -8,27 +9,33 @@ import (
 	"github.com/irisnet/irishub-sync/util/helper"
 )
 
-func handleProposal(docTx document.CommonTx) {
+// save or update proposal which is submitted, deposited or voted by msg of tx
+func SaveOrUpdateProposal(docTx document.CommonTx, batch *store.Batch) {
 	switch docTx.Type {
 	case constant.TxTypeSubmitProposal:
 		if proposal, err := helper.GetProposal(docTx.ProposalId); err == nil {
-			store.SaveOrUpdate(proposal)
+			// switch height of software upgrade is used to switch codec
+			if upgrade, ok := docTx.Msg.(types.SubmitSoftwareUpgradeProposal); ok {
+				proposal.Version = upgrade.Version
+				proposal.SwitchHeight = int64(upgrade.SwitchHeight)
+			}
+			batch.SaveOrUpdate(proposal)
 		}
 	case constant.TxTypeDeposit:
-		if proposal, err := document.QueryProposal(docTx.ProposalId); err == nil {
+		if proposal, err := queryProposal(docTx.ProposalId, batch); err == nil {
 			propo, _ := helper.GetProposal(docTx.ProposalId)
 			proposal.TotalDeposit = propo.TotalDeposit
 			proposal.Status = propo.Status
 			proposal.VotingStartTime = propo.VotingStartTime
 			proposal.VotingEndTime = propo.VotingEndTime
-			store.SaveOrUpdate(proposal)
+			batch.SaveOrUpdate(proposal)
 		}
 	case constant.TxTypeVote:
 		//失败的投票不计入统计
 		if docTx.Status == document.TxStatusFail {
 			return
 		}
-		if proposal, err := document.QueryProposal(docTx.ProposalId); err == nil {
+		if proposal, err := queryProposal(docTx.ProposalId, batch); err == nil {
 			voteMsg := docTx.Msg.(types.Vote)
 			vote := document.PVote{
 				Voter:  voteMsg.Voter,
@@ -48,7 +55,49 @@ func handleProposal(docTx document.CommonTx) {
 			} else {
 				proposal.Votes = append(proposal.Votes, vote)
 			}
-			store.SaveOrUpdate(proposal)
+			batch.SaveOrUpdate(proposal)
+		}
+	}
+}
+
+// revert proposal which is modified by tx, used when tx is rolled back.
+// proposal submitted by tx will be removed, deposit is rebuilt from blockchain
+// and vote is removed from proposal
+func RevertProposal(docTx document.CommonTx, batch *store.Batch) {
+	switch docTx.Type {
+	case constant.TxTypeSubmitProposal:
+		proposal := document.Proposal{
+			ProposalId: docTx.ProposalId,
+		}
+		if err := batch.Delete(proposal); err != nil {
+			logger.Error("remove proposal fail", logger.Uint64("proposalId", docTx.ProposalId),
+				logger.String("err", err.Error()))
+		}
+	case constant.TxTypeDeposit:
+		SaveOrUpdateProposal(docTx, batch)
+	case constant.TxTypeVote:
+		voteMsg, ok := docTx.Msg.(types.Vote)
+		if !ok {
+			return
+		}
+		if proposal, err := queryProposal(docTx.ProposalId, batch); err == nil {
+			var votes []document.PVote
+			for _, v := range proposal.Votes {
+				if v.Voter != voteMsg.Voter {
+					votes = append(votes, v)
+				}
+			}
+			proposal.Votes = votes
+			batch.SaveOrUpdate(proposal)
 		}
 	}
 }
+
+// query proposal which is written in batch or stored in db,
+// proposal may be submitted and deposited or voted in same block
+func queryProposal(proposalId uint64, batch *store.Batch) (document.Proposal, error) {
+	if proposal, ok := batch.Get(document.Proposal{ProposalId: proposalId}); ok {
+		return proposal.(document.Proposal), nil
+	}
+	return document.QueryProposal(batch.Store(), proposalId)
+}
@@ -4,11 +4,11 @@ import (
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
-	"sync"
+	"github.com/irisnet/irishub-sync/util/helper"
 )
 
 // save Tx document into collection
-func SaveTx(docTx document.CommonTx, mutex sync.Mutex) {
+func SaveTx(docTx document.CommonTx, batch *store.Batch) {
 	var (
 		methodName = "SaveTx"
 	)
@@ -17,23 +17,46 @@ func SaveTx(docTx document.CommonTx, mutex sync.Mutex) {
 	// save common docTx document
 	saveCommonTx := func(commonTx document.CommonTx) {
 		//save tx
-		err := store.Save(commonTx)
+		err := batch.Save(commonTx)
 		if err != nil {
 			logger.Error("Save commonTx failed", logger.Any("Tx", commonTx), logger.String("err", err.Error()))
 		}
-		//save tx_msg
-		msg := commonTx.Msg
-		if msg != nil {
-			txMsg := document.TxMsg{
-				Hash:    docTx.TxHash,
-				Type:    msg.Type(),
-				Content: msg.String(),
+		//save tx_msg of every msg in tx
+		for _, txMsg := range buildTxMsgs(commonTx) {
+			if err := batch.Save(txMsg); err != nil {
+				logger.Error("Save txMsg failed", logger.Any("TxMsg", txMsg), logger.String("err", err.Error()))
 			}
-			store.Save(txMsg)
 		}
-		handleProposal(commonTx)
 	}
 
 	saveCommonTx(docTx)
 	logger.Debug("End", logger.String("method", methodName))
 }
+
+// build tx_msg documents of msgs in tx,
+// tx which was parsed before msgs were indexed only has msg in tx itself
+func buildTxMsgs(docTx document.CommonTx) []document.TxMsg {
+	var txMsgs []document.TxMsg
+	for _, msg := range docTx.SplitMsgs() {
+		if msg.Msg == nil {
+			continue
+		}
+		index := 0
+		if len(msg.Msgs) > 0 {
+			index = msg.Msgs[0].Index
+		}
+		txMsgs = append(txMsgs, document.TxMsg{
+			Hash:    docTx.TxHash,
+			Index:   index,
+			Type:    msg.Msg.Type(),
+			Content: msg.Msg.String(),
+		})
+	}
+	return txMsgs
+}
+
+// rebuild msg of tx from tx_msg document,
+// only msg whose decoder has unmarshaler can be rebuilt
+func BuildMsg(txMsg document.TxMsg) store.Msg {
+	return helper.UnmarshalMsg(txMsg.Type, txMsg.Content)
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"sync"
 	"testing"
 
 	"github.com/irisnet/irishub-sync/logger"
@@ -41,10 +40,18 @@ func buildDocData(blockHeight int64) document.CommonTx {
 		logger.Panic(err.Error())
 	}
 
+	blockResults, err := client.Client.BlockResults(&blockHeight)
+	if err != nil {
+		logger.Panic(err.Error())
+	}
+
 	if block.BlockMeta.Header.NumTxs > 0 {
 		txs := block.Block.Data.Txs
 		txByte := txs[0]
-		docTx := helper.ParseTx(txByte, block.Block)
+		docTx, err := helper.ParseTx(txByte, block.Block, *blockResults.Results.DeliverTx[0])
+		if err != nil {
+			logger.Panic(err.Error())
+		}
 
 		return docTx
 
@@ -55,7 +62,7 @@ func buildDocData(blockHeight int64) document.CommonTx {
 func TestSaveTx(t *testing.T) {
 	type args struct {
 		docTx document.CommonTx
-		mutex sync.Mutex
+		batch *store.Batch
 	}
 	tests := []struct {
 		name string
@@ -65,48 +72,51 @@ func TestSaveTx(t *testing.T) {
 		//	name: "tx bank",
 		//	args: args{
 		//		docTx: buildDocData(BankHeight),
-		//		mutex: sync.Mutex{},
+		//		batch: store.NewBatch(),
 		//	},
 		//},
 		//{
 		//	name: "tx stake/create",
 		//	args: args{
 		//		docTx: buildDocData(StakeCreateHeight),
-		//		mutex: sync.Mutex{},
+		//		batch: store.NewBatch(),
 		//	},
 		//},
 		//{
 		//	name: "tx stake/edit",
 		//	args: args{
 		//		docTx: buildDocData(StakeEditHeight),
-		//		mutex: sync.Mutex{},
+		//		batch: store.NewBatch(),
 		//	},
 		//},
 		//{
 		//	name: "tx stake/delegate",
 		//	args: args{
 		//		docTx: buildDocData(StakeDelegateHeight),
-		//		mutex: sync.Mutex{},
+		//		batch: store.NewBatch(),
 		//	},
 		//},
 		//{
 		//	name: "tx stake/beginUnbonding",
 		//	args: args{
 		//		docTx: buildDocData(StakeBeginUnbondingHeight),
-		//		mutex: sync.Mutex{},
+		//		batch: store.NewBatch(),
 		//	},
 		//},
 		{
 			name: "tx stake/completeUnbonding",
 			args: args{
 				docTx: buildDocData(StakeCompleteUnbondingHeight),
-				mutex: sync.Mutex{},
+				batch: store.NewBatch(store.Backend()),
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			SaveTx(tt.args.docTx, tt.args.mutex)
+			SaveTx(tt.args.docTx, tt.args.batch)
+			if err := tt.args.batch.Commit(); err != nil {
+				t.Fatal(err)
+			}
 		})
 	}
 }
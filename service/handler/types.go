@@ -2,8 +2,8 @@ package handler
 
 import (
 	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
-	"sync"
 )
 
 // get tx type
@@ -14,9 +14,11 @@ func GetTxType(docTx document.CommonTx) string {
 	return docTx.Type
 }
 
-type Action = func(tx document.CommonTx, mutex sync.Mutex)
+// action handles tx and writes documents into batch,
+// documents in batch are committed together with block
+type Action = func(tx document.CommonTx, batch *store.Batch)
 
-func Handle(docTx document.CommonTx, mutex sync.Mutex, actions []Action) {
+func Handle(docTx document.CommonTx, batch *store.Batch, actions []Action) {
 	defer func() {
 		if err := recover(); err != nil {
 			logger.Error("Parse Tx failed", logger.Int64("height", docTx.Height),
@@ -26,7 +28,15 @@ func Handle(docTx document.CommonTx, mutex sync.Mutex, actions []Action) {
 
 	for _, action := range actions {
 		if docTx.TxHash != "" {
-			action(docTx, mutex)
+			action(docTx, batch)
 		}
 	}
 }
+
+// handle every msg of tx, actions receive tx which only contains one msg
+// and fields of this msg are set into tx
+func HandleMsgs(docTx document.CommonTx, batch *store.Batch, actions []Action) {
+	for _, msgTx := range docTx.SplitMsgs() {
+		Handle(msgTx, batch, actions)
+	}
+}
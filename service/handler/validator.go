@@ -6,7 +6,6 @@ import (
 	"github.com/irisnet/irishub-sync/store/document"
 	"github.com/irisnet/irishub-sync/types"
 	"github.com/irisnet/irishub-sync/util/helper"
-	"sort"
 )
 
 // compare validatorSet stored in irishub and validatorSet stored in db
@@ -15,7 +14,7 @@ import (
 // second, store latest validators which query from sdk store into db
 // note: this function isn't thread safe, should be invoked during watch block
 //       not fast sync
-func CompareAndUpdateValidators() {
+func CompareAndUpdateValidators(s store.Store) {
 	var (
 		methodName = "CompareAndUpdateValidators"
 
@@ -23,7 +22,7 @@ func CompareAndUpdateValidators() {
 	)
 
 	// get all validatorSets from db
-	dbCandidates := candidateModel.QueryAll()
+	dbCandidates := candidateModel.QueryAll(s)
 
 	// get all validatorSets from blockChain
 	validators := helper.GetValidators()
@@ -44,14 +43,14 @@ func CompareAndUpdateValidators() {
 	// dbCandidates not equal chainValidators
 	if compareValidators(dbCandidates, chainValidators) {
 		// remove all data which stored in db
-		if err := candidateModel.RemoveCandidates(); err != nil {
+		if err := candidateModel.RemoveCandidates(s); err != nil {
 			logger.Error("RemoveCandidates err ", logger.String("method", methodName), logger.String("err", err.Error()))
 		}
 
-		updateValidatorsRank(chainValidators)
+		document.RankCandidates(chainValidators)
 
 		// store latest validators into db
-		if err := candidateModel.SaveAll(chainValidators); err != nil {
+		if err := candidateModel.SaveAll(s, chainValidators); err != nil {
 			logger.Error("SaveAll", logger.String("method", methodName), logger.String("err", err.Error()))
 		}
 	}
@@ -65,8 +64,8 @@ func BuildValidatorDocument(v types.StakeValidator) document.Candidate {
 		Details:  v.Description.Details,
 	}
 
-	floatTokens := helper.ParseFloat(v.Tokens.String())
-	floatDelegatorShares := helper.ParseFloat(v.DelegatorShares.String())
+	tokens := helper.ParseDec(v.Tokens.String())
+	delegatorShares := helper.ParseDec(v.DelegatorShares.String())
 	pubKey, err := types.Bech32ifyValPub(v.ConsPubKey)
 	if err != nil {
 		logger.Error("Can't get validator pubKey", logger.String("pubKey", pubKey), logger.String("err", err.Error()))
@@ -76,9 +75,9 @@ func BuildValidatorDocument(v types.StakeValidator) document.Candidate {
 		PubKey:          pubKey,
 		PubKeyAddr:      v.ConsPubKey.Address().String(),
 		Jailed:          v.Jailed,
-		Tokens:          floatTokens,
-		OriginalTokens:  helper.RoundString(v.Tokens.String(), 0),
-		DelegatorShares: floatDelegatorShares,
+		Tokens:          tokens,
+		OriginalTokens:  tokens.Round(0).String(),
+		DelegatorShares: delegatorShares,
 		Description:     description,
 		BondHeight:      v.BondHeight,
 		Status:          types.BondStatusToString(v.Status),
@@ -110,11 +109,11 @@ func compareValidators(dbVals []document.Candidate, chainVals []document.Candida
 			return true
 		}
 
-		if v.Tokens != v1.Tokens {
+		if !v.Tokens.Equal(v1.Tokens) {
 			logger.Info("Candidate's votingPower has changed",
 				logger.String("validator", v.Address),
-				logger.Float64("dbTokens", v1.Tokens),
-				logger.Float64("tmTokens", v1.Tokens),
+				logger.String("dbTokens", v.Tokens.String()),
+				logger.String("tmTokens", v1.Tokens.String()),
 			)
 			return true
 		}
@@ -141,24 +140,7 @@ func compareValidators(dbVals []document.Candidate, chainVals []document.Candida
 	return false
 }
 
-func updateValidatorsRank(candidates []document.Candidate) {
-	sort.SliceStable(candidates, func(i, j int) bool {
-		return candidates[i].Tokens > candidates[j].Tokens
-	})
-
-	var rank int
-	for index, _ := range candidates {
-		rank = index + 1
-		if index >= 1 {
-			if candidates[index-1].Tokens == candidates[index].Tokens {
-				rank = candidates[index-1].Rank
-			}
-		}
-		candidates[index].Rank = rank
-	}
-}
-
-func updateValidator(valAddress string) {
+func updateValidator(valAddress string, batch *store.Batch) {
 	defer func() {
 		if err := recover(); err != nil {
 			logger.Error("updateValidator panic", logger.Any("ex", err))
@@ -173,7 +155,7 @@ func updateValidator(valAddress string) {
 	}
 
 	editValidator := BuildValidatorDocument(validator)
-	if err := store.Update(editValidator); err != nil {
+	if err := batch.Update(editValidator); err != nil {
 		logger.Error("update candidate error", logger.String("address", valAddress))
 	}
 	logger.Info("Update candidate success", logger.String("Address", valAddress))
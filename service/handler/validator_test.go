@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/types"
 	"github.com/irisnet/irishub-sync/util/helper"
 	"sort"
@@ -33,7 +34,7 @@ func TestCompareAndUpdateValidators(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			CompareAndUpdateValidators()
+			CompareAndUpdateValidators(store.Backend())
 		})
 	}
 }
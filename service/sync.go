@@ -1,38 +1,23 @@
 package service
 
 import (
+	"context"
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/service/handler"
 	"github.com/irisnet/irishub-sync/service/task"
 	"github.com/robfig/cron"
+	"sync"
 	"time"
 )
 
-var (
-	engine *SyncEngine
-)
-
-func init() {
-	engine = &SyncEngine{
-		cron:      cron.New(),
-		tasks:     []task.Task{},
-		initFuncs: []func(){},
-	}
-
-	engine.AddTask(task.MakeCalculateAndSaveValidatorUpTimeTask())
-	engine.AddTask(task.MakeCalculateTxGasAndGasPriceTask())
-	engine.AddTask(task.MakeSyncProposalStatusTask())
-	engine.AddTask(task.MakeValidatorHistoryTask())
-	engine.AddTask(task.MakeUpdateDelegatorTask())
-
-	// init delegator for genesis validator
-	engine.initFuncs = append(engine.initFuncs, handler.InitDelegator)
-}
-
 type SyncEngine struct {
-	cron      *cron.Cron  //cron
-	tasks     []task.Task // my timer task
-	initFuncs []func()    // module init fun
+	env       task.Env       // store and nodes which tasks read and write
+	cron      *cron.Cron     //cron
+	tasks     []task.Task    // my timer task
+	initFuncs []func() error // module init fun
+
+	cancel context.CancelFunc // stop create and execute task
+	wg     sync.WaitGroup     // wait create and execute task exit
 }
 
 func (engine *SyncEngine) AddTask(task task.Task) {
@@ -40,44 +25,98 @@ func (engine *SyncEngine) AddTask(task task.Task) {
 	engine.cron.AddFunc(task.GetCron(), task.GetCommand())
 }
 
-func (engine *SyncEngine) Start() {
+// start engine, it refuses to start when nodes or stored blocks don't belong to configured chain
+func (engine *SyncEngine) Start() error {
+	if err := task.VerifyChainIdentity(engine.env); err != nil {
+		return err
+	}
+
 	// init module info
 	for _, init := range engine.initFuncs {
-		init()
+		if err := init(); err != nil {
+			return err
+		}
 	}
-	go task.StartCreateTask()
-	go task.StartExecuteTask()
+	var ctx context.Context
+	ctx, engine.cancel = context.WithCancel(context.Background())
+
+	engine.wg.Add(3)
+	go func() {
+		defer engine.wg.Done()
+		task.StartCreateTask(ctx, engine.env)
+	}()
+	go func() {
+		defer engine.wg.Done()
+		task.StartExecuteTask(ctx, engine.env)
+	}()
+	go func() {
+		defer engine.wg.Done()
+		task.StartSyncStatusTask(ctx, engine.env)
+	}()
 
 	// cron task should start after fast sync finished
-	fastSyncChan := make(chan bool, 1)
-	ticker := time.NewTicker(1 * time.Minute)
 	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
 		for {
-			<-ticker.C
-			flag, err := task.AssertFastSyncFinished()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			flag, err := task.AssertFastSyncFinished(engine.env)
 			if err != nil {
 				logger.Error("assert fast sync finished failed", logger.String("err", err.Error()))
 			}
 			if flag {
-				close(fastSyncChan)
+				logger.Info("fast sync finished, now cron task can start")
+				engine.cron.Start()
 				return
 			}
 		}
 	}()
-	<-fastSyncChan
-	logger.Info("fast sync finished, now cron task can start")
 
-	engine.cron.Start()
+	return nil
 }
 
+// stop engine, it returns after all workers finished their current block
+// and released their tasks, so pool and store can be closed safely after it
 func (engine *SyncEngine) Stop() {
 	logger.Info("release resource :SyncEngine")
+	if engine.cancel != nil {
+		engine.cancel()
+	}
+	engine.wg.Wait()
+
 	engine.cron.Stop()
 	for _, t := range engine.tasks {
 		t.Release()
 	}
 }
 
-func New() *SyncEngine {
+// create sync engine whose tasks read and write store and nodes of env
+func New(env task.Env) *SyncEngine {
+	engine := &SyncEngine{
+		env:       env,
+		cron:      cron.New(),
+		tasks:     []task.Task{},
+		initFuncs: []func() error{},
+	}
+
+	engine.AddTask(task.MakeCalculateAndSaveValidatorUpTimeTask(env))
+	engine.AddTask(task.MakeCalculateTxGasAndGasPriceTask(env))
+	engine.AddTask(task.MakeSyncProposalStatusTask(env))
+	engine.AddTask(task.MakeValidatorHistoryTask(env))
+	engine.AddTask(task.MakeUpdateDelegatorTask(env))
+	engine.AddTask(task.MakeAuditSyncGapTask(env))
+	engine.AddTask(task.MakeArchiveBlockTask(env))
+
+	// switch codec at software upgrades which have been synced, it's loaded before codec is used
+	engine.initFuncs = append(engine.initFuncs, func() error { return task.LoadCodecSchedule(env) })
+	// init delegator for genesis validator
+	engine.initFuncs = append(engine.initFuncs, func() error {
+		handler.InitDelegator(env.Store)
+		return nil
+	})
 	return engine
 }
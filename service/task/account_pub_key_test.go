@@ -0,0 +1,76 @@
+package task
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+func Test_saveAccountPubKey(t *testing.T) {
+	env := newTestEnv(&stubNode{})
+
+	if err := env.Store.EnsureIndex(document.CollectionNmAccount, store.Index{Key: []string{"address"}, Unique: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Store.Save(document.Account{Address: "a", Height: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// blocks are synced out of order, pubkey of lowest height is kept
+	for _, height := range []int64{5, 3, 7, 4} {
+		batch := store.NewBulkBatch(env.Store)
+		tx := document.CommonTx{
+			Height: height,
+			TxHash: fmt.Sprintf("tx%d", height),
+			Signers: []document.Signer{
+				{Address: "a", PubKey: "pa", Sequence: uint64(height)},
+				{Address: "b", PubKey: "pb"},
+			},
+		}
+		handleTx(tx, batch)
+		if err := batch.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := document.QueryAccount(env.Store, "a")
+	if err != nil || a.PubKey != "pa" || a.PubKeyHeight != 3 || a.Height != 1 {
+		t.Errorf("account a = %+v, %v", a, err)
+	}
+	b, err := document.QueryAccount(env.Store, "b")
+	if err != nil || b.PubKey != "pb" || b.PubKeyHeight != 3 {
+		t.Errorf("account b = %+v, %v", b, err)
+	}
+
+	// pubkey isn't recorded when fence of batch fails, i.e. task of block is lost
+	batch := store.NewBulkBatch(env.Store)
+	handleTx(document.CommonTx{Height: 2, TxHash: "tx2", Signers: []document.Signer{{Address: "a", PubKey: "pc"}}}, batch)
+	batch.AddFence(txn.Op{C: document.CollectionNameSyncTask, Id: bson.NewObjectId(), Assert: txn.DocExists})
+	if err := batch.Commit(); err != txn.ErrAborted {
+		t.Fatalf("Commit() of aborted batch, err = %v", err)
+	}
+	if a, err := document.QueryAccount(env.Store, "a"); err != nil || a.PubKey != "pa" || a.PubKeyHeight != 3 {
+		t.Errorf("account a after aborted batch = %+v, %v", a, err)
+	}
+
+	var tx document.CommonTx
+	if err := env.Store.Find(tx.Name(), nil).One(&tx); err != nil || len(tx.Signers) != 2 {
+		t.Errorf("signers of tx = %+v, %v", tx.Signers, err)
+	}
+
+	// account which is created in range is removed, pubkey of account which is created before range is cleared
+	if err := rollbackDocs(env, 2, 10); err != nil {
+		t.Fatal(err)
+	}
+	a, err = document.QueryAccount(env.Store, "a")
+	if err != nil || a.PubKey != "" || a.PubKeyHeight != 0 {
+		t.Errorf("account a after rollback = %+v, %v", a, err)
+	}
+	if _, err := document.QueryAccount(env.Store, "b"); err != store.ErrNotFound {
+		t.Errorf("account b after rollback, err = %v", err)
+	}
+}
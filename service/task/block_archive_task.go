@@ -0,0 +1,70 @@
+package task
+
+import (
+	"time"
+
+	conf "github.com/irisnet/irishub-sync/conf/server"
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store/document"
+)
+
+// archive blocks which are older than retention policy,
+// archived blocks only keep header in db and their content is moved into archive files
+func archiveBlocks(env Env) {
+	var (
+		methodName = "ArchiveBlocks"
+		blockModel document.Block
+	)
+	logger.Info("Start", logger.String("method", methodName))
+
+	height, err := getArchiveHeight(env, conf.ArchiveKeepBlocks, conf.ArchiveKeepDays, time.Now())
+	if err != nil {
+		logger.Error("get archive height fail", logger.String("err", err.Error()))
+		return
+	}
+	if height <= 0 {
+		return
+	}
+
+	num, err := blockModel.Archive(env.Store, height)
+	if err != nil {
+		logger.Error("archive blocks fail", logger.Int64("height", height), logger.String("err", err.Error()))
+		return
+	}
+	logger.Info("End", logger.String("method", methodName), logger.Int64("height", height), logger.Int("num", num))
+}
+
+// get max height of blocks which should be archived,
+// block is archived when it's older than latest keepBlocks blocks or keepDays days
+func getArchiveHeight(env Env, keepBlocks int64, keepDays int, now time.Time) (int64, error) {
+	var (
+		blockModel document.Block
+		height     int64
+	)
+
+	if keepBlocks > 0 {
+		maxHeight, err := blockModel.GetMaxHeight(env.Store)
+		if err != nil {
+			return 0, err
+		}
+		height = maxHeight - keepBlocks
+	}
+	if keepDays > 0 {
+		h, err := blockModel.GetMaxHeightBefore(env.Store, now.AddDate(0, 0, -keepDays))
+		if err != nil {
+			return 0, err
+		}
+		if h > height {
+			height = h
+		}
+	}
+	return height, nil
+}
+
+func MakeArchiveBlockTask(env Env) Task {
+	return NewLockTaskFromEnv(conf.CronArchiveBlock, "archive_block_lock", func() {
+		logger.Debug("========================task's trigger [ArchiveBlocks] begin===================")
+		archiveBlocks(env)
+		logger.Debug("========================task's trigger [ArchiveBlocks] end===================")
+	})
+}
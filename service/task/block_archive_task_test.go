@@ -0,0 +1,98 @@
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	dbconf "github.com/irisnet/irishub-sync/conf/db"
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func Test_archiveBlocks(t *testing.T) {
+	var blockModel document.Block
+
+	env := newTestEnv(&stubNode{})
+
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(v string) { dbconf.ArchiveDir = v }(dbconf.ArchiveDir)
+	dbconf.ArchiveDir = dir
+
+	now := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	for height := int64(1); height <= 1200; height++ {
+		block := document.Block{
+			Height: height,
+			Hash:   fmt.Sprintf("block%d", height),
+			Time:   now.Add(time.Duration(height-1200) * time.Hour),
+			Block: document.BlockContent{LastCommit: document.Commit{Precommits: []document.Vote{
+				{ValidatorAddress: "a"}, {ValidatorAddress: fmt.Sprintf("v%d", height%2)},
+			}}},
+		}
+		if err := env.Store.Insert(blockModel.Name(), block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := blockModel.CalculateValidatorPreCommit(env.Store, 900, 1100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		keepBlocks int64
+		keepDays   int
+		want       int64
+	}{
+		{keepBlocks: 0, keepDays: 0, want: 0},
+		{keepBlocks: 200, keepDays: 0, want: 1000},
+		{keepBlocks: 200, keepDays: 10, want: 1000},
+		{keepBlocks: 100, keepDays: 0, want: 1100},
+	}
+	for _, tt := range tests {
+		height, err := getArchiveHeight(env, tt.keepBlocks, tt.keepDays, now)
+		if err != nil || height != tt.want {
+			t.Errorf("getArchiveHeight(%v, %v) = %v, %v, want %v", tt.keepBlocks, tt.keepDays, height, err, tt.want)
+		}
+	}
+
+	// blocks are archived in two runs, second run merges blocks into same archive file
+	for _, height := range []int64{1000, 1100, 1100} {
+		if _, err := blockModel.Archive(env.Store, height); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var slim document.Block
+	if err := env.Store.Find(blockModel.Name(), bson.M{"height": 1050}).One(&slim); err != nil {
+		t.Fatal(err)
+	}
+	if !slim.Archived || slim.Hash != "block1050" || len(slim.Block.LastCommit.Precommits) != 0 {
+		t.Errorf("slim block = %+v", slim)
+	}
+	if n, _ := env.Store.Find(blockModel.Name(), bson.M{document.Block_Field_Archived: true}).Count(); n != 1100 {
+		t.Errorf("got %v archived blocks, want 1100", n)
+	}
+
+	// archived blocks are rehydrated from archive files
+	block, err := blockModel.GetBlockByHeight(env.Store, 1050)
+	if err != nil || len(block.Block.LastCommit.Precommits) != 2 {
+		t.Errorf("rehydrated block = %+v, %v", block, err)
+	}
+	after, err := blockModel.CalculateValidatorPreCommit(env.Store, 900, 1100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []document.ResValidatorPreCommits{
+		{Address: "a", PreCommitsNum: 200},
+		{Address: "v0", PreCommitsNum: 100},
+		{Address: "v1", PreCommitsNum: 100},
+	}
+	if len(before) != 3 || before[0] != want[0] || fmt.Sprint(after) != fmt.Sprint(want) {
+		t.Errorf("precommits before archive = %v, after archive = %v, want %v", before, after, want)
+	}
+}
@@ -0,0 +1,209 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	conf "github.com/irisnet/irishub-sync/conf/server"
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/types"
+	"github.com/irisnet/irishub-sync/util/helper"
+)
+
+const (
+	blockWatcherSubscriber = "irishub-sync-block-watcher"
+
+	// max interval between two NewBlock events, subscription is treated as dropped
+	// when no event received during this interval
+	newBlockEventTimeout = 30 * time.Second
+	subscribeTimeout     = 10 * time.Second
+	resubscribeInterval  = 5 * time.Second
+	// interval of polling latest height when subscription is unavailable
+	pollLatestHeightInterval = 2 * time.Second
+)
+
+var (
+	watcher          = newBlockWatcher()
+	startWatcherOnce sync.Once
+)
+
+// blockWatcher keeps latest block height of blockchain by subscribing NewBlock event through websocket.
+// once subscription dropped, it resubscribes automatically, and polls latest height
+// from node until subscription recovered.
+// blocks missed during subscription dropped are not lost, because follow task syncs block one by one
+// from it's current height, and if follow task falls behind too much, it becomes invalid
+// and catch up tasks are created by create task.
+type blockWatcher struct {
+	mutex        sync.RWMutex
+	latestHeight int64
+	subscribed   bool
+	updated      chan struct{} // closed when latest height updated
+}
+
+func newBlockWatcher() *blockWatcher {
+	return &blockWatcher{
+		updated: make(chan struct{}),
+	}
+}
+
+// start block watcher until ctx is done, only first invoke takes effect
+func startBlockWatcher(ctx context.Context, env Env) {
+	startWatcherOnce.Do(func() {
+		go watcher.subscribe(ctx)
+		go watcher.poll(ctx, env)
+	})
+}
+
+// get latest block height known by watcher
+func (w *blockWatcher) LatestHeight() int64 {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.latestHeight
+}
+
+// wait until latest block height reaches given height, timeout or ctx is done,
+// return latest block height
+func (w *blockWatcher) WaitHeight(ctx context.Context, height int64, timeout time.Duration) int64 {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		w.mutex.RLock()
+		latestHeight, updated := w.latestHeight, w.updated
+		w.mutex.RUnlock()
+
+		if latestHeight >= height {
+			return latestHeight
+		}
+
+		select {
+		case <-updated:
+		case <-timer.C:
+			return w.LatestHeight()
+		case <-ctx.Done():
+			return w.LatestHeight()
+		}
+	}
+}
+
+func (w *blockWatcher) updateLatestHeight(height int64) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	// height of event may be less than latest height polled from other node
+	if height <= w.latestHeight {
+		return
+	}
+	w.latestHeight = height
+	close(w.updated)
+	w.updated = make(chan struct{})
+}
+
+func (w *blockWatcher) setSubscribed(subscribed bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.subscribed = subscribed
+}
+
+func (w *blockWatcher) isSubscribed() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.subscribed
+}
+
+// subscribe NewBlock event, resubscribe when subscription dropped
+func (w *blockWatcher) subscribe(ctx context.Context) {
+	for {
+		err := w.watchNewBlock(ctx)
+		w.setSubscribed(false)
+		select {
+		case <-ctx.Done():
+			logger.Info("block watcher stopped")
+			return
+		default:
+		}
+
+		logger.Warn("NewBlock subscription dropped, now resubscribe",
+			logger.String("err", err.Error()), logger.Duration("interval", resubscribeInterval))
+		time.Sleep(resubscribeInterval)
+	}
+}
+
+func (w *blockWatcher) watchNewBlock(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("watch NewBlock event panic: %v", r)
+		}
+	}()
+
+	client := helper.NewEventClient()
+	network, err := client.Network()
+	if err != nil {
+		return err
+	}
+	if network != conf.ChainId {
+		return fmt.Errorf("network of node %v is %v, mismatch chain id %v", client.Id, network, conf.ChainId)
+	}
+	if err := client.Start(); err != nil {
+		return err
+	}
+	defer client.Stop()
+
+	out := make(chan interface{}, 10)
+	subscribeCtx, cancel := context.WithTimeout(ctx, subscribeTimeout)
+	defer cancel()
+	if err := client.Subscribe(subscribeCtx, blockWatcherSubscriber, types.EventQueryNewBlock, out); err != nil {
+		return err
+	}
+	defer client.UnsubscribeAll(context.Background(), blockWatcherSubscriber)
+
+	w.setSubscribed(true)
+	logger.Info("subscribe NewBlock event success", logger.String("node", client.Id))
+
+	timer := time.NewTimer(newBlockEventTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case event, ok := <-out:
+			if !ok {
+				return fmt.Errorf("NewBlock event channel closed")
+			}
+			if data, ok := event.(types.EventDataNewBlock); ok && data.Block != nil {
+				w.updateLatestHeight(data.Block.Height)
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(newBlockEventTimeout)
+		case <-timer.C:
+			return fmt.Errorf("no NewBlock event received in %v", newBlockEventTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// poll latest height from node when subscription is unavailable
+func (w *blockWatcher) poll(ctx context.Context, env Env) {
+	ticker := time.NewTicker(pollLatestHeightInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !w.isSubscribed() {
+			height, err := getBlockChainLatestHeight(env)
+			if err != nil {
+				logger.Error("get block chain latest height fail", logger.String("err", err.Error()))
+			} else {
+				w.updateLatestHeight(height)
+			}
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func Test_blockWatcher_WaitHeight(t *testing.T) {
+	w := newBlockWatcher()
+	w.updateLatestHeight(10)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		w.updateLatestHeight(12)
+	}()
+
+	if height := w.WaitHeight(context.Background(), 12, 5*time.Second); height != 12 {
+		t.Fatalf("want latest height 12, got %v", height)
+	}
+	if height := w.WaitHeight(context.Background(), 13, 100*time.Millisecond); height != 12 {
+		t.Fatalf("want latest height 12 after timeout, got %v", height)
+	}
+
+	// height of event which is less than latest height should be ignored
+	w.updateLatestHeight(11)
+	if height := w.LatestHeight(); height != 12 {
+		t.Fatalf("want latest height 12, got %v", height)
+	}
+}
@@ -0,0 +1,53 @@
+package task
+
+import (
+	"fmt"
+
+	conf "github.com/irisnet/irishub-sync/conf/server"
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store/document"
+	"github.com/irisnet/irishub-sync/util/helper"
+	"gopkg.in/mgo.v2"
+)
+
+// verify nodes and stored blocks belong to configured chain,
+// engine shouldn't run when they mismatch, otherwise blocks of other chain are written into db
+func VerifyChainIdentity(env Env) error {
+	var (
+		blockModel document.Block
+	)
+
+	if err := helper.VerifyNodesNetwork(conf.ChainId); err != nil {
+		return err
+	}
+
+	block, err := blockModel.GetFirstBlock(env.Store)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	if block.Meta.Header.ChainID != conf.ChainId {
+		return fmt.Errorf("chain id of stored block is %v, mismatch chain id %v",
+			block.Meta.Header.ChainID, conf.ChainId)
+	}
+
+	// first stored block is genesis block unless sync started from configured start height
+	client := env.Nodes.GetClient()
+	defer client.Release()
+	height := block.Height
+	res, err := client.Block(&height)
+	if err != nil {
+		logger.Warn("can't get first stored block from node, skip verifying it's hash",
+			logger.Int64("height", height), logger.String("err", err.Error()))
+		return nil
+	}
+	if hash := helper.BuildHex(res.BlockMeta.BlockID.Hash); hash != block.Hash {
+		return fmt.Errorf("hash of stored block %v at height %v mismatch hash of block on node %v",
+			block.Hash, height, hash)
+	}
+
+	logger.Info("chain identity verified", logger.String("chain_id", conf.ChainId))
+	return nil
+}
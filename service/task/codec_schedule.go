@@ -0,0 +1,66 @@
+package task
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	conf "github.com/irisnet/irishub-sync/conf/server"
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store/document"
+	"github.com/irisnet/irishub-sync/types"
+)
+
+var (
+	// codec schedule which is loaded from software upgrades last time
+	upgradeSchedule     []types.CodecUpgrade
+	upgradeScheduleLock sync.Mutex
+)
+
+// switch codec at heights of passed software upgrade proposals,
+// it does nothing when CODEC_SCHEDULE is configured or no upgrade has passed.
+// error is returned when codec of any upgraded version isn't registered, blocks shouldn't be synced by wrong codec
+func LoadCodecSchedule(env Env) error {
+	if conf.CodecSchedule != "" {
+		return nil
+	}
+
+	proposals, err := document.QuerySoftwareUpgrades(env.Store)
+	if err != nil {
+		return fmt.Errorf("query software upgrade proposals fail, %v", err)
+	}
+	if len(proposals) == 0 {
+		return nil
+	}
+
+	upgrades := upgradeCodecSchedule(proposals)
+	upgradeScheduleLock.Lock()
+	defer upgradeScheduleLock.Unlock()
+	if reflect.DeepEqual(upgrades, upgradeSchedule) {
+		return nil
+	}
+	if err := types.SetCodecSchedule(upgrades); err != nil {
+		return fmt.Errorf("switch codec at software upgrades %v fail, %v", upgrades, err)
+	}
+	upgradeSchedule = upgrades
+	logger.Info("codec schedule is loaded from software upgrades", logger.Any("upgrades", upgrades))
+	return nil
+}
+
+// codec of protocol v0 is used from genesis, codec of upgraded version is used from switch height.
+// newest version is decoded by latest codec of irishub dependency unless codec of it is registered
+func upgradeCodecSchedule(proposals []document.Proposal) []types.CodecUpgrade {
+	upgrades := []types.CodecUpgrade{{Height: 1, Version: types.CodecVersion(0)}}
+	for _, v := range proposals {
+		last := &upgrades[len(upgrades)-1]
+		if v.SwitchHeight == last.Height {
+			last.Version = types.CodecVersion(v.Version)
+			continue
+		}
+		upgrades = append(upgrades, types.CodecUpgrade{Height: v.SwitchHeight, Version: types.CodecVersion(v.Version)})
+	}
+	if newest := &upgrades[len(upgrades)-1]; !types.IsCodecVersionRegistered(newest.Version) {
+		newest.Version = types.CodecVersionLatest
+	}
+	return upgrades
+}
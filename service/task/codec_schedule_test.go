@@ -0,0 +1,92 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/irisnet/irishub-sync/store/document"
+	"github.com/irisnet/irishub-sync/types"
+	"github.com/irisnet/irishub-sync/util/constant"
+	"github.com/irisnet/irishub/codec"
+)
+
+func TestLoadCodecSchedule(t *testing.T) {
+	env := newTestEnv(&stubNode{})
+
+	v1 := codec.New()
+	types.RegisterCodecVersion(types.CodecVersion(1), func() *codec.Codec { return v1 })
+	defer func() {
+		upgradeSchedule = nil
+		types.SetCodecSchedule([]types.CodecUpgrade{{Height: 1, Version: types.CodecVersionLatest}})
+	}()
+
+	proposals := []document.Proposal{
+		{ProposalId: 1, Status: constant.StatusPassed, Version: 1, SwitchHeight: 1000},
+		// upgrade which is rejected isn't used
+		{ProposalId: 2, Status: constant.StatusRejected, Version: 2, SwitchHeight: 500},
+		{ProposalId: 3, Status: constant.StatusPassed},
+	}
+	for _, v := range proposals {
+		if err := env.Store.Save(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := LoadCodecSchedule(env); err != nil {
+		t.Fatal(err)
+	}
+	v0 := types.GetCodecAt(1)
+	tests := []struct {
+		height int64
+		want   *codec.Codec
+	}{
+		{height: 1, want: v0},
+		{height: 500, want: v0},
+		{height: 999, want: v0},
+		{height: 1000, want: v1},
+		{height: 1001, want: v1},
+	}
+	for _, tt := range tests {
+		if got := types.GetCodecAt(tt.height); got != tt.want {
+			t.Errorf("codec at height %v isn't codec of protocol version at it", tt.height)
+		}
+	}
+	if v0 == v1 || types.GetCodec() != v1 {
+		t.Error("GetCodec() isn't codec of upgraded version")
+	}
+
+	// newest version v3 is decoded by latest codec, codec of v2 which isn't newest is missing
+	for _, v := range []document.Proposal{
+		{ProposalId: 4, Status: constant.StatusPassed, Version: 2, SwitchHeight: 2000},
+		{ProposalId: 5, Status: constant.StatusPassed, Version: 3, SwitchHeight: 3000},
+	} {
+		if err := env.Store.Save(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := LoadCodecSchedule(env); err == nil {
+		t.Error("codec schedule is loaded without codec of v2")
+	}
+	if types.GetCodecAt(2000) != v1 {
+		t.Error("codec schedule is changed by upgrades whose codec is missing")
+	}
+}
+
+func Test_upgradeCodecSchedule(t *testing.T) {
+	proposals := []document.Proposal{
+		{Version: 1, SwitchHeight: 100},
+		{Version: 2, SwitchHeight: 200},
+		// upgrade at same height replaces former one
+		{Version: 3, SwitchHeight: 200},
+	}
+	got := upgradeCodecSchedule(proposals)
+	// codec of newest version v3 isn't registered, it's decoded by latest codec
+	want := []types.CodecUpgrade{{Height: 1, Version: "v0"}, {Height: 100, Version: "v1"}, {Height: 200, Version: types.CodecVersionLatest}}
+	if len(got) != len(want) {
+		t.Fatalf("upgradeCodecSchedule() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("upgradeCodecSchedule() = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package task
+
+import (
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/util/helper"
+)
+
+// Env is store and nodes which tasks read and write, it's injected by sync engine,
+// so tasks can run against in-memory store and stub nodes in tests
+type Env struct {
+	Store store.Store
+	Nodes helper.Nodes
+}
+
+// env of store backend and client pool which are configured by env vars
+func DefaultEnv() Env {
+	return Env{Store: store.Backend(), Nodes: helper.PoolNodes()}
+}
@@ -0,0 +1,93 @@
+package task
+
+import (
+	"fmt"
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/types"
+	"github.com/irisnet/irishub-sync/util/helper"
+)
+
+const testNetwork = "irishub-test"
+
+// env of a test which runs against a new in-memory store and stub node,
+// so it doesn't depend on db and node
+func newTestEnv(node *stubNode) Env {
+	return Env{
+		Store: store.NewMemStore(),
+		Nodes: node,
+	}
+}
+
+// stub of node whose chain is made of empty blocks up to latest height,
+// blocks above fork height are replaced by blocks of another branch.
+// methods which aren't stubbed panic on the nil client
+type stubNode struct {
+	types.Client
+	latestHeight int64
+	forkHeight   int64
+}
+
+func (n *stubNode) GetClient() helper.NodeClient {
+	return n
+}
+
+func (n *stubNode) Release() {}
+
+// hash of block at height which is served by node
+func (n *stubNode) blockHash(height int64) types.HexBytes {
+	if n.forkHeight > 0 && height > n.forkHeight {
+		return types.HexBytes(fmt.Sprintf("fork%d", height))
+	}
+	return types.HexBytes(fmt.Sprintf("block%d", height))
+}
+
+func (n *stubNode) assertHeight(height *int64) error {
+	if height == nil || *height < 1 || *height > n.latestHeight {
+		return fmt.Errorf("height must be between 1 and latest height %v", n.latestHeight)
+	}
+	return nil
+}
+
+func (n *stubNode) Status() (*types.ResultStatus, error) {
+	status := &types.ResultStatus{}
+	status.NodeInfo.Network = testNetwork
+	status.SyncInfo.LatestBlockHeight = n.latestHeight
+	return status, nil
+}
+
+func (n *stubNode) Block(height *int64) (*types.ResultBlock, error) {
+	if err := n.assertHeight(height); err != nil {
+		return nil, err
+	}
+	meta := &types.BlockMeta{}
+	meta.BlockID.Hash = n.blockHash(*height)
+	meta.Header.ChainID = testNetwork
+	meta.Header.Height = *height
+	if *height > 1 {
+		meta.Header.LastBlockID.Hash = n.blockHash(*height - 1)
+	}
+
+	block := &types.Block{LastCommit: &types.Commit{}}
+	block.Header = meta.Header
+	return &types.ResultBlock{BlockMeta: meta, Block: block}, nil
+}
+
+func (n *stubNode) BlockResults(height *int64) (*types.ResultBlockResults, error) {
+	if err := n.assertHeight(height); err != nil {
+		return nil, err
+	}
+	return &types.ResultBlockResults{
+		Height: *height,
+		Results: &types.ABCIResponses{
+			EndBlock:   &types.ResponseEndBlock{},
+			BeginBlock: &types.ResponseBeginBlock{},
+		},
+	}, nil
+}
+
+func (n *stubNode) Validators(height *int64) (*types.ResultValidators, error) {
+	if err := n.assertHeight(height); err != nil {
+		return nil, err
+	}
+	return &types.ResultValidators{BlockHeight: *height}, nil
+}
@@ -0,0 +1,156 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2"
+)
+
+const (
+	// interval of reloading sync conf from db
+	reloadSyncConfInterval = 10 * time.Second
+)
+
+var (
+	confWatcher          = newSyncConfWatcher()
+	startConfWatcherOnce sync.Once
+)
+
+// syncConfWatcher reloads sync conf from db periodically, so that change of sync conf
+// is applied to create and execute task without restart.
+// invalid conf is rejected and current conf is kept, every change is recorded in sync_conf_history.
+type syncConfWatcher struct {
+	mutex    sync.RWMutex
+	conf     document.SyncConf
+	applied  bool
+	rejected *document.SyncConf // last rejected conf, avoid validating it repeatedly
+	loaded   chan struct{}      // closed when first valid conf applied
+}
+
+func newSyncConfWatcher() *syncConfWatcher {
+	return &syncConfWatcher{
+		loaded: make(chan struct{}),
+	}
+}
+
+// start sync conf watcher until ctx is done, only first invoke takes effect
+func startSyncConfWatcher(ctx context.Context, env Env) {
+	startConfWatcherOnce.Do(func() {
+		go confWatcher.watch(ctx, env)
+	})
+}
+
+// get current applied sync conf
+func (w *syncConfWatcher) Conf() document.SyncConf {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.conf
+}
+
+// wait until valid sync conf applied, return false when ctx is done before that
+func (w *syncConfWatcher) WaitLoaded(ctx context.Context) bool {
+	select {
+	case <-w.loaded:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (w *syncConfWatcher) watch(ctx context.Context, env Env) {
+	ticker := time.NewTicker(reloadSyncConfInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.reload(env); err != nil {
+			logger.Error("reload sync conf fail", logger.String("err", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Info("sync conf watcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// load sync conf from db and apply it when it's changed and valid
+func (w *syncConfWatcher) reload(env Env) error {
+	var (
+		syncConfModel document.SyncConf
+	)
+
+	conf, err := syncConfModel.GetConf(env.Store)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.RLock()
+	previous, applied, rejected := w.conf, w.applied, w.rejected
+	w.mutex.RUnlock()
+	if applied && conf == previous {
+		return nil
+	}
+	if rejected != nil && conf == *rejected {
+		return nil
+	}
+
+	validateErr := conf.Validate()
+	if err := recordSyncConfChange(env, conf, validateErr); err != nil {
+		logger.Error("record sync conf change fail", logger.String("err", err.Error()))
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if validateErr != nil {
+		w.rejected = &conf
+		return fmt.Errorf("sync conf is rejected, %v", validateErr)
+	}
+	w.conf, w.rejected = conf, nil
+	if !w.applied {
+		w.applied = true
+		close(w.loaded)
+	}
+	logger.Info("sync conf applied", logger.Any("previous", previous), logger.Any("current", conf))
+	return nil
+}
+
+// record change of sync conf into history, change which has been recorded by other instance is skipped
+func recordSyncConfChange(env Env, conf document.SyncConf, validateErr error) error {
+	var (
+		historyModel document.SyncConfHistory
+	)
+
+	latest, err := historyModel.GetLatest(env.Store)
+	if err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+	if err == nil && latest.Conf == conf {
+		return nil
+	}
+
+	history := document.SyncConfHistory{
+		Seq:          latest.Seq + 1,
+		PreviousConf: latest.Conf,
+		Conf:         conf,
+		Status:       document.SyncConfHistoryStatusApplied,
+		ChangeTime:   time.Now().Unix(),
+	}
+	if validateErr != nil {
+		history.Status = document.SyncConfHistoryStatusRejected
+		history.Err = validateErr.Error()
+	}
+
+	// seq is unique, insert fails when same seq has been recorded by other instance
+	err = env.Store.Save(history)
+	if err != nil && !mgo.IsDup(err) && err.Error() != "Record exists" {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package task
+
+import (
+	"time"
+
+	conf "github.com/irisnet/irishub-sync/conf/server"
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2"
+)
+
+const (
+	// num of heights which are audited by one query
+	auditWindowSize = int64(10000)
+	// gap won't be repaired any more when it has been repaired max times,
+	// e.g. tx in block can't be parsed, so num of txs always mismatch num_txs of block
+	maxGapRepairTimes = 3
+)
+
+// range of heights [start, end] which is being synced, end is 0 for follow task
+type syncingRange struct {
+	start int64
+	end   int64
+}
+
+// audit blocks and txs which have been synced,
+// record missing heights as gaps and create sync tasks to repair them
+func auditSyncGap(env Env) {
+	var (
+		methodName    = "AuditSyncGap"
+		syncConfModel document.SyncConf
+		syncTaskModel document.SyncTask
+		blockModel    document.Block
+	)
+	logger.Info("Start", logger.String("method", methodName))
+
+	syncConf, err := syncConfModel.GetConf(env.Store)
+	if err != nil {
+		logger.Error("get sync conf fail", logger.String("err", err.Error()))
+		return
+	}
+	minHeight, err := syncTaskModel.GetMinStartHeight(env.Store)
+	if err != nil {
+		logger.Error("get min start height of sync task fail", logger.String("err", err.Error()))
+		return
+	}
+	maxHeight, err := blockModel.GetMaxHeight(env.Store)
+	if err != nil {
+		logger.Error("get max height of block fail", logger.String("err", err.Error()))
+		return
+	}
+	if minHeight == 0 || maxHeight < minHeight {
+		return
+	}
+
+	syncingRanges, err := getSyncingRanges(env)
+	if err != nil {
+		logger.Error("get syncing ranges fail", logger.String("err", err.Error()))
+		return
+	}
+
+	gaps, err := findSyncGaps(env, minHeight, maxHeight, syncingRanges)
+	if err != nil {
+		logger.Error("find sync gaps fail", logger.String("err", err.Error()))
+		return
+	}
+	for _, gap := range gaps {
+		repairSyncGap(env, gap, syncConf.BlockNumPerWorkerHandle)
+	}
+
+	logger.Info("End", logger.String("method", methodName), logger.Int("gaps", len(gaps)))
+}
+
+// get height ranges which are being synced by unfinished tasks,
+// blocks in these ranges are not gaps
+func getSyncingRanges(env Env) ([]syncingRange, error) {
+	var (
+		syncTaskModel document.SyncTask
+		ranges        []syncingRange
+	)
+
+	tasks, err := syncTaskModel.QueryAll(env.Store, []string{
+		document.SyncTaskStatusUnHandled,
+		document.SyncTaskStatusUnderway,
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		start := task.StartHeight
+		if task.CurrentHeight != 0 {
+			start = task.CurrentHeight + 1
+		}
+		ranges = append(ranges, syncingRange{start: start, end: task.EndHeight})
+	}
+	return ranges, nil
+}
+
+func isSyncing(height int64, ranges []syncingRange) bool {
+	for _, r := range ranges {
+		if height >= r.start && (r.end == 0 || height <= r.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// find gaps in [minHeight, maxHeight] by window, heights of window are checked one by one
+// only when num of blocks or txs in window mismatch
+func findSyncGaps(env Env, minHeight, maxHeight int64, syncingRanges []syncingRange) ([]document.SyncGap, error) {
+	var (
+		blockModel       document.Block
+		txModel          document.CommonTx
+		undecodedTxModel document.UndecodedTx
+		gaps             []document.SyncGap
+	)
+
+	for start := minHeight - 1; start < maxHeight; start += auditWindowSize {
+		end := start + auditWindowSize
+		if end > maxHeight {
+			end = maxHeight
+		}
+
+		numBlocks, numTxs, err := blockModel.StatByHeightRange(env.Store, start, end)
+		if err != nil {
+			return nil, err
+		}
+		// txs which can't be decoded are kept in tx_undecoded, they are counted as stored txs
+		storedNumTxs, err := txModel.CountByHeightRange(env.Store, start, end)
+		if err != nil {
+			return nil, err
+		}
+		numUndecodedTxs, err := undecodedTxModel.CountByHeightRange(env.Store, start, end)
+		if err != nil {
+			return nil, err
+		}
+		storedNumTxs += numUndecodedTxs
+		if numBlocks == end-start && numTxs == storedNumTxs {
+			continue
+		}
+
+		blocks, err := blockModel.QueryNumTxsByHeightRange(env.Store, start, end)
+		if err != nil {
+			return nil, err
+		}
+		txCounts, err := txModel.CountGroupByHeight(env.Store, start, end)
+		if err != nil {
+			return nil, err
+		}
+		undecodedTxCounts, err := undecodedTxModel.CountGroupByHeight(env.Store, start, end)
+		if err != nil {
+			return nil, err
+		}
+		for height, num := range undecodedTxCounts {
+			txCounts[height] += num
+		}
+		blockNumTxs := make(map[int64]int64, len(blocks))
+		for _, v := range blocks {
+			blockNumTxs[v.Height] = v.NumTxs
+		}
+
+		for height := start + 1; height <= end; height++ {
+			if isSyncing(height, syncingRanges) {
+				continue
+			}
+			if num, ok := blockNumTxs[height]; !ok {
+				gaps = appendGapHeight(gaps, height, document.SyncGapTypeBlock)
+			} else if num != txCounts[height] {
+				gaps = appendGapHeight(gaps, height, document.SyncGapTypeTx)
+			}
+		}
+	}
+
+	return gaps, nil
+}
+
+// append height into gaps, height is merged into last gap
+// when they are continuous and have same type
+func appendGapHeight(gaps []document.SyncGap, height int64, gapType string) []document.SyncGap {
+	if len(gaps) > 0 {
+		last := &gaps[len(gaps)-1]
+		if last.Type == gapType && last.EndHeight+1 == height {
+			last.EndHeight = height
+			return gaps
+		}
+	}
+
+	return append(gaps, document.SyncGap{
+		StartHeight: height,
+		EndHeight:   height,
+		Type:        gapType,
+	})
+}
+
+// remove documents in gap and create sync tasks to re-sync it
+func repairSyncGap(env Env, gap document.SyncGap, blockNumPerWorker int64) {
+	var (
+		syncGapModel document.SyncGap
+	)
+
+	recordedGap, err := syncGapModel.GetGap(env.Store, gap)
+	if err == nil {
+		gap = recordedGap
+	} else if err == mgo.ErrNotFound {
+		gap.DetectTime = time.Now().Unix()
+	} else {
+		logger.Error("get sync gap fail", logger.Any("gap", gap), logger.String("err", err.Error()))
+		return
+	}
+
+	if gap.RepairTimes >= maxGapRepairTimes {
+		logger.Warn("sync gap has been repaired max times, skip it", logger.Any("gap", gap))
+		return
+	}
+	logger.Info("repair sync gap", logger.Any("gap", gap))
+
+	// documents in gap should be removed before it's re-synced, otherwise
+	// block and txs which have been saved can't be saved again
+	if err := removeGapDocs(env, gap); err != nil {
+		logger.Error("remove documents in sync gap fail", logger.Any("gap", gap), logger.String("err", err.Error()))
+		return
+	}
+	if err := createRangeTasks(env, gap.StartHeight, gap.EndHeight, blockNumPerWorker); err != nil {
+		logger.Error("create repair task fail", logger.Any("gap", gap), logger.String("err", err.Error()))
+		return
+	}
+
+	gap.RepairTimes++
+	gap.LastRepairAt = time.Now().Unix()
+	if err := env.Store.SaveOrUpdate(gap); err != nil {
+		logger.Error("save sync gap fail", logger.Any("gap", gap), logger.String("err", err.Error()))
+	}
+}
+
+// only rows of block and txs in gap are removed, txs of gap which have been saved
+// aren't reverted, because their modification of proposals and delegators is applied again
+// by the same handlers when gap is re-synced
+func removeGapDocs(env Env, gap document.SyncGap) error {
+	var (
+		txModel document.CommonTx
+		hashes  []string
+	)
+
+	startHeight := gap.StartHeight - 1
+	txs, err := txModel.QueryByHeightRange(env.Store, startHeight, gap.EndHeight)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		hashes = append(hashes, tx.TxHash)
+	}
+
+	batch := store.NewBatch(env.Store)
+	if err := removeBlockDocs(startHeight, gap.EndHeight, hashes, batch); err != nil {
+		return err
+	}
+	return batch.Commit()
+}
+
+func MakeAuditSyncGapTask(env Env) Task {
+	return NewLockTaskFromEnv(conf.CronAuditSyncGap, "audit_sync_gap_lock", func() {
+		logger.Debug("========================task's trigger [AuditSyncGap] begin===================")
+		auditSyncGap(env)
+		logger.Debug("========================task's trigger [AuditSyncGap] end===================")
+	})
+}
@@ -0,0 +1,152 @@
+package task
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	"github.com/irisnet/irishub-sync/util/constant"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func Test_appendGapHeight(t *testing.T) {
+	var gaps []document.SyncGap
+	gaps = appendGapHeight(gaps, 10, document.SyncGapTypeBlock)
+	gaps = appendGapHeight(gaps, 11, document.SyncGapTypeBlock)
+	gaps = appendGapHeight(gaps, 12, document.SyncGapTypeTx)
+	gaps = appendGapHeight(gaps, 15, document.SyncGapTypeTx)
+
+	want := []document.SyncGap{
+		{StartHeight: 10, EndHeight: 11, Type: document.SyncGapTypeBlock},
+		{StartHeight: 12, EndHeight: 12, Type: document.SyncGapTypeTx},
+		{StartHeight: 15, EndHeight: 15, Type: document.SyncGapTypeTx},
+	}
+	if len(gaps) != len(want) {
+		t.Fatalf("want %v gaps, got %v", len(want), len(gaps))
+	}
+	for i := range want {
+		if gaps[i] != want[i] {
+			t.Errorf("want gap %v, got %v", want[i], gaps[i])
+		}
+	}
+}
+
+func Test_isSyncing(t *testing.T) {
+	ranges := []syncingRange{
+		{start: 1, end: 100},
+		{start: 201, end: 0},
+	}
+	tests := []struct {
+		height int64
+		want   bool
+	}{
+		{height: 50, want: true},
+		{height: 150, want: false},
+		{height: 1000, want: true},
+	}
+	for _, tt := range tests {
+		if got := isSyncing(tt.height, ranges); got != tt.want {
+			t.Errorf("height %v: want %v, got %v", tt.height, tt.want, got)
+		}
+	}
+}
+
+func Test_findSyncGaps(t *testing.T) {
+	env := newTestEnv(&stubNode{})
+
+	// every block has one tx, tx of block 3 is undecoded, tx of block 4 and block 5 are missing
+	for height := int64(1); height <= 6; height++ {
+		if height == 5 {
+			continue
+		}
+		docs := []store.Docs{document.Block{Height: height, Hash: fmt.Sprintf("block%d", height), NumTxs: 1}}
+		switch height {
+		case 3:
+			docs = append(docs, document.UndecodedTx{Height: height, TxHash: fmt.Sprintf("tx%d", height)})
+		case 4:
+		default:
+			docs = append(docs, document.CommonTx{Height: height, TxHash: fmt.Sprintf("tx%d", height)})
+		}
+		for _, doc := range docs {
+			if err := env.Store.Save(doc); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	gaps, err := findSyncGaps(env, 1, 6, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []document.SyncGap{
+		{StartHeight: 4, EndHeight: 4, Type: document.SyncGapTypeTx},
+		{StartHeight: 5, EndHeight: 5, Type: document.SyncGapTypeBlock},
+	}
+	if len(gaps) != len(want) {
+		t.Fatalf("gaps = %v, want %v", gaps, want)
+	}
+	for i := range want {
+		if gaps[i] != want[i] {
+			t.Errorf("want gap %v, got %v", want[i], gaps[i])
+		}
+	}
+
+	// window whose txs are all stored, including undecoded ones, has no gap
+	if gaps, err := findSyncGaps(env, 1, 3, nil); err != nil || len(gaps) != 0 {
+		t.Errorf("gaps = %v, err = %v, want no gap", gaps, err)
+	}
+}
+
+func Test_repairSyncGap(t *testing.T) {
+	var (
+		blockModel   document.Block
+		txModel      document.CommonTx
+		syncGapModel document.SyncGap
+	)
+
+	env := newTestEnv(&stubNode{})
+
+	for height := int64(1); height <= 4; height++ {
+		docs := []store.Docs{
+			document.Block{Height: height, Hash: fmt.Sprintf("block%d", height)},
+			document.CommonTx{
+				Height:     height,
+				TxHash:     fmt.Sprintf("tx%d", height),
+				Type:       constant.TxTypeSubmitProposal,
+				ProposalId: uint64(height),
+			},
+			document.Proposal{ProposalId: uint64(height)},
+		}
+		for _, doc := range docs {
+			if err := env.Store.Save(doc); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	gap := document.SyncGap{StartHeight: 2, EndHeight: 3, Type: document.SyncGapTypeTx, RepairTimes: 1}
+	if err := env.Store.Save(gap); err != nil {
+		t.Fatal(err)
+	}
+
+	repairSyncGap(env, document.SyncGap{StartHeight: 2, EndHeight: 3, Type: document.SyncGapTypeTx}, 10)
+
+	if blocks, err := blockModel.QueryNumTxsByHeightRange(env.Store, 0, 4); err != nil || len(blocks) != 2 {
+		t.Errorf("blocks after repair = %v, err = %v, want blocks 1 and 4", blocks, err)
+	}
+	if txs, err := txModel.QueryByHeightRange(env.Store, 0, 4); err != nil || len(txs) != 2 {
+		t.Errorf("txs after repair = %v, err = %v, want txs 1 and 4", txs, err)
+	}
+	// proposals submitted by txs in gap are saved again when gap is re-synced, so they aren't reverted
+	for id := uint64(1); id <= 4; id++ {
+		if _, err := document.QueryProposal(env.Store, id); err != nil {
+			t.Errorf("proposal %v is reverted, err = %v", id, err)
+		}
+	}
+	if n, err := env.Store.Find(document.CollectionNameSyncTask, bson.M{"start_height": 2, "end_height": 3}).Count(); err != nil || n != 1 {
+		t.Errorf("repair tasks = %v, err = %v", n, err)
+	}
+	if recorded, err := syncGapModel.GetGap(env.Store, gap); err != nil || recorded.RepairTimes != 2 {
+		t.Errorf("recorded gap = %+v, err = %v", recorded, err)
+	}
+}
@@ -3,34 +3,40 @@ package task
 import (
 	conf "github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
-	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
 	"github.com/irisnet/irishub-sync/util/constant"
 	"github.com/irisnet/irishub-sync/util/helper"
 )
 
-func syncProposalStatus() {
+func syncProposalStatus(env Env) {
 	var status = []string{constant.StatusDepositPeriod, constant.StatusVotingPeriod}
-	if proposals, err := document.QueryByStatus(status); err == nil {
+	if proposals, err := document.QueryByStatus(env.Store, status); err == nil {
 		for _, proposal := range proposals {
 			propo, err := helper.GetProposal(proposal.ProposalId)
 			if err != nil {
-				store.Delete(proposal)
+				env.Store.Delete(proposal)
 				return
 			}
 			if propo.Status != proposal.Status {
 				propo.SubmitTime = proposal.SubmitTime
 				propo.Votes = proposal.Votes
-				store.SaveOrUpdate(propo)
+				propo.Version = proposal.Version
+				propo.SwitchHeight = proposal.SwitchHeight
+				env.Store.SaveOrUpdate(propo)
+				if propo.Status == constant.StatusPassed && propo.SwitchHeight > 0 {
+					if err := LoadCodecSchedule(env); err != nil {
+						logger.Error("load codec schedule fail", logger.String("err", err.Error()))
+					}
+				}
 			}
 		}
 	}
 }
 
-func MakeSyncProposalStatusTask() Task {
+func MakeSyncProposalStatusTask(env Env) Task {
 	return NewLockTaskFromEnv(conf.SyncProposalStatus, "sync_proposal_status_lock", func() {
 		logger.Debug("========================task's trigger [SyncProposalStatus] begin===================")
-		syncProposalStatus()
+		syncProposalStatus(env)
 		logger.Debug("========================task's trigger [SyncProposalStatus] end===================")
 	})
 }
@@ -0,0 +1,140 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2"
+)
+
+const (
+	// interval of updating sync status
+	updateSyncStatusInterval = 30 * time.Second
+)
+
+// update sync status periodically until ctx is done,
+// it runs from engine start rather than as cron task, because progress of catch up matters most
+func StartSyncStatusTask(ctx context.Context, env Env) {
+	ticker := time.NewTicker(updateSyncStatusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Stop sync status task")
+			return
+		case <-ticker.C:
+		}
+
+		if err := updateSyncStatus(env); err != nil {
+			logger.Error("update sync status fail", logger.String("err", err.Error()))
+		}
+	}
+}
+
+func updateSyncStatus(env Env) error {
+	var (
+		syncTaskModel   document.SyncTask
+		syncStatusModel document.SyncStatus
+	)
+
+	tasks, err := syncTaskModel.QueryAll(env.Store, nil, "")
+	if err != nil {
+		return err
+	}
+	latestHeight, err := getBlockChainLatestHeight(env)
+	if err != nil {
+		return err
+	}
+	previous, err := syncStatusModel.GetStatus(env.Store)
+	if err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+
+	status := buildSyncStatus(tasks, latestHeight, confWatcher.Conf().HaltHeight, previous, time.Now().Unix())
+	logger.Info("sync status",
+		logger.Int64("latest_height", status.LatestHeight),
+		logger.Int64("remaining_blocks", status.RemainingBlocks),
+		logger.Any("sync_rate", status.SyncRate),
+		logger.Int64("eta", status.Eta))
+
+	return env.Store.SaveOrUpdate(status)
+}
+
+// build sync status by tasks and latest height of blockchain,
+// sync rate is calculated by synced blocks since previous status
+func buildSyncStatus(tasks []document.SyncTask, latestHeight, haltHeight int64,
+	previous document.SyncStatus, now int64) document.SyncStatus {
+	var (
+		coveredHeight int64
+		// blocks above halt height are never synced
+		targetHeight = limitByHaltHeight(latestHeight, haltHeight)
+	)
+	status := document.SyncStatus{
+		LatestHeight: latestHeight,
+		Eta:          -1,
+		UpdateTime:   now,
+	}
+
+	for _, task := range tasks {
+		syncedHeight := task.CurrentHeight
+		if syncedHeight == 0 {
+			syncedHeight = task.StartHeight - 1
+		}
+		status.SyncedBlocks += syncedHeight - task.StartHeight + 1
+
+		unfinished := task.Status == document.SyncTaskStatusUnHandled || task.Status == document.SyncTaskStatusUnderway
+		if task.EndHeight != 0 && task.EndHeight > coveredHeight {
+			coveredHeight = task.EndHeight
+		}
+		// blocks above height of invalid follow task are covered by catch up tasks
+		if task.EndHeight == 0 && unfinished && syncedHeight > coveredHeight {
+			coveredHeight = syncedHeight
+		}
+		if !unfinished {
+			continue
+		}
+
+		progress := document.SyncTaskProgress{
+			TaskId:        task.ID.Hex(),
+			Type:          document.SyncTaskTypeCatchUp,
+			StartHeight:   task.StartHeight,
+			EndHeight:     task.EndHeight,
+			CurrentHeight: task.CurrentHeight,
+			WorkerId:      task.WorkerId,
+		}
+		endHeight := task.EndHeight
+		if endHeight == 0 {
+			progress.Type = document.SyncTaskTypeFollow
+			endHeight = targetHeight
+		}
+		if endHeight > syncedHeight {
+			progress.RemainingBlocks = endHeight - syncedHeight
+		}
+		if total := endHeight - task.StartHeight + 1; total > 0 {
+			progress.Progress = float64(total-progress.RemainingBlocks) / float64(total) * 100
+		}
+		if progress.Type == document.SyncTaskTypeCatchUp {
+			status.RemainingBlocks += progress.RemainingBlocks
+		}
+		status.Tasks = append(status.Tasks, progress)
+	}
+
+	// blocks which are not covered by any task are synced by tasks created later
+	if targetHeight > coveredHeight {
+		status.RemainingBlocks += targetHeight - coveredHeight
+	}
+
+	if previous.UpdateTime > 0 && now > previous.UpdateTime && status.SyncedBlocks > previous.SyncedBlocks {
+		status.SyncRate = float64(status.SyncedBlocks-previous.SyncedBlocks) / float64(now-previous.UpdateTime)
+	}
+	if status.RemainingBlocks == 0 {
+		status.Eta = 0
+	} else if status.SyncRate > 0 {
+		status.Eta = int64(float64(status.RemainingBlocks) / status.SyncRate)
+	}
+
+	return status
+}
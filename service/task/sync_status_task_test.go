@@ -0,0 +1,48 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/irisnet/irishub-sync/store/document"
+)
+
+func Test_buildSyncStatus(t *testing.T) {
+	tasks := []document.SyncTask{
+		{StartHeight: 1, EndHeight: 100, CurrentHeight: 100, Status: document.SyncTaskStatusCompleted},
+		{StartHeight: 101, EndHeight: 200, CurrentHeight: 150, Status: document.SyncTaskStatusUnderway},
+		{StartHeight: 201, EndHeight: 300, Status: document.SyncTaskStatusUnHandled},
+	}
+	previous := document.SyncStatus{SyncedBlocks: 100, UpdateTime: 1000}
+
+	status := buildSyncStatus(tasks, 350, 0, previous, 1010)
+
+	if status.SyncedBlocks != 150 {
+		t.Errorf("want synced blocks 150, got %v", status.SyncedBlocks)
+	}
+	// 50 blocks in underway task, 100 blocks in unhandled task and 50 blocks not covered by task
+	if status.RemainingBlocks != 200 {
+		t.Errorf("want remaining blocks 200, got %v", status.RemainingBlocks)
+	}
+	if status.SyncRate != 5 {
+		t.Errorf("want sync rate 5, got %v", status.SyncRate)
+	}
+	if status.Eta != 40 {
+		t.Errorf("want eta 40, got %v", status.Eta)
+	}
+	if len(status.Tasks) != 2 || status.Tasks[0].Progress != 50 || status.Tasks[1].Progress != 0 {
+		t.Errorf("unexpected progress of tasks %v", status.Tasks)
+	}
+
+	// follow task has synced to halt height
+	tasks = []document.SyncTask{
+		{StartHeight: 1, EndHeight: 300, CurrentHeight: 300, Status: document.SyncTaskStatusCompleted},
+		{StartHeight: 301, CurrentHeight: 320, Status: document.SyncTaskStatusUnderway},
+	}
+	status = buildSyncStatus(tasks, 350, 320, document.SyncStatus{}, 1010)
+	if status.RemainingBlocks != 0 || status.Eta != 0 {
+		t.Errorf("want no remaining blocks, got %v, eta %v", status.RemainingBlocks, status.Eta)
+	}
+	if status.Tasks[0].Type != document.SyncTaskTypeFollow || status.Tasks[0].Progress != 100 {
+		t.Errorf("unexpected progress of follow task %v", status.Tasks[0])
+	}
+}
@@ -1,46 +1,54 @@
 package task
 
 import (
+	"context"
 	serverConf "github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
-	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
-	"github.com/irisnet/irishub-sync/util/helper"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/mgo.v2/txn"
+	"sync"
 	"time"
 )
 
-func StartCreateTask() {
+// start create task until ctx is done
+func StartCreateTask(ctx context.Context, env Env) {
 	log := logger.GetLogger("StartCreateTask")
-	var (
-		syncConfModel           document.SyncConf
-		blockNumPerWorkerHandle int64
-	)
 
-	// get sync conf
-	syncConf, err := syncConfModel.GetConf()
-	if err != nil {
-		log.Fatal("Get sync conf failed", logger.String("err", err.Error()))
-	}
-	blockNumPerWorkerHandle = syncConf.BlockNumPerWorkerHandle
-	if blockNumPerWorkerHandle <= 0 {
-		log.Fatal("blockNumPerWorkerHandle should greater than 0")
+	// sync conf is reloaded by watcher when it's changed,
+	// every round of create task uses latest applied conf
+	startSyncConfWatcher(ctx, env)
+	if !confWatcher.WaitLoaded(ctx) {
+		log.Info("Stop create task before sync conf loaded")
+		return
 	}
 
-	log.Info("Start create task", logger.Any("sync conf", syncConf))
+	log.Info("Start create task", logger.Any("sync conf", confWatcher.Conf()))
 
 	// buffer channel to limit goroutine num
 	chanLimit := make(chan bool, serverConf.WorkerNumCreateTask)
 
+	var wg sync.WaitGroup
 	for {
-		chanLimit <- true
-		go createTask(blockNumPerWorkerHandle, chanLimit)
+		select {
+		case <-ctx.Done():
+			log.Info("Stop create task")
+			wg.Wait()
+			return
+		case chanLimit <- true:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				createTask(env, confWatcher.Conf(), chanLimit)
+			}()
+		}
 	}
 }
 
-func createTask(blockNumPerWorker int64, chanLimit chan bool) {
+func createTask(env Env, syncConf document.SyncConf, chanLimit chan bool) {
 	var (
+		blockNumPerWorker = syncConf.BlockNumPerWorkerHandle
 		syncTaskModel     document.SyncTask
 		syncTasks         []document.SyncTask
 		ops               []txn.Op
@@ -57,7 +65,7 @@ func createTask(blockNumPerWorker int64, chanLimit chan bool) {
 
 	// check valid follow task if exist
 	// status of valid follow task is unhandled or underway
-	validFollowTasks, err := syncTaskModel.QueryAll(
+	validFollowTasks, err := syncTaskModel.QueryAll(env.Store,
 		[]string{
 			document.SyncTaskStatusUnHandled,
 			document.SyncTaskStatusUnderway,
@@ -67,23 +75,32 @@ func createTask(blockNumPerWorker int64, chanLimit chan bool) {
 	}
 	if len(validFollowTasks) == 0 {
 		// get max end_height from sync_task
-		maxEndHeight, err := syncTaskModel.GetMaxBlockHeight()
+		maxEndHeight, err := syncTaskModel.GetMaxBlockHeight(env.Store)
 		if err != nil {
 			log.Error("Get max end_block failed", logger.String("err", err.Error()))
 			return
 		}
+		// blocks below start height are not synced
+		if maxEndHeight < syncConf.StartHeight-1 {
+			maxEndHeight = syncConf.StartHeight - 1
+		}
+		if syncConf.HaltHeight > 0 && maxEndHeight >= syncConf.HaltHeight {
+			log.Info("Halt height reached, no more sync task is created", logger.Int64("halt_height", syncConf.HaltHeight))
+			return
+		}
 
-		currentBlockHeight, err := getBlockChainLatestHeight()
+		currentBlockHeight, err := getBlockChainLatestHeight(env)
 		if err != nil {
 			log.Error("Get current block height failed", logger.String("err", err.Error()))
 			return
 		}
+		currentBlockHeight = limitByHaltHeight(currentBlockHeight, syncConf.HaltHeight)
 
 		if maxEndHeight+blockNumPerWorker <= currentBlockHeight {
 			syncTasks = createCatchUpTask(maxEndHeight, blockNumPerWorker, currentBlockHeight)
 			log.Info("Create catch up task during follow task not exist", logger.Int64("from", maxEndHeight+1), logger.Int64("to", currentBlockHeight))
 		} else {
-			finished, err := assertAllCatchUpTaskFinished()
+			finished, err := assertAllCatchUpTaskFinished(env)
 			if err != nil {
 				log.Error("AssertAllCatchUpTaskFinished failed", logger.String("err", err.Error()))
 				return
@@ -100,11 +117,12 @@ func createTask(blockNumPerWorker int64, chanLimit chan bool) {
 			followedHeight = followTask.StartHeight - 1
 		}
 
-		currentBlockHeight, err := getBlockChainLatestHeight()
+		currentBlockHeight, err := getBlockChainLatestHeight(env)
 		if err != nil {
 			log.Error("Get current block height failed", logger.String("err", err.Error()))
 			return
 		}
+		currentBlockHeight = limitByHaltHeight(currentBlockHeight, syncConf.HaltHeight)
 
 		if followedHeight+blockNumPerWorker <= currentBlockHeight {
 			syncTasks = createCatchUpTask(followedHeight, blockNumPerWorker, currentBlockHeight)
@@ -139,13 +157,18 @@ func createTask(blockNumPerWorker int64, chanLimit chan bool) {
 				"$set": bson.M{
 					"status": document.FollowTaskStatusInvalid,
 				},
+				// blocks above followed height are synced by catch up tasks,
+				// so writes of worker which is executing invalid follow task are rejected
+				"$inc": bson.M{
+					"fencing_token": 1,
+				},
 			},
 		}
 		ops = append(ops, op)
 	}
 
 	if len(ops) > 0 {
-		err := store.Txn(ops)
+		err := env.Store.Txn(ops)
 		if err != nil {
 			log.Error("Create sync task fail", logger.String("err", err.Error()))
 		} else {
@@ -155,8 +178,8 @@ func createTask(blockNumPerWorker int64, chanLimit chan bool) {
 }
 
 // get current block height
-func getBlockChainLatestHeight() (int64, error) {
-	client := helper.GetClient()
+func getBlockChainLatestHeight(env Env) (int64, error) {
+	client := env.Nodes.GetClient()
 	defer func() {
 		client.Release()
 	}()
@@ -169,6 +192,14 @@ func getBlockChainLatestHeight() (int64, error) {
 	return currentBlockHeight, nil
 }
 
+// blocks above halt height are not synced, so latest height which can be synced is limited by it
+func limitByHaltHeight(height, haltHeight int64) int64 {
+	if haltHeight > 0 && height > haltHeight {
+		return haltHeight
+	}
+	return height
+}
+
 func createCatchUpTask(maxEndHeight, blockNumPerWorker, currentBlockHeight int64) []document.SyncTask {
 	var (
 		syncTasks []document.SyncTask
@@ -189,14 +220,76 @@ func createCatchUpTask(maxEndHeight, blockNumPerWorker, currentBlockHeight int64
 	return syncTasks
 }
 
-func assertAllCatchUpTaskFinished() (bool, error) {
+// create catch up tasks which sync blocks in [startHeight, endHeight], range is split by blockNumPerWorker.
+// start_height and end_height of task are unique, so existed task which has same range with new task
+// is reset to unhandled and it's fencing token is increased instead of creating new one
+func createRangeTasks(env Env, startHeight, endHeight, blockNumPerWorker int64) error {
+	var (
+		syncTaskModel document.SyncTask
+		ops           []txn.Op
+	)
+
+	for start := startHeight; start <= endHeight; start += blockNumPerWorker {
+		end := start + blockNumPerWorker - 1
+		if end > endHeight {
+			end = endHeight
+		}
+
+		task, err := syncTaskModel.GetTaskByRange(env.Store, start, end)
+		if err == nil {
+			ops = append(ops, txn.Op{
+				C:      document.CollectionNameSyncTask,
+				Id:     task.ID,
+				Assert: txn.DocExists,
+				Update: bson.M{
+					"$set": bson.M{
+						"status":            document.SyncTaskStatusUnHandled,
+						"current_height":    0,
+						"worker_id":         "",
+						"last_update_time":  time.Now().Unix(),
+						"lease_expire_time": 0,
+					},
+					// writes of worker which is executing this task are rejected
+					"$inc": bson.M{
+						"fencing_token": 1,
+					},
+				},
+			})
+			continue
+		}
+		if err != mgo.ErrNotFound {
+			return err
+		}
+
+		objectId := bson.NewObjectId()
+		ops = append(ops, txn.Op{
+			C:      document.CollectionNameSyncTask,
+			Id:     objectId,
+			Assert: txn.DocMissing,
+			Insert: document.SyncTask{
+				ID:             objectId,
+				StartHeight:    start,
+				EndHeight:      end,
+				Status:         document.SyncTaskStatusUnHandled,
+				LastUpdateTime: time.Now().Unix(),
+			},
+		})
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+	return env.Store.Txn(ops)
+}
+
+func assertAllCatchUpTaskFinished(env Env) (bool, error) {
 	var (
 		syncTaskModel          document.SyncTask
 		allCatchUpTaskFinished = false
 	)
 
 	// assert all catch up task whether finished
-	tasks, err := syncTaskModel.QueryAll(
+	tasks, err := syncTaskModel.QueryAll(env.Store,
 		[]string{
 			document.SyncTaskStatusUnHandled,
 			document.SyncTaskStatusUnderway,
@@ -1,47 +1,66 @@
 package task
 
 import (
-	"github.com/irisnet/irishub-sync/store"
-	"os"
-	"sync"
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2/bson"
 	"testing"
 )
 
-func TestMain(m *testing.M) {
-	store.Start()
-
-	code := m.Run()
-
-	os.Exit(code)
-}
-
 func Test_createTask(t *testing.T) {
-	type args struct {
-		blockNumPerWorker int64
-		chanLimit         chan bool
-	}
+	var (
+		syncTaskModel document.SyncTask
+	)
 
-	chanLimit := make(chan bool, 2)
-	var wg sync.WaitGroup
+	node := &stubNode{latestHeight: 250}
+	env := newTestEnv(node)
+	syncConf := document.SyncConf{BlockNumPerWorkerHandle: 100, MaxWorkerSleepTime: 60}
 
 	tests := []struct {
 		name string
-		args args
+		// catch up tasks which are completed before create task
+		complete     bool
+		latestHeight int64
+		wantCatchUp  int
+		wantFollow   int
 	}{
-		{
-			name: "test create task",
-			args: args{
-				blockNumPerWorker: 100,
-				chanLimit:         chanLimit,
-			},
-		},
+		{name: "catch up tasks are created below latest height", latestHeight: 250, wantCatchUp: 2},
+		{name: "no task is created when catch up tasks aren't finished", latestHeight: 260, wantCatchUp: 2},
+		{name: "follow task is created after catch up tasks are finished", complete: true, latestHeight: 260, wantFollow: 1},
+		{name: "follow task is kept when it's near latest height", latestHeight: 290, wantFollow: 1},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			wg.Add(1)
+			if tt.complete {
+				if _, err := env.Store.RemoveAll(syncTaskModel.Name(), nil); err != nil {
+					t.Fatal(err)
+				}
+				if err := env.Store.Save(document.SyncTask{ID: bson.NewObjectId(), StartHeight: 1, EndHeight: 200, CurrentHeight: 200,
+					Status: document.SyncTaskStatusCompleted}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			node.latestHeight = tt.latestHeight
+
+			chanLimit := make(chan bool, 1)
 			chanLimit <- true
-			go createTask(tt.args.blockNumPerWorker, tt.args.chanLimit)
-			wg.Wait()
+			createTask(env, syncConf, chanLimit)
+
+			status := []string{document.SyncTaskStatusUnHandled, document.SyncTaskStatusUnderway}
+			catchUpTasks, err := syncTaskModel.QueryAll(env.Store, status, document.SyncTaskTypeCatchUp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			followTasks, err := syncTaskModel.QueryAll(env.Store, status, document.SyncTaskTypeFollow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(catchUpTasks) != tt.wantCatchUp || len(followTasks) != tt.wantFollow {
+				t.Errorf("catch up tasks = %v, follow tasks = %v, want %v catch up and %v follow tasks",
+					catchUpTasks, followTasks, tt.wantCatchUp, tt.wantFollow)
+			}
+			if len(followTasks) > 0 && followTasks[0].StartHeight != 201 {
+				t.Errorf("follow task starts at %v, want 201", followTasks[0].StartHeight)
+			}
 		})
 	}
 }
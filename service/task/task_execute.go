@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	serverConf "github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
@@ -17,41 +18,48 @@ import (
 	"time"
 )
 
-func StartExecuteTask() {
-	var (
-		syncConfModel           document.SyncConf
-		blockNumPerWorkerHandle int64
-		maxWorkerSleepTime      int64
-	)
+// start execute task until ctx is done,
+// it returns after all workers finished their current block and released their tasks
+func StartExecuteTask(ctx context.Context, env Env) {
 	log := logger.GetLogger("TaskExecutor")
 
-	// get sync conf
-	syncConf, err := syncConfModel.GetConf()
-	if err != nil {
-		log.Fatal("Get sync conf failed", logger.String("err", err.Error()))
-	}
-	blockNumPerWorkerHandle = syncConf.BlockNumPerWorkerHandle
-	if blockNumPerWorkerHandle <= 0 {
-		log.Fatal("blockNumPerWorkerHandle should greater than 0")
-	}
-	maxWorkerSleepTime = syncConf.MaxWorkerSleepTime
-	if maxWorkerSleepTime <= 0 {
-		log.Fatal("maxWorkerSleepTime should greater than 0")
+	// sync conf is reloaded by watcher when it's changed,
+	// workers use latest applied conf when they sync every block
+	startSyncConfWatcher(ctx, env)
+	if !confWatcher.WaitLoaded(ctx) {
+		log.Info("Stop execute task before sync conf loaded")
+		return
 	}
 
-	log.Info("Start execute task", logger.Any("sync conf", syncConf))
+	log.Info("Start execute task", logger.Any("sync conf", confWatcher.Conf()))
+
+	// follow task is driven by latest height which is kept by block watcher
+	startBlockWatcher(ctx, env)
 
 	// buffer channel to limit goroutine num
 	chanLimit := make(chan bool, serverConf.WorkerNumExecuteTask)
 
+	var wg sync.WaitGroup
 	for {
-		chanLimit <- true
-		go executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime, chanLimit)
+		select {
+		case <-ctx.Done():
+			log.Info("Stop execute task, wait for workers exit")
+			wg.Wait()
+			log.Info("All workers exit")
+			return
+		case chanLimit <- true:
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				executeTask(ctx, env, chanLimit)
+			}()
+		}
 	}
 }
 
-func executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime int64, chanLimit chan bool) {
+func executeTask(ctx context.Context, env Env, chanLimit chan bool) {
 	var (
+		syncConf               = confWatcher.Conf()
 		syncTaskModel          document.SyncTask
 		workerId, taskType     string
 		blockChainLatestHeight int64
@@ -65,7 +73,7 @@ func executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime int64, chanLimit ch
 
 	healthCheckQuit := make(chan bool)
 	workerId = genWorkerId()
-	client := helper.GetClient()
+	client := env.Nodes.GetClient()
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -76,10 +84,21 @@ func executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime int64, chanLimit ch
 		client.Release()
 	}()
 
+	if ctx.Err() != nil {
+		return
+	}
+
+	// software upgrades may be synced by other workers since last task,
+	// task isn't taken over when codec of upgraded version is missing
+	if err := LoadCodecSchedule(env); err != nil {
+		log.Error("load codec schedule fail", logger.String("err", err.Error()))
+		return
+	}
+
 	// check whether exist executable task
 	// status = unhandled or
 	// status = underway and now - lastUpdateTime > confTime
-	tasks, err := syncTaskModel.GetExecutableTask(maxWorkerSleepTime)
+	tasks, err := syncTaskModel.GetExecutableTask(env.Store, syncConf.MaxWorkerSleepTime)
 	if err != nil {
 		log.Error("Get executable task fail", logger.String("err", err.Error()))
 	}
@@ -88,10 +107,9 @@ func executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime int64, chanLimit ch
 		return
 	}
 
-	// take over sync task
-	// attempt to update status, worker_id and worker_logs
-	task := tasks[0]
-	err = syncTaskModel.TakeOverTask(task, workerId)
+	// take over sync task, task is leased to current worker for max worker sleep time
+	// attempt to update status, worker_id, worker_logs, lease and fencing token
+	task, err := syncTaskModel.TakeOverTask(env.Store, tasks[0], workerId, syncConf.MaxWorkerSleepTime)
 	if err != nil {
 		if err == mgo.ErrNotFound {
 			log.Info("Task has been take over by other goroutine")
@@ -99,9 +117,6 @@ func executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime int64, chanLimit ch
 			log.Error("Take over task fail", logger.String("err", err.Error()))
 		}
 		return
-	} else {
-		// task over task success, update task worker to current worker
-		task.WorkerId = workerId
 	}
 
 	if task.EndHeight != 0 {
@@ -113,11 +128,11 @@ func executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime int64, chanLimit ch
 		logger.String("cur_worker", workerId), logger.String("task_id", task.ID.Hex()),
 		logger.String("from-to", fmt.Sprintf("%v-%v", task.StartHeight, task.EndHeight)))
 
-	// worker health check, if worker is alive, then update last update time every minute.
+	// worker health check, if worker is alive, then renew lease of task every minute.
 	// health check will exit in follow conditions:
 	// 1. task is not owned by current worker
 	// 2. task is invalid
-	workerHealthCheck := func(taskId bson.ObjectId, currentWorker string) {
+	workerHealthCheck := func(taskId bson.ObjectId, currentWorker string, fencingToken int64) {
 		defer func() {
 			if r := recover(); r != nil {
 				log.Error("worker health check err", logger.Any("err", r))
@@ -126,24 +141,35 @@ func executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime int64, chanLimit ch
 
 		func() {
 			for {
+				// lease is renewed several times before it expires
+				interval := renewLeaseInterval(confWatcher.Conf().MaxWorkerSleepTime)
 				select {
 				case <-healthCheckQuit:
 					logger.Info("get health check quit signal, now exit health check")
 					return
 				default:
-					task, err := syncTaskModel.GetTaskByIdAndWorker(taskId, workerId)
+					task, err := syncTaskModel.GetTaskByIdAndWorker(env.Store, taskId, workerId)
 					if err == nil {
-						blockChainLatestHeight, err := getBlockChainLatestHeight()
+						blockChainLatestHeight, err := getBlockChainLatestHeight(env)
 						if err == nil {
-							if assertTaskValid(task, blockNumPerWorkerHandle, blockChainLatestHeight) {
-								// update task last update time
-								if err := syncTaskModel.UpdateLastUpdateTime(task); err != nil {
-									log.Error("update last update time fail", logger.String("err", err.Error()),
+							syncConf := confWatcher.Conf()
+							if assertTaskValid(task, syncConf.BlockNumPerWorkerHandle,
+								limitByHaltHeight(blockChainLatestHeight, syncConf.HaltHeight)) {
+								// renew lease of task with fencing token held by current worker
+								task.FencingToken = fencingToken
+								if err := syncTaskModel.RenewLease(env.Store, task, syncConf.MaxWorkerSleepTime); err != nil {
+									if err == mgo.ErrNotFound {
+										log.Info("lease of task is lost, exit health check",
+											logger.String("task_id", taskId.Hex()), logger.String("current_worker", workerId))
+										return
+									}
+									log.Error("renew lease of task fail", logger.String("err", err.Error()),
 										logger.String("task_id", task.ID.Hex()))
 								}
-								logger.Info("health check success, now sleep one minute",
+								logger.Info("health check success, now sleep until next renewal",
 									logger.String("task_id", task.ID.Hex()),
-									logger.String("task_current_worker", task.WorkerId))
+									logger.String("task_current_worker", task.WorkerId),
+									logger.Duration("interval", interval))
 							} else {
 								log.Info("task is invalid, exit health check", logger.String("task_id", taskId.Hex()))
 								return
@@ -162,21 +188,41 @@ func executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime int64, chanLimit ch
 						}
 					}
 				}
-				time.Sleep(1 * time.Minute)
+				time.Sleep(interval)
 			}
 		}()
 	}
-	go workerHealthCheck(task.ID, workerId)
+	go workerHealthCheck(task.ID, workerId, task.FencingToken)
 
 	// check task is valid
 	// valid catch up task: current_height < end_height
 	// valid follow task: current_height + blockNumPerWorkerHandle > blockChainLatestHeight
-	blockChainLatestHeight, err = getBlockChainLatestHeight()
+	blockChainLatestHeight, err = getBlockChainLatestHeight(env)
 	if err != nil {
 		log.Error("get block chain latest height fail", logger.String("err", err.Error()))
 		return
 	}
-	for assertTaskValid(task, blockNumPerWorkerHandle, blockChainLatestHeight) {
+	for {
+		// conf may be changed during syncing, use latest applied conf for every block
+		syncConf = confWatcher.Conf()
+		if !assertTaskValid(task, syncConf.BlockNumPerWorkerHandle,
+			limitByHaltHeight(blockChainLatestHeight, syncConf.HaltHeight)) {
+			break
+		}
+
+		// stop signal received, release task so that it can be taken over
+		// immediately after restart instead of waiting max worker sleep time
+		if ctx.Err() != nil {
+			if err := syncTaskModel.ReleaseTask(env.Store, task); err != nil {
+				log.Error("release task fail", logger.String("task_id", task.ID.Hex()),
+					logger.String("err", err.Error()))
+			} else {
+				log.Info("worker stopped, release task", logger.String("task_id", task.ID.Hex()),
+					logger.Int64("current_height", task.CurrentHeight))
+			}
+			return
+		}
+
 		var inProcessBlock int64
 		if task.CurrentHeight == 0 {
 			inProcessBlock = task.StartHeight
@@ -184,64 +230,99 @@ func executeTask(blockNumPerWorkerHandle, maxWorkerSleepTime int64, chanLimit ch
 			inProcessBlock = task.CurrentHeight + 1
 		}
 
+		// follow task stops at halt height, it's completed so that no more follow task is created
+		if taskType == document.SyncTaskTypeFollow && syncConf.HaltHeight > 0 && inProcessBlock > syncConf.HaltHeight {
+			if err := syncTaskModel.CompleteFollowTask(env.Store, task); err != nil {
+				log.Error("complete follow task fail", logger.String("task_id", task.ID.Hex()),
+					logger.String("err", err.Error()))
+			} else {
+				log.Info("halt height reached, follow task stopped", logger.String("task_id", task.ID.Hex()),
+					logger.Int64("halt_height", syncConf.HaltHeight), logger.Int64("current_height", task.CurrentHeight))
+			}
+			return
+		}
+
 		// if task is follow task,
-		// wait value of blockChainLatestHeight updated when inProcessBlock >= blockChainLatestHeight
+		// wait value of blockChainLatestHeight updated by block watcher when inProcessBlock >= blockChainLatestHeight
 		if taskType == document.SyncTaskTypeFollow {
-			blockChainLatestHeight, err = getBlockChainLatestHeight()
-			if err != nil {
-				log.Error("get block chain latest height fail", logger.String("err", err.Error()))
-				return
-			}
+			blockChainLatestHeight = watcher.LatestHeight()
 
 			if task.CurrentHeight+2 >= blockChainLatestHeight {
 				// wait block chain latest block height updated, must interval two block
-				log.Info("wait block chain latest block height updated, must interval two block",
+				log.Debug("wait block chain latest block height updated, must interval two block",
 					logger.String("taskId", task.ID.String()),
 					logger.String("workerId", task.WorkerId),
 					logger.Int64("taskCurrentHeight", task.CurrentHeight),
 					logger.Int64("blockChainLatestHeight", blockChainLatestHeight))
-				time.Sleep(2 * time.Second)
+				blockChainLatestHeight = watcher.WaitHeight(ctx, task.CurrentHeight+3, newBlockEventTimeout)
 				continue
 			}
 		}
 
 		// parse block and tx
-		blockDoc, err := parseBlock(inProcessBlock, client)
+		blockDoc, batch, err := parseBlock(env, inProcessBlock, client)
 		if err != nil {
 			log.Error("Parse block fail", logger.Int64("block", inProcessBlock),
 				logger.String("err", err.Error()))
+		} else if taskType == document.SyncTaskTypeFollow && inProcessBlock > syncConf.StartHeight {
+			// block should be linked to stored block before it's documents are saved,
+			// once blockchain forked, rollback documents above common ancestor and re-sync from it.
+			// block at start height has no stored parent block, so it's not checked
+			link, err := assertBlockContinuous(env, blockDoc)
+			if err != nil {
+				log.Error("assert block continuous fail", logger.Int64("block", inProcessBlock),
+					logger.String("err", err.Error()))
+				return
+			}
+			if link == blockParentMissing {
+				// missing parent is left to sync gap task, stored chain isn't rolled back for it
+				log.Warn("parent of block is missing, block is saved without link check",
+					logger.Int64("block", inProcessBlock))
+			}
+			if link == blockForked {
+				ancestorHeight, err := rollbackToCommonAncestor(env, task, client)
+				if err == txn.ErrAborted {
+					log.Info("task has been taken over by other worker, rollback is rejected",
+						logger.Any("task_id", task.ID), logger.String("worker", workerId))
+					return
+				}
+				if err != nil {
+					log.Error("rollback to common ancestor fail", logger.Int64("block", inProcessBlock),
+						logger.String("err", err.Error()))
+					return
+				}
+				log.Info("rollback to common ancestor success, now re-sync from it",
+					logger.String("task_id", task.ID.Hex()), logger.Int64("ancestor_height", ancestorHeight))
+				task.CurrentHeight = ancestorHeight
+				continue
+			}
 		}
 
-		// check task owner
-		workerUnchanged, err := assertTaskWorkerUnchanged(task.ID, task.WorkerId)
-		if err != nil {
-			log.Error("assert task worker is unchanged fail", logger.String("err", err.Error()))
+		// save data and update sync task,
+		// owner of task is checked by fencing token when documents are committed
+		taskDoc := task
+		taskDoc.CurrentHeight = inProcessBlock
+		taskDoc.LastUpdateTime = time.Now().Unix()
+		taskDoc.Status = document.SyncTaskStatusUnderway
+		if inProcessBlock == task.EndHeight {
+			taskDoc.Status = document.SyncTaskStatusCompleted
 		}
-		if workerUnchanged {
-			// save data and update sync task
-			taskDoc := task
-			taskDoc.CurrentHeight = inProcessBlock
-			taskDoc.LastUpdateTime = time.Now().Unix()
-			taskDoc.Status = document.SyncTaskStatusUnderway
-			if inProcessBlock == task.EndHeight {
-				taskDoc.Status = document.SyncTaskStatusCompleted
-			}
 
-			err := saveDocs(blockDoc, taskDoc)
-			if err != nil {
-				log.Error("save docs fail", logger.String("err", err.Error()))
-			} else {
-				task.CurrentHeight = inProcessBlock
+		err = saveDocs(blockDoc, taskDoc, batch)
+		if err == txn.ErrAborted {
+			log.Info("task has been taken over by other worker, save docs is rejected",
+				logger.Any("task_id", task.ID), logger.String("worker", workerId))
+			return
+		}
+		if err != nil {
+			log.Error("save docs fail", logger.String("err", err.Error()))
+		} else {
+			task.CurrentHeight = inProcessBlock
 
-				if taskType == document.SyncTaskTypeFollow {
-					// compare and update validators
-					handler.CompareAndUpdateValidators()
-				}
+			if taskType == document.SyncTaskTypeFollow {
+				// compare and update validators
+				handler.CompareAndUpdateValidators(env.Store)
 			}
-		} else {
-			log.Info("task worker changed", logger.Any("task_id", task.ID),
-				logger.String("origin worker", workerId), logger.String("current worker", task.WorkerId))
-			return
 		}
 	}
 
@@ -283,43 +364,86 @@ func assertTaskValid(task document.SyncTask, blockNumPerWorkerHandle, blockChain
 	return flag
 }
 
-func parseBlock(b int64, client *helper.Client) (document.Block, error) {
-	var (
-		mutex    sync.Mutex
-		blockDoc document.Block
-	)
-
+var (
 	// define functions which should be executed
 	// during parse tx and block
-	funcChain := []handler.Action{
-		handler.SaveTx, handler.SaveAccount, handler.SaveOrUpdateDelegator,
+	txFuncChain = []handler.Action{
+		handler.SaveTx, handler.SaveAccountPubKey,
+	}
+	// define functions which should be executed
+	// during parse every msg of tx
+	msgFuncChain = []handler.Action{
+		handler.SaveAccount, handler.SaveOrUpdateDelegator, handler.SaveOrUpdateProposal,
 	}
+)
+
+// interval of renewing lease of task, it's half of lease length which is max worker sleep time in seconds,
+// so lease is renewed again when one renewal fails
+func renewLeaseInterval(leaseSeconds int64) time.Duration {
+	return time.Duration(leaseSeconds) * time.Second / 2
+}
 
+// handle tx and every msg of tx, documents are written into batch
+func handleTx(docTx document.CommonTx, batch *store.Batch) {
+	handler.Handle(docTx, batch, txFuncChain)
+	handler.HandleMsgs(docTx, batch, msgFuncChain)
+}
+
+func parseBlock(env Env, b int64, client helper.NodeClient) (document.Block, *store.Batch, error) {
+	var (
+		blockDoc document.Block
+	)
+	batch := store.NewBulkBatch(env.Store)
+
+	// block, block results and validators are fetched once for each block
 	block, err := client.Block(&b)
 	if err != nil {
-		// there is possible parse block fail when in iterator
-		var err2 error
-		client2 := helper.GetClient()
-		block, err2 = client2.Block(&b)
-		client2.Release()
-		if err2 != nil {
-			return blockDoc, err2
+		// there is possible parse block fail when in iterator,
+		// so use another client to fetch block and it's results
+		client2 := env.Nodes.GetClient()
+		defer client2.Release()
+		client = client2
+		block, err = client.Block(&b)
+		if err != nil {
+			return blockDoc, batch, err
 		}
 	}
 
-	// save or update common_tx, tx_msg, proposal, delegator, candidate, account document
-	// TODO: saveOrUpdate above documents, save block and update sync task should be in a transaction.
-	// TODO  this task will be finished during second refactor plan.
+	// results of txs are in same order as txs in block
+	blockResults, err := client.BlockResults(&b)
+	if err != nil {
+		return blockDoc, batch, err
+	}
+	txs := block.Block.Data.Txs
+	if blockResults.Results == nil || len(blockResults.Results.DeliverTx) != len(txs) {
+		return blockDoc, batch, fmt.Errorf("tx results of block %v mismatch txs in block", b)
+	}
+
+	// collect common_tx, tx_msg, proposal, delegator, candidate, account document into batch,
+	// they are written by bulk after fencing token of sync task is checked
 	if block.BlockMeta.Header.NumTxs > 0 {
-		txs := block.Block.Data.Txs
-		for _, txByte := range txs {
-			docTx := helper.ParseTx(txByte, block.Block)
+		for i, txByte := range txs {
+			result := blockResults.Results.DeliverTx[i]
+			if result == nil {
+				return blockDoc, batch, fmt.Errorf("tx result of block %v at index %v is empty", b, i)
+			}
+			docTx, err := helper.ParseTx(txByte, block.Block, *result)
+			if err != nil {
+				// tx is kept with raw bytes, it's decoded again by retry-tx command after codec is upgraded
+				undecodedTx := helper.BuildUndecodedTx(txByte, block.Block, *result, err)
+				logger.Warn("decode tx fail", logger.Int64("height", b), logger.String("txHash", undecodedTx.TxHash),
+					logger.String("err", err.Error()))
+				if err := batch.SaveOrUpdate(undecodedTx); err != nil {
+					return blockDoc, batch, err
+				}
+				continue
+			}
 			txHash := helper.BuildHex(txByte.Hash())
 			if txHash == "" {
 				logger.Warn("Tx has no hash, skip this tx.", logger.Any("Tx", docTx))
 				continue
 			}
-			handler.Handle(docTx, mutex, funcChain)
+			handleTx(docTx, batch)
 		}
 	}
 
@@ -332,32 +456,13 @@ func parseBlock(b int64, client *helper.Client) (document.Block, error) {
 		validators = res.Validators
 	}
 
-	return handler.ParseBlock(block.BlockMeta, block.Block, validators), nil
-}
-
-// assert task worker unchanged
-func assertTaskWorkerUnchanged(taskId bson.ObjectId, workerId string) (bool, error) {
-	var (
-		syncTaskModel document.SyncTask
-	)
-	// check task owner
-	task, err := syncTaskModel.GetTaskById(taskId)
-	if err != nil {
-		return false, err
-	}
-
-	if task.WorkerId == workerId {
-		return true, nil
-	} else {
-		return false, nil
-	}
+	return handler.ParseBlock(block.BlockMeta, block.Block, validators, blockResults, batch), batch, nil
 }
 
-func saveDocs(blockDoc document.Block, taskDoc document.SyncTask) error {
-	var (
-		ops []txn.Op
-	)
-
+// check fencing token of task, write documents in batch by bulk, then save block and update sync task
+// in one transaction, return txn.ErrAborted when fencing token of task mismatch, which means task is owned
+// by other worker. documents of block are idempotent, so they are same when block is synced again after failure
+func saveDocs(blockDoc document.Block, taskDoc document.SyncTask, batch *store.Batch) error {
 	if blockDoc.Hash == "" {
 		return fmt.Errorf("block document is empty")
 	}
@@ -368,10 +473,18 @@ func saveDocs(blockDoc document.Block, taskDoc document.SyncTask) error {
 		Insert: blockDoc,
 	}
 
+	// documents aren't written when task has been taken over by other worker
+	fenceOp := txn.Op{
+		C:      document.CollectionNameSyncTask,
+		Id:     taskDoc.ID,
+		Assert: bson.M{"fencing_token": taskDoc.FencingToken},
+	}
+
+	// transaction is aborted when task is taken over by other worker after documents are written
 	updateOp := txn.Op{
 		C:      document.CollectionNameSyncTask,
 		Id:     taskDoc.ID,
-		Assert: txn.DocExists,
+		Assert: bson.M{"fencing_token": taskDoc.FencingToken},
 		Update: bson.M{
 			"$set": bson.M{
 				"current_height":   taskDoc.CurrentHeight,
@@ -381,14 +494,8 @@ func saveDocs(blockDoc document.Block, taskDoc document.SyncTask) error {
 		},
 	}
 
-	ops = append(ops, insertOp, updateOp)
-
-	if len(ops) > 0 {
-		err := store.Txn(ops)
-		if err != nil {
-			return err
-		}
-	}
+	batch.AddFence(fenceOp)
+	batch.AddOps(insertOp, updateOp)
 
-	return nil
+	return batch.Commit()
 }
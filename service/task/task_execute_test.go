@@ -1,11 +1,11 @@
 package task
 
 import (
-	"sync"
+	"context"
 	"testing"
 
 	"encoding/json"
-	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
 	"github.com/irisnet/irishub-sync/util/helper"
 	"gopkg.in/mgo.v2/bson"
@@ -13,48 +13,46 @@ import (
 )
 
 func Test_executeTask(t *testing.T) {
-	type args struct {
-		blockNumPerWorkerHandle int64
-		maxWorkerSleepTime      int64
-		chanLimit               chan bool
-	}
-
-	limitChan := make(chan bool, 2)
+	var (
+		syncTaskModel document.SyncTask
+		blockModel    document.Block
+	)
 
-	tests := []struct {
-		name string
-		args args
-	}{
-		{
-			name: "test execute task",
-			args: args{
-				blockNumPerWorkerHandle: 100,
-				maxWorkerSleepTime:      10 * 60,
-				chanLimit:               limitChan,
-			},
-		},
+	env := newTestEnv(&stubNode{latestHeight: 250})
+	if err := env.Store.Save(document.SyncConf{BlockNumPerWorkerHandle: 100, MaxWorkerSleepTime: 60}); err != nil {
+		t.Fatal(err)
+	}
+	if err := confWatcher.reload(env); err != nil {
+		t.Fatal(err)
+	}
+	if err := createRangeTasks(env, 1, 200, 100); err != nil {
+		t.Fatal(err)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			var wg sync.WaitGroup
-			wg.Add(1)
 
-			tt.args.chanLimit <- true
-			go executeTask(tt.args.blockNumPerWorkerHandle, tt.args.maxWorkerSleepTime, tt.args.chanLimit)
+	// every worker takes over one task and syncs all blocks of it
+	for i := 0; i < 2; i++ {
+		chanLimit := make(chan bool, 1)
+		chanLimit <- true
+		executeTask(context.Background(), env, chanLimit)
+	}
 
-			wg.Wait()
-		})
+	tasks, err := syncTaskModel.QueryAll(env.Store, []string{document.SyncTaskStatusCompleted}, document.SyncTaskTypeCatchUp)
+	if err != nil || len(tasks) != 2 {
+		t.Errorf("completed tasks = %v, err = %v, want 2 tasks", tasks, err)
+	}
+	if n, err := env.Store.Find(blockModel.Name(), nil).Count(); err != nil || n != 200 {
+		t.Errorf("num of blocks = %v, err = %v, want 200", n, err)
+	}
+	block, err := blockModel.GetBlockByHeight(env.Store, 200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block.Hash != helper.BuildHex([]byte("block200")) || block.Meta.Header.LastBlockID.Hash != helper.BuildHex([]byte("block199")) {
+		t.Errorf("unexpected block %v", block)
 	}
 }
 
 func Test_assertTaskValid(t *testing.T) {
-	var (
-		syncTaskModel document.SyncTask
-	)
-
-	catchUpTask, _ := syncTaskModel.GetTaskById(bson.ObjectIdHex("5c176b243b6c5c3ff62deaea"))
-	followTask, _ := syncTaskModel.GetTaskById(bson.ObjectIdHex("5c176dc63b6c5c4027b8fb92"))
-
 	type args struct {
 		task                    document.SyncTask
 		blockNumPerWorkerHandle int64
@@ -63,11 +61,21 @@ func Test_assertTaskValid(t *testing.T) {
 	tests := []struct {
 		name string
 		args args
+		want bool
 	}{
 		{
 			name: "test assert catch up task valid",
 			args: args{
-				task:                    catchUpTask,
+				task:                    document.SyncTask{StartHeight: 1, EndHeight: 100, CurrentHeight: 50},
+				blockNumPerWorkerHandle: 100,
+				blockChainLatestHeight:  100,
+			},
+			want: true,
+		},
+		{
+			name: "test assert finished catch up task invalid",
+			args: args{
+				task:                    document.SyncTask{StartHeight: 1, EndHeight: 100, CurrentHeight: 100},
 				blockNumPerWorkerHandle: 100,
 				blockChainLatestHeight:  100,
 			},
@@ -75,7 +83,16 @@ func Test_assertTaskValid(t *testing.T) {
 		{
 			name: "test assert follow task valid",
 			args: args{
-				task:                    followTask,
+				task:                    document.SyncTask{StartHeight: 201, CurrentHeight: 700},
+				blockNumPerWorkerHandle: 200,
+				blockChainLatestHeight:  800,
+			},
+			want: true,
+		},
+		{
+			name: "test assert follow task which falls behind invalid",
+			args: args{
+				task:                    document.SyncTask{StartHeight: 201},
 				blockNumPerWorkerHandle: 200,
 				blockChainLatestHeight:  800,
 			},
@@ -83,41 +100,69 @@ func Test_assertTaskValid(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := assertTaskValid(tt.args.task, tt.args.blockNumPerWorkerHandle, tt.args.blockChainLatestHeight)
-			t.Log(got)
+			if got := assertTaskValid(tt.args.task, tt.args.blockNumPerWorkerHandle, tt.args.blockChainLatestHeight); got != tt.want {
+				t.Errorf("assertTaskValid() = %v, want %v", got, tt.want)
+			}
 		})
 	}
 }
 
-func Test_parseBlock(t *testing.T) {
-	client := helper.GetClient()
+func Test_renewLeaseInterval(t *testing.T) {
+	tests := []struct {
+		leaseSeconds int64
+		want         time.Duration
+	}{
+		{leaseSeconds: 120, want: time.Minute},
+		{leaseSeconds: 30, want: 15 * time.Second},
+		{leaseSeconds: 1, want: 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := renewLeaseInterval(tt.leaseSeconds); got != tt.want || got >= time.Duration(tt.leaseSeconds)*time.Second {
+			t.Errorf("renewLeaseInterval(%v) = %v, want %v", tt.leaseSeconds, got, tt.want)
+		}
+	}
+}
 
-	defer func() {
-		logger.Info("release client")
-		client.Release()
-	}()
+func Test_parseBlock(t *testing.T) {
+	node := &stubNode{latestHeight: 200}
+	env := newTestEnv(node)
 
 	type args struct {
 		b      int64
-		client *helper.Client
+		client helper.NodeClient
 	}
 	tests := []struct {
-		name string
-		args args
+		name    string
+		args    args
+		wantErr bool
 	}{
 		{
 			name: "test parse block",
 			args: args{
-				client: client,
-				b:      107061,
+				client: node,
+				b:      107,
 			},
 		},
+		{
+			name: "test parse block above latest height",
+			args: args{
+				client: node,
+				b:      201,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res, err := parseBlock(tt.args.b, tt.args.client)
+			res, _, err := parseBlock(env, tt.args.b, tt.args.client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBlock() err = %v, wantErr %v", err, tt.wantErr)
+			}
 			if err != nil {
-				t.Fatal(err)
+				return
+			}
+			if res.Height != tt.args.b || res.Hash != helper.BuildHex(node.blockHash(tt.args.b)) {
+				t.Errorf("unexpected block %v", res)
 			}
 			resBytes, err := json.MarshalIndent(res, "", "\t")
 			if err != nil {
@@ -128,44 +173,29 @@ func Test_parseBlock(t *testing.T) {
 	}
 }
 
-func Test_assertTaskWorkerUnchanged(t *testing.T) {
-	type args struct {
-		taskId   bson.ObjectId
-		workerId string
-	}
-	tests := []struct {
-		name string
-		args args
-	}{
-		{
-			name: "test assert test worker unchanged",
-			args: args{
-				taskId:   bson.ObjectIdHex("5b176dc63b6c5c4027b8fb92"),
-				workerId: "ObjectIdHex(\"5c19cec10000000000000000\")",
-			},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			res, err := assertTaskWorkerUnchanged(tt.args.taskId, tt.args.workerId)
-			if err != nil {
-				t.Fatal(err)
-			}
-			t.Log(res)
-		})
-	}
-}
-
 func Test_saveDocs(t *testing.T) {
 	var (
 		syncTaskModel document.SyncTask
+		blockModel    document.Block
 	)
 
+	env := newTestEnv(&stubNode{})
+	if err := createRangeTasks(env, 1, 480, 480); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := syncTaskModel.GetExecutableTask(env.Store, 60)
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("get executable task, tasks = %v, err = %v", tasks, err)
+	}
+	task, err := syncTaskModel.TakeOverTask(env.Store, tasks[0], "worker", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	block := document.Block{
 		Height: 480,
 		Hash:   bson.NewObjectId().Hex(),
 	}
-	task, _ := syncTaskModel.GetTaskById(bson.ObjectIdHex("5c19e9e03b6c5ca9a96fdf62"))
 	task.CurrentHeight = block.Height
 	task.LastUpdateTime = time.Now().Unix()
 
@@ -187,10 +217,12 @@ func Test_saveDocs(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := saveDocs(tt.args.blockDoc, tt.args.taskDoc); err != nil {
+			if err := saveDocs(tt.args.blockDoc, tt.args.taskDoc, store.NewBatch(env.Store)); err != nil {
 				t.Fatal(err)
 			}
-			t.Log("save success")
+			if saved, err := blockModel.GetBlockByHeight(env.Store, tt.args.blockDoc.Height); err != nil || saved.Hash != tt.args.blockDoc.Hash {
+				t.Errorf("saved block = %v, err = %v", saved, err)
+			}
 		})
 	}
 }
@@ -0,0 +1,63 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store/document"
+)
+
+const (
+	// num of blocks whose documents are removed at once during reindex
+	reindexRollbackBlockNum = int64(1000)
+)
+
+// reindex blocks in [fromHeight, toHeight],
+// documents in range are removed and catch up tasks are created to re-sync them.
+// range shouldn't overlap heights which are being synced by unfinished tasks,
+// so reindex is safe while follow task keeps advancing
+func Reindex(env Env, fromHeight, toHeight int64) error {
+	var (
+		syncConfModel document.SyncConf
+	)
+
+	if fromHeight <= 0 || toHeight < fromHeight {
+		return fmt.Errorf("invalid reindex range [%v, %v]", fromHeight, toHeight)
+	}
+
+	syncConf, err := syncConfModel.GetConf(env.Store)
+	if err != nil {
+		return err
+	}
+
+	syncingRanges, err := getSyncingRanges(env)
+	if err != nil {
+		return err
+	}
+	for _, r := range syncingRanges {
+		if toHeight >= r.start && (r.end == 0 || fromHeight <= r.end) {
+			return fmt.Errorf("reindex range [%v, %v] overlaps range [%v, %v] which is being synced",
+				fromHeight, toHeight, r.start, r.end)
+		}
+	}
+
+	logger.Info("start reindex", logger.Int64("from", fromHeight), logger.Int64("to", toHeight))
+
+	// remove documents from top to bottom, so newer modification will be reverted first
+	for endHeight := toHeight; endHeight >= fromHeight; endHeight -= reindexRollbackBlockNum {
+		startHeight := endHeight - reindexRollbackBlockNum
+		if startHeight < fromHeight-1 {
+			startHeight = fromHeight - 1
+		}
+		if err := rollbackDocs(env, startHeight, endHeight); err != nil {
+			return err
+		}
+	}
+
+	if err := createRangeTasks(env, fromHeight, toHeight, syncConf.BlockNumPerWorkerHandle); err != nil {
+		return err
+	}
+
+	logger.Info("reindex tasks created", logger.Int64("from", fromHeight), logger.Int64("to", toHeight))
+	return nil
+}
@@ -0,0 +1,51 @@
+package task
+
+import (
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	"github.com/irisnet/irishub-sync/util/helper"
+)
+
+// decode undecoded txs which height in (fromHeight, toHeight] again with current codec,
+// toHeight 0 means all heights. decoded tx is handled like it's synced with block,
+// and it's removed from undecoded txs in same transaction. return num of decoded and failed txs
+func RetryUndecodedTxs(env Env, fromHeight, toHeight int64) (int, int, error) {
+	var (
+		undecodedTxModel document.UndecodedTx
+		decoded, failed  int
+	)
+
+	txs, err := undecodedTxModel.QueryByHeightRange(env.Store, fromHeight, toHeight)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, tx := range txs {
+		batch := store.NewBatch(env.Store)
+
+		docTx, err := helper.ParseUndecodedTx(tx)
+		if err != nil {
+			tx.Error = err.Error()
+			tx.Retries++
+			if err := batch.Update(tx); err != nil {
+				return decoded, failed, err
+			}
+			failed++
+		} else {
+			handleTx(docTx, batch)
+			if err := batch.Delete(tx); err != nil {
+				return decoded, failed, err
+			}
+			decoded++
+		}
+
+		if err := batch.Commit(); err != nil {
+			return decoded, failed, err
+		}
+	}
+
+	logger.Info("retry undecoded txs", logger.Int64("from", fromHeight), logger.Int64("to", toHeight),
+		logger.Int("decoded", decoded), logger.Int("failed", failed))
+	return decoded, failed, nil
+}
@@ -0,0 +1,52 @@
+package task
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func Test_retryUndecodedTxs(t *testing.T) {
+	var undecodedTxModel document.UndecodedTx
+
+	env := newTestEnv(&stubNode{})
+
+	for height := int64(1); height <= 3; height++ {
+		tx := document.UndecodedTx{
+			TxHash: fmt.Sprintf("tx%d", height),
+			Height: height,
+			Tx:     []byte{0xff, 0xff},
+			Error:  "unknown msg type",
+		}
+		if err := env.Store.Save(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// txs which still can't be decoded are kept with error of last retry
+	decoded, failed, err := RetryUndecodedTxs(env, 1, 0)
+	if err != nil || decoded != 0 || failed != 2 {
+		t.Fatalf("RetryUndecodedTxs() = %v, %v, %v", decoded, failed, err)
+	}
+	var tx document.UndecodedTx
+	if err := env.Store.Find(undecodedTxModel.Name(), bson.M{"tx_hash": "tx3"}).One(&tx); err != nil {
+		t.Fatal(err)
+	}
+	if tx.Retries != 1 || tx.Error == "unknown msg type" || len(tx.Tx) != 2 {
+		t.Errorf("undecoded tx after retry = %+v", tx)
+	}
+	if err := env.Store.Find(undecodedTxModel.Name(), bson.M{"tx_hash": "tx1"}).One(&tx); err != nil || tx.Retries != 0 {
+		t.Errorf("tx out of range is retried, tx = %+v, err = %v", tx, err)
+	}
+
+	// undecoded txs are removed with blocks during rollback
+	if err := rollbackDocs(env, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	txs, err := undecodedTxModel.QueryByHeightRange(env.Store, 0, 0)
+	if err != nil || len(txs) != 1 || txs[0].TxHash != "tx1" {
+		t.Errorf("undecoded txs after rollback = %v, %v", txs, err)
+	}
+}
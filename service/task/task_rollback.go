@@ -0,0 +1,234 @@
+package task
+
+import (
+	"fmt"
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/service/handler"
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	"github.com/irisnet/irishub-sync/util/helper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+	"time"
+)
+
+const (
+	// max block num which can be rolled back when blockchain forked
+	maxRollbackBlockNum = int64(1000)
+)
+
+// result of checking block against stored block at height-1
+type blockLink int
+
+const (
+	// parent hash of block matches hash of stored block
+	blockLinked blockLink = iota
+	// stored block at height-1 is missing, e.g. it's in a sync gap which isn't repaired yet
+	blockParentMissing
+	// parent hash of block mismatch hash of stored block, blockchain forked
+	blockForked
+)
+
+// assert block is linked to block stored in db,
+// only blockForked means stored chain should be rolled back
+func assertBlockContinuous(env Env, block document.Block) (blockLink, error) {
+	var (
+		blockModel document.Block
+	)
+
+	height := block.Height
+	if height <= 1 {
+		return blockLinked, nil
+	}
+
+	parentBlock, err := blockModel.GetBlockByHeight(env.Store, height-1)
+	if err != nil {
+		if err == mgo.ErrNotFound {
+			return blockParentMissing, nil
+		}
+		return blockForked, err
+	}
+
+	parentHash := block.Meta.Header.LastBlockID.Hash
+	if parentBlock.Hash != parentHash {
+		logger.Warn("parent hash of block mismatch hash of stored block",
+			logger.Int64("height", height),
+			logger.String("parent_hash", parentHash),
+			logger.String("stored_hash", parentBlock.Hash))
+		return blockForked, nil
+	}
+
+	return blockLinked, nil
+}
+
+// walk back from synced height of task to find common ancestor of stored chain and blockchain,
+// return height of common ancestor
+func getCommonAncestorHeight(env Env, syncedHeight int64, client helper.NodeClient) (int64, error) {
+	var (
+		blockModel document.Block
+	)
+
+	for height := syncedHeight; height > 0 && syncedHeight-height < maxRollbackBlockNum; height-- {
+		storedBlock, err := blockModel.GetBlockByHeight(env.Store, height)
+		if err != nil {
+			if err == mgo.ErrNotFound {
+				continue
+			}
+			return 0, err
+		}
+
+		block, err := client.Block(&height)
+		if err != nil {
+			return 0, err
+		}
+
+		if storedBlock.Hash == helper.BuildHex(block.BlockMeta.BlockID.Hash) {
+			return height, nil
+		}
+	}
+
+	return 0, fmt.Errorf("can't find common ancestor in latest %v blocks below height %v",
+		maxRollbackBlockNum, syncedHeight)
+}
+
+// rollback documents which were written above common ancestor,
+// and reset current height of task to common ancestor height.
+// return height of common ancestor
+func rollbackToCommonAncestor(env Env, task document.SyncTask, client helper.NodeClient) (int64, error) {
+	ancestorHeight, err := getCommonAncestorHeight(env, task.CurrentHeight, client)
+	if err != nil {
+		return 0, err
+	}
+
+	logger.Warn("blockchain forked, now rollback documents above common ancestor",
+		logger.String("task_id", task.ID.Hex()),
+		logger.Int64("ancestor_height", ancestorHeight),
+		logger.Int64("synced_height", task.CurrentHeight))
+
+	// current height of task is reset in same transaction with rollback,
+	// and transaction is aborted when task has been taken over by other worker
+	resetOp := txn.Op{
+		C:      document.CollectionNameSyncTask,
+		Id:     task.ID,
+		Assert: bson.M{"fencing_token": task.FencingToken},
+		Update: bson.M{
+			"$set": bson.M{
+				"current_height":   ancestorHeight,
+				"last_update_time": time.Now().Unix(),
+			},
+		},
+	}
+	if err := rollbackDocs(env, ancestorHeight, task.CurrentHeight, resetOp); err != nil {
+		return 0, err
+	}
+
+	return ancestorHeight, nil
+}
+
+// remove block, tx, tx_msg, undecoded tx and account documents which height in (startHeight, endHeight],
+// then revert delegator and proposal documents which were modified by removed txs.
+// all of them are committed in one transaction with given ops
+func rollbackDocs(env Env, startHeight, endHeight int64, ops ...txn.Op) error {
+	var (
+		txModel      document.CommonTx
+		txMsgModel   document.TxMsg
+		accountModel document.Account
+		hashes       []string
+	)
+
+	// define functions which should be executed
+	// during revert tx
+	revertChain := []handler.Action{
+		handler.RevertProposal, handler.SaveOrUpdateDelegator,
+	}
+
+	batch := store.NewBatch(env.Store)
+
+	txs, err := txModel.QueryByHeightRange(env.Store, startHeight, endHeight)
+	if err != nil {
+		return err
+	}
+	for _, tx := range txs {
+		hashes = append(hashes, tx.TxHash)
+	}
+
+	if len(hashes) > 0 {
+		txMsgs, err := txMsgModel.QueryByHashes(env.Store, hashes)
+		if err != nil {
+			return err
+		}
+		// msg is rebuilt for every msg of tx, because revert functions are executed by msg
+		type msgKey struct {
+			hash  string
+			index int
+		}
+		msgMap := make(map[msgKey]document.TxMsg, len(txMsgs))
+		for _, v := range txMsgs {
+			msgMap[msgKey{v.Hash, v.Index}] = v
+		}
+		for i := range txs {
+			if txMsg, ok := msgMap[msgKey{txs[i].TxHash, 0}]; ok {
+				txs[i].Msg = handler.BuildMsg(txMsg)
+			}
+			for j := range txs[i].Msgs {
+				if txMsg, ok := msgMap[msgKey{txs[i].TxHash, txs[i].Msgs[j].Index}]; ok {
+					txs[i].Msgs[j].Msg = handler.BuildMsg(txMsg)
+				}
+			}
+		}
+	}
+
+	if err := removeBlockDocs(startHeight, endHeight, hashes, batch); err != nil {
+		return err
+	}
+	if err := accountModel.RemoveByHeightRange(startHeight, endHeight, batch); err != nil {
+		return err
+	}
+	// pubkey of accounts which are created before range is cleared, it's recorded again when it's re-synced
+	pubKeyAccounts, err := accountModel.QueryByPubKeyHeightRange(env.Store, startHeight, endHeight)
+	if err != nil {
+		return err
+	}
+	for _, account := range pubKeyAccounts {
+		if account.Height > startHeight {
+			continue
+		}
+		account.PubKey, account.PubKeyHeight = "", 0
+		if err := batch.Update(account); err != nil {
+			return err
+		}
+	}
+
+	// txs are sorted by height desc, so newer modification will be reverted first
+	for _, tx := range txs {
+		handler.HandleMsgs(tx, batch, revertChain)
+	}
+	batch.AddOps(ops...)
+
+	return batch.Commit()
+}
+
+// remove blocks, txs, msgs and undecoded txs which height in (startHeight, endHeight],
+// hashes are hashes of txs in range
+func removeBlockDocs(startHeight, endHeight int64, hashes []string, batch *store.Batch) error {
+	var (
+		blockModel       document.Block
+		txModel          document.CommonTx
+		txMsgModel       document.TxMsg
+		undecodedTxModel document.UndecodedTx
+	)
+
+	if len(hashes) > 0 {
+		if err := txMsgModel.RemoveByHashes(hashes, batch); err != nil {
+			return err
+		}
+	}
+	if err := txModel.RemoveByHeightRange(startHeight, endHeight, batch); err != nil {
+		return err
+	}
+	if err := undecodedTxModel.RemoveByHeightRange(startHeight, endHeight, batch); err != nil {
+		return err
+	}
+	return blockModel.RemoveByHeightRange(startHeight, endHeight, batch)
+}
@@ -0,0 +1,207 @@
+package task
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	"github.com/irisnet/irishub-sync/util/constant"
+	"github.com/irisnet/irishub-sync/util/helper"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+func Test_assertBlockContinuous(t *testing.T) {
+	node := &stubNode{latestHeight: 200}
+	env := newTestEnv(node)
+
+	if err := env.Store.Save(document.Block{Height: 106, Hash: helper.BuildHex(node.blockHash(106))}); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		height int64
+	}
+	tests := []struct {
+		name string
+		args args
+		want blockLink
+	}{
+		{
+			name: "test assert block continuous",
+			args: args{
+				height: 107,
+			},
+			want: blockLinked,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockDoc, _, err := parseBlock(env, tt.args.height, node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := assertBlockContinuous(env, blockDoc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tt.want {
+				t.Errorf("assertBlockContinuous() = %v, want %v", res, tt.want)
+			}
+		})
+	}
+}
+
+func Test_assertBlockContinuous_link(t *testing.T) {
+	env := newTestEnv(&stubNode{})
+
+	if err := env.Store.Save(document.Block{Height: 10, Hash: "block10"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		height     int64
+		parentHash string
+		want       blockLink
+	}{
+		{name: "first block", height: 1, want: blockLinked},
+		{name: "linked", height: 11, parentHash: "block10", want: blockLinked},
+		{name: "forked", height: 11, parentHash: "other", want: blockForked},
+		{name: "parent missing", height: 13, parentHash: "block12", want: blockParentMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := document.Block{Height: tt.height}
+			block.Meta.Header.LastBlockID.Hash = tt.parentHash
+			got, err := assertBlockContinuous(env, block)
+			if err != nil || got != tt.want {
+				t.Errorf("assertBlockContinuous() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func Test_getCommonAncestorHeight(t *testing.T) {
+	// blocks above 105 are forked on node
+	node := &stubNode{latestHeight: 200, forkHeight: 105}
+	env := newTestEnv(node)
+
+	for height := int64(100); height <= 110; height++ {
+		if err := env.Store.Save(document.Block{Height: height, Hash: helper.BuildHex([]byte(fmt.Sprintf("block%d", height)))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type args struct {
+		syncedHeight int64
+	}
+	tests := []struct {
+		name string
+		args args
+		want int64
+	}{
+		{
+			name: "test get common ancestor height",
+			args: args{
+				syncedHeight: 110,
+			},
+			want: 105,
+		},
+		{
+			name: "test get common ancestor height below fork height",
+			args: args{
+				syncedHeight: 103,
+			},
+			want: 103,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := getCommonAncestorHeight(env, tt.args.syncedHeight, node)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res != tt.want {
+				t.Errorf("getCommonAncestorHeight() = %v, want %v", res, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rollbackDocs(t *testing.T) {
+	var (
+		syncTaskModel document.SyncTask
+		blockModel    document.Block
+		txModel       document.CommonTx
+	)
+
+	// sync pipeline runs against in-memory store, so it doesn't depend on db and node
+	env := newTestEnv(&stubNode{})
+
+	if err := createRangeTasks(env, 1, 4, 2); err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := syncTaskModel.GetExecutableTask(env.Store, 60)
+	if err != nil || len(tasks) != 2 {
+		t.Fatalf("get executable task, tasks = %v, err = %v", tasks, err)
+	}
+	task, err := syncTaskModel.TakeOverTask(env.Store, tasks[0], "worker", 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for height := task.StartHeight; height <= task.EndHeight; height++ {
+		batch := store.NewBulkBatch(env.Store)
+		tx := document.CommonTx{
+			Height:     height,
+			TxHash:     fmt.Sprintf("tx%d", height),
+			Type:       constant.TxTypeSubmitProposal,
+			ProposalId: uint64(height),
+		}
+		if err := batch.Save(tx); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Save(document.Proposal{ProposalId: uint64(height)}); err != nil {
+			t.Fatal(err)
+		}
+
+		task.CurrentHeight = height
+		block := document.Block{Height: height, Hash: fmt.Sprintf("block%d", height)}
+		if err := saveDocs(block, task, batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// write of worker which lost task is rejected, documents of block aren't written either
+	stale := task
+	stale.FencingToken--
+	staleBatch := store.NewBulkBatch(env.Store)
+	if err := staleBatch.Save(document.CommonTx{Height: 3, TxHash: "stale"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveDocs(document.Block{Height: 3, Hash: "block3"}, stale, staleBatch); err != txn.ErrAborted {
+		t.Fatalf("save docs with stale fencing token, err = %v", err)
+	}
+	if n, _ := env.Store.Find(txModel.Name(), bson.M{"tx_hash": "stale"}).Count(); n != 0 {
+		t.Error("tx of worker which lost task is written")
+	}
+
+	if err := rollbackDocs(env, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if height, err := blockModel.GetMaxHeight(env.Store); err != nil || height != 1 {
+		t.Errorf("max block height = %v, err = %v, want 1", height, err)
+	}
+	if txs, err := txModel.QueryByHeightRange(env.Store, 0, 2); err != nil || len(txs) != 1 {
+		t.Errorf("txs after rollback = %v, err = %v", txs, err)
+	}
+	if _, err := document.QueryProposal(env.Store, 2); err != mgo.ErrNotFound {
+		t.Errorf("proposal submitted by removed tx should be reverted, err = %v", err)
+	}
+	if _, err := document.QueryProposal(env.Store, 1); err != nil {
+		t.Error(err)
+	}
+}
@@ -4,25 +4,25 @@ import (
 	"github.com/irisnet/irishub-sync/store/document"
 )
 
-func AssertFastSyncFinished() (bool, error) {
+func AssertFastSyncFinished(env Env) (bool, error) {
 	var (
 		syncTaskModel document.SyncTask
 		syncConfModel document.SyncConf
 	)
 
 	status := []string{document.SyncTaskStatusUnderway}
-	tasks, err := syncTaskModel.QueryAll(status, document.SyncTaskTypeFollow)
+	tasks, err := syncTaskModel.QueryAll(env.Store, status, document.SyncTaskTypeFollow)
 
 	if err != nil {
 		return false, err
 	}
 
 	if len(tasks) != 0 {
-		blockChainLatestHeight, err := getBlockChainLatestHeight()
+		blockChainLatestHeight, err := getBlockChainLatestHeight(env)
 		if err != nil {
 			return false, err
 		}
-		syncConf, err := syncConfModel.GetConf()
+		syncConf, err := syncConfModel.GetConf(env.Store)
 		if err != nil {
 			return false, err
 		}
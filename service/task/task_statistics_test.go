@@ -1,26 +1,59 @@
 package task
 
-import "testing"
+import (
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2/bson"
+	"testing"
+)
 
 func Test_assertFastSyncFinished(t *testing.T) {
+	env := newTestEnv(&stubNode{latestHeight: 300})
+	if err := env.Store.Save(document.SyncConf{BlockNumPerWorkerHandle: 100, MaxWorkerSleepTime: 60}); err != nil {
+		t.Fatal(err)
+	}
+
 	tests := []struct {
 		name string
+		// current height of underway follow task, no follow task when it's 0
+		followedHeight int64
+		want           bool
 	}{
 		{
-			name: "assert fast sync finished",
+			name: "assert fast sync unfinished without follow task",
+		},
+		{
+			name:           "assert fast sync unfinished when follow task falls behind",
+			followedHeight: 150,
+		},
+		{
+			name:           "assert fast sync finished",
+			followedHeight: 250,
+			want:           true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res, err := AssertFastSyncFinished()
+			if _, err := env.Store.RemoveAll(document.CollectionNameSyncTask, nil); err != nil {
+				t.Fatal(err)
+			}
+			if tt.followedHeight > 0 {
+				task := document.SyncTask{ID: bson.NewObjectId(), StartHeight: 101, CurrentHeight: tt.followedHeight,
+					Status: document.SyncTaskStatusUnderway}
+				if err := env.Store.Save(task); err != nil {
+					t.Fatal(err)
+				}
+			}
+			res, err := AssertFastSyncFinished(env)
 			if err != nil {
 				t.Fatal(err)
 			}
-			t.Log(res)
+			if res != tt.want {
+				t.Errorf("AssertFastSyncFinished() = %v, want %v", res, tt.want)
+			}
 		})
 	}
 }
 
 func TestMakeUpdateDelegatorTask(t *testing.T) {
-	updateDelegator()
+	updateDelegator(newTestEnv(&stubNode{}))
 }
@@ -4,21 +4,20 @@ import (
 	"github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/service/handler"
-	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
 )
 
-func MakeUpdateDelegatorTask() Task {
+func MakeUpdateDelegatorTask(env Env) Task {
 	return NewLockTaskFromEnv(server.CronUpdateDelegator, "save_update_delegator_lock", func() {
 		logger.Debug("========================task's trigger [MakeUpdateDelegatorTask] begin===================")
-		updateDelegator()
+		updateDelegator(env)
 		logger.Debug("========================task's trigger [MakeUpdateDelegatorTask] end===================")
 	})
 }
 
-func updateDelegator() {
+func updateDelegator(env Env) {
 	var delegatorStore document.Delegator
-	delegators := delegatorStore.QueryUnbonding()
+	delegators := delegatorStore.QueryUnbonding(env.Store)
 	if len(delegators) == 0 {
 		logger.Info("no delegator is unbonding")
 		return
@@ -29,10 +28,10 @@ func updateDelegator() {
 		d.UnbondingDelegation = ubd
 		if d.BondedHeight < 0 &&
 			d.UnbondingDelegation.CreationHeight < 0 {
-			store.Delete(d)
+			env.Store.Delete(d)
 			logger.Info("delete delegator", logger.String("delAddress", d.Address), logger.String("valAddress", d.ValidatorAddr))
 		} else {
-			store.Update(d)
+			env.Store.Update(d)
 			logger.Info("Update delegator", logger.String("delAddress", d.Address), logger.String("valAddress", d.ValidatorAddr))
 		}
 	}
@@ -3,11 +3,12 @@ package task
 import (
 	conf "github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
 	"github.com/irisnet/irishub-sync/util/constant"
 )
 
-func calculateTxGasAndGasPrice() {
+func calculateTxGasAndGasPrice(env Env) {
 	var (
 		methodName    = "CalculateTxGasAndGasPrice"
 		intervalTxNum = constant.IntervalTxNumCalculateTxGas
@@ -31,7 +32,7 @@ func calculateTxGasAndGasPrice() {
 	}
 
 	for _, v := range txTypes {
-		txs, err := txModel.CalculateTxGasAndGasPrice(v, intervalTxNum)
+		txs, err := txModel.CalculateTxGasAndGasPrice(env.Store, v, intervalTxNum)
 		if err != nil {
 			logger.Error("Can't calculate gas and gasPrice", logger.String("err", err.Error()))
 			continue
@@ -43,14 +44,14 @@ func calculateTxGasAndGasPrice() {
 	}
 
 	// remove all data
-	err := txGasModel.RemoveAll()
+	err := txGasModel.RemoveAll(env.Store)
 	if err != nil {
 		logger.Error("Remove all data fail", logger.String("err", err.Error()))
 		return
 	}
 
 	// save all data
-	err2 := txGasModel.SaveAll(txGases)
+	err2 := txGasModel.SaveAll(env.Store, txGases)
 	if err2 != nil {
 		logger.Error("Save latest data fail", logger.String("err", err2.Error()))
 		return
@@ -76,10 +77,10 @@ func buildTxGas(txs []document.CommonTx) document.TxGas {
 	}
 	minGasUsed := txs[0].GasUsed
 	maxGasUsed := txs[0].GasUsed
-	totalGasUsed := float64(0)
+	totalGasUsed := int64(0)
 	minGasPrice := txs[0].GasPrice
 	maxGasPrice := txs[0].GasPrice
-	totalGasPrice := float64(0)
+	totalGasPrice := store.Dec{}
 	for _, v := range txs {
 		if v.GasUsed < minGasUsed {
 			minGasUsed = v.GasUsed
@@ -87,15 +88,15 @@ func buildTxGas(txs []document.CommonTx) document.TxGas {
 		if v.GasUsed > maxGasUsed {
 			minGasUsed = v.GasUsed
 		}
-		totalGasUsed += float64(v.GasUsed)
+		totalGasUsed += v.GasUsed
 
-		if v.GasPrice < minGasPrice {
+		if v.GasPrice.Cmp(minGasPrice) < 0 {
 			minGasPrice = v.GasPrice
 		}
-		if v.GasPrice > maxGasPrice {
+		if v.GasPrice.Cmp(maxGasPrice) > 0 {
 			maxGasPrice = v.GasPrice
 		}
-		totalGasPrice += v.GasPrice
+		totalGasPrice = totalGasPrice.Add(v.GasPrice)
 	}
 
 	txGas = document.TxGas{
@@ -103,23 +104,23 @@ func buildTxGas(txs []document.CommonTx) document.TxGas {
 		GasUsed: document.GasUsed{
 			MinGasUsed: float64(minGasUsed),
 			MaxGasUsed: float64(maxGasUsed),
-			AvgGasUsed: totalGasUsed / float64(len(txs)),
+			AvgGasUsed: store.NewDec(totalGasUsed).QuoInt64(int64(len(txs))),
 		},
 		GasPrice: document.GasPrice{
 			Denom:       gasPriceDenom,
 			MinGasPrice: minGasPrice,
 			MaxGasPrice: maxGasPrice,
-			AvgGasPrice: totalGasPrice / float64(len(txs)),
+			AvgGasPrice: totalGasPrice.QuoInt64(int64(len(txs))),
 		},
 	}
 
 	return txGas
 }
 
-func MakeCalculateTxGasAndGasPriceTask() Task {
+func MakeCalculateTxGasAndGasPriceTask(env Env) Task {
 	return NewLockTaskFromEnv(conf.CronCalculateTxGas, "calculate_tx_gas_and_gas_price_lock", func() {
 		logger.Debug("========================task's trigger [CalculateTxGasAndGasPrice] begin===================")
-		calculateTxGasAndGasPrice()
+		calculateTxGasAndGasPrice(env)
 		logger.Debug("========================task's trigger [CalculateTxGasAndGasPrice] end===================")
 	})
 }
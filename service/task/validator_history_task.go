@@ -7,21 +7,21 @@ import (
 	"time"
 )
 
-func MakeValidatorHistoryTask() Task {
+func MakeValidatorHistoryTask(env Env) Task {
 	return NewLockTaskFromEnv(server.CronSaveValidatorHistory, "save_validator_history_lock", func() {
 		logger.Debug("========================task's trigger [CalculateAndSaveValidatorUpTime] begin===================")
-		SaveValidatorHistory()
+		SaveValidatorHistory(env)
 		logger.Debug("========================task's trigger [CalculateAndSaveValidatorUpTime] end===================")
 	})
 }
 
-func SaveValidatorHistory() {
+func SaveValidatorHistory(env Env) {
 
 	var vHistory []document.ValidatorHistory
 	var validatorsModel document.Candidate
 	var historyModel document.ValidatorHistory
 
-	validators := validatorsModel.QueryAll()
+	validators := validatorsModel.QueryAll(env.Store)
 
 	updateTime := time.Now()
 	for _, v := range validators {
@@ -31,7 +31,7 @@ func SaveValidatorHistory() {
 		})
 	}
 
-	if err := historyModel.RemoveAll(); err == nil {
-		historyModel.SaveAll(vHistory)
+	if err := historyModel.RemoveAll(env.Store); err == nil {
+		historyModel.SaveAll(env.Store, vHistory)
 	}
 }
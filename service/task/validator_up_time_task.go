@@ -3,16 +3,16 @@ package task
 import (
 	conf "github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
 	"github.com/irisnet/irishub-sync/util/constant"
-	"github.com/irisnet/irishub-sync/util/helper"
 )
 
 // calculate and save validator upTime
 // latest x blocks, calculate how much precommit which validator had execute(n).
 // so upTime is n / x
 // note: this method is not goroutine safety, it should be execute during watch block.
-func calculateAndSaveValidatorUpTime() {
+func calculateAndSaveValidatorUpTime(env Env) {
 	var (
 		methodName    = "AnalyzeValidatorUpTime"
 		intervalBlock = constant.IntervalBlockNumCalculateValidatorUpTime
@@ -23,7 +23,7 @@ func calculateAndSaveValidatorUpTime() {
 	)
 	logger.Info("Start", logger.String("method", methodName))
 	// query synced latest height
-	tasks, err := syncTaskModel.QueryAll([]string{document.SyncTaskStatusUnderway},
+	tasks, err := syncTaskModel.QueryAll(env.Store, []string{document.SyncTaskStatusUnderway},
 		document.SyncTaskTypeFollow)
 	if err != nil {
 		logger.Error("Query follow task failed", logger.String("err", err.Error()))
@@ -36,7 +36,7 @@ func calculateAndSaveValidatorUpTime() {
 	latestHeight := tasks[0].CurrentHeight
 
 	// get validator precommit
-	res, err := blockModel.CalculateValidatorPreCommit(latestHeight-intervalBlock, latestHeight)
+	res, err := blockModel.CalculateValidatorPreCommit(env.Store, latestHeight-intervalBlock, latestHeight)
 
 	if err != nil {
 		logger.Error("blockModel.CalculateValidatorPreCommit fail", logger.String("err", err.Error()))
@@ -45,23 +45,22 @@ func calculateAndSaveValidatorUpTime() {
 
 	if len(res) > 0 {
 		for _, v := range res {
-			tmp := float64(v.PreCommitsNum) / float64(intervalBlock) //注意必须是浮点数相除
 			valUpTime := document.ValidatorUpTime{
 				ValAddress: v.Address,
-				UpTime:     helper.RoundFloat(tmp*100, 0),
+				UpTime:     store.NewDec(v.PreCommitsNum * 100).QuoInt64(intervalBlock).Round(0),
 			}
 			valUpTimes = append(valUpTimes, valUpTime)
 		}
 
 		// remove all data
-		err := model.RemoveAll()
+		err := model.RemoveAll(env.Store)
 		if err != nil {
 			logger.Error("RemoveAll fail", logger.String("err", err.Error()))
 			return
 		}
 
 		// save latest data
-		err2 := model.SaveAll(valUpTimes)
+		err2 := model.SaveAll(env.Store, valUpTimes)
 		if err2 != nil {
 			logger.Error("SaveAll fail", logger.String("err", err2.Error()))
 			return
@@ -71,10 +70,10 @@ func calculateAndSaveValidatorUpTime() {
 	logger.Info("End", logger.String("method", methodName))
 }
 
-func MakeCalculateAndSaveValidatorUpTimeTask() Task {
+func MakeCalculateAndSaveValidatorUpTimeTask(env Env) Task {
 	return NewLockTaskFromEnv(conf.CronCalculateUpTime, "calculate_and_save_validator_uptime_lock", func() {
 		logger.Debug("========================task's trigger [CalculateAndSaveValidatorUpTime] begin===================")
-		calculateAndSaveValidatorUpTime()
+		calculateAndSaveValidatorUpTime(env)
 		logger.Debug("========================task's trigger [CalculateAndSaveValidatorUpTime] end===================")
 	})
 }
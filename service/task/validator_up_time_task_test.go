@@ -0,0 +1,89 @@
+package task
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	dbconf "github.com/irisnet/irishub-sync/conf/db"
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+)
+
+func Test_compactPreCommits(t *testing.T) {
+	var blockModel document.Block
+
+	env := newTestEnv(&stubNode{})
+
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(v string) { dbconf.ArchiveDir = v }(dbconf.ArchiveDir)
+	dbconf.ArchiveDir = dir
+
+	sets := []document.ValidatorSet{
+		{Hash: "set0", Validators: []document.Validator{{Address: "a"}, {Address: "b"}, {Address: "c"}}},
+		{Hash: "set1", Validators: []document.Validator{{Address: "d"}, {Address: "c"}}},
+	}
+	// validator set is saved with every block, but it's stored once
+	for height := int64(1); height <= 20; height++ {
+		set := sets[height/10%2]
+		precommits := []document.Vote{{ValidatorAddress: "c"}}
+		if height%2 == 0 {
+			precommits = append(precommits, document.Vote{ValidatorAddress: set.Validators[0].Address})
+		}
+		block := document.Block{
+			Height: height,
+			Hash:   fmt.Sprintf("block%d", height),
+			Meta:   document.BlockMeta{Header: document.Header{ValidatorsHash: set.Hash}},
+			Block:  document.BlockContent{LastCommit: document.Commit{Precommits: precommits}},
+		}
+		// blocks which are stored by previous versions have full precommits
+		if height > 5 {
+			signers, ok := set.SignerBitmap(precommits)
+			if !ok {
+				t.Fatalf("SignerBitmap() of block %v failed", height)
+			}
+			block.Block.LastCommit.Precommits = nil
+			block.Block.LastCommit.Signers = signers
+		}
+		batch := store.NewBulkBatch(env.Store)
+		if err := batch.Save(set); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Save(block); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, _ := env.Store.Find(document.CollectionNmValidatorSet, nil).Count(); n != 2 {
+		t.Errorf("got %v validator sets, want 2", n)
+	}
+	if _, ok := sets[0].SignerBitmap([]document.Vote{{ValidatorAddress: "d"}}); ok {
+		t.Error("SignerBitmap() of unknown signer should fail")
+	}
+
+	want := []document.ResValidatorPreCommits{
+		{Address: "c", PreCommitsNum: 20},
+		{Address: "a", PreCommitsNum: 5},
+		{Address: "d", PreCommitsNum: 5},
+	}
+	got, err := blockModel.CalculateValidatorPreCommit(env.Store, 0, 20)
+	if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("CalculateValidatorPreCommit() = %v, %v, want %v", got, err, want)
+	}
+
+	// compact blocks are counted in same way after they are archived
+	if _, err := blockModel.Archive(env.Store, 12); err != nil {
+		t.Fatal(err)
+	}
+	got, err = blockModel.CalculateValidatorPreCommit(env.Store, 0, 20)
+	if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("CalculateValidatorPreCommit() after archive = %v, %v, want %v", got, err, want)
+	}
+}
@@ -0,0 +1,381 @@
+// collect documents written during handling one block and commit them in one transaction,
+// or in bulk writes when documents are written during syncing blocks
+
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+const (
+	batchOpInsert = "insert"
+	batchOpUpdate = "update"
+	batchOpRemove = "remove"
+	// insert document or replace existed one, it's only used by bulk batch
+	batchOpUpsert = "upsert"
+)
+
+type batchOp struct {
+	kind string
+	id   interface{}
+	doc  Docs
+}
+
+type conditionalOp struct {
+	collection string
+	op         BulkOp
+}
+
+// Batch holds pending writes of documents, writes are not visible in db
+// until Commit is called, and all of them are applied or none of them is applied.
+// Pending documents are merged by primary key, so later write of same document
+// overwrites former one in batch.
+//
+// Bulk batch doesn't query documents in db when they are written, documents are written
+// by unordered bulk upserts of each collection when batch is committed, then raw ops are
+// committed in one transaction. writes of documents aren't atomic, so they should be idempotent
+// like documents parsed from block, which are same every time block is synced.
+// documents which are written on behalf of sync task are fenced by AddFence,
+// so worker which has lost task doesn't write them.
+type Batch struct {
+	store   Store
+	bulk    bool
+	ops     []*batchOp
+	pending map[string]*batchOp
+	extra   []txn.Op
+	// assertions which are checked before documents are written
+	fence []txn.Op
+	// conditional writes which are applied after batch is committed
+	conditional []conditionalOp
+}
+
+// create batch of documents which are written into s
+func NewBatch(s Store) *Batch {
+	return &Batch{
+		store:   s,
+		pending: make(map[string]*batchOp),
+	}
+}
+
+func NewBulkBatch(s Store) *Batch {
+	b := NewBatch(s)
+	b.bulk = true
+	return b
+}
+
+// insert document, return error when document exists in db or batch.
+// bulk batch only checks documents in batch, document existed in db is kept when batch is committed
+func (b *Batch) Save(h Docs) error {
+	op, ok := b.pending[batchKey(h)]
+	if ok && op.kind != batchOpRemove {
+		return errors.New("Record exists")
+	}
+	if b.bulk {
+		if ok {
+			op.kind, op.doc = batchOpUpsert, h
+			return nil
+		}
+		b.put(&batchOp{kind: batchOpInsert, doc: h})
+		return nil
+	}
+
+	if _, err := b.queryDocId(h); err != ErrNotFound {
+		if err != nil {
+			return err
+		}
+		return errors.New("Record exists")
+	}
+
+	b.put(&batchOp{kind: batchOpInsert, id: bson.NewObjectId(), doc: h})
+	return nil
+}
+
+// insert document if it doesn't exist in db or batch, otherwise update it
+func (b *Batch) SaveOrUpdate(h Docs) error {
+	if op, ok := b.pending[batchKey(h)]; ok {
+		if b.bulk {
+			op.kind = batchOpUpsert
+		} else if op.kind == batchOpRemove {
+			op.kind = batchOpUpdate
+		}
+		op.doc = h
+		return nil
+	}
+	if b.bulk {
+		b.put(&batchOp{kind: batchOpUpsert, doc: h})
+		return nil
+	}
+
+	id, err := b.queryDocId(h)
+	if err != nil {
+		if err == ErrNotFound {
+			b.put(&batchOp{kind: batchOpInsert, id: bson.NewObjectId(), doc: h})
+			return nil
+		}
+		return err
+	}
+
+	b.put(&batchOp{kind: batchOpUpdate, id: id, doc: h})
+	return nil
+}
+
+// update document which exists in db or batch,
+// bulk batch doesn't return ErrNotFound when document doesn't exist in db
+func (b *Batch) Update(h Docs) error {
+	if op, ok := b.pending[batchKey(h)]; ok {
+		if op.kind == batchOpRemove {
+			return ErrNotFound
+		}
+		if b.bulk && op.kind == batchOpInsert {
+			op.kind = batchOpUpsert
+		}
+		op.doc = h
+		return nil
+	}
+	if b.bulk {
+		b.put(&batchOp{kind: batchOpUpdate, doc: h})
+		return nil
+	}
+
+	id, err := b.queryDocId(h)
+	if err != nil {
+		return err
+	}
+
+	b.put(&batchOp{kind: batchOpUpdate, id: id, doc: h})
+	return nil
+}
+
+// delete document which exists in db or batch
+func (b *Batch) Delete(h Docs) error {
+	key := batchKey(h)
+	if op, ok := b.pending[key]; ok && b.bulk {
+		// document may exist in db, so it's always removed when batch is committed
+		if op.kind == batchOpRemove {
+			return ErrNotFound
+		}
+		op.kind = batchOpRemove
+		return nil
+	}
+	if op, ok := b.pending[key]; ok {
+		switch op.kind {
+		case batchOpInsert:
+			// document is never written into db, just drop it
+			delete(b.pending, key)
+			for i, v := range b.ops {
+				if v == op {
+					b.ops = append(b.ops[:i], b.ops[i+1:]...)
+					break
+				}
+			}
+		case batchOpUpdate:
+			op.kind = batchOpRemove
+		case batchOpRemove:
+			return ErrNotFound
+		}
+		return nil
+	}
+
+	if b.bulk {
+		b.put(&batchOp{kind: batchOpRemove, doc: h})
+		return nil
+	}
+
+	id, err := b.queryDocId(h)
+	if err != nil {
+		return err
+	}
+
+	b.put(&batchOp{kind: batchOpRemove, id: id, doc: h})
+	return nil
+}
+
+// delete all documents in collection which match selector, return num of deleted documents.
+// documents are removed by _id when batch is committed, they aren't tracked as pending documents
+func (b *Batch) DeleteAll(collection string, selector interface{}) (int, error) {
+	var docs []bson.M
+
+	if err := b.store.Find(collection, selector).Select(bson.M{"_id": 1}).All(&docs); err != nil {
+		return 0, err
+	}
+
+	for _, v := range docs {
+		b.extra = append(b.extra, txn.Op{
+			C:      collection,
+			Id:     v["_id"],
+			Assert: txn.DocExists,
+			Remove: true,
+		})
+	}
+	return len(docs), nil
+}
+
+// get store which batch writes into, documents which aren't in batch are read from it
+func (b *Batch) Store() Store {
+	return b.store
+}
+
+// get pending document which has same primary key with h,
+// return false when document isn't written in batch or is deleted in batch
+func (b *Batch) Get(h Docs) (Docs, bool) {
+	if op, ok := b.pending[batchKey(h)]; ok && op.kind != batchOpRemove {
+		return op.doc, true
+	}
+	return nil, false
+}
+
+// add raw transaction ops which are committed with documents in batch
+func (b *Batch) AddOps(ops ...txn.Op) {
+	b.extra = append(b.extra, ops...)
+}
+
+// add assertions which are checked before any write of batch is applied, e.g. fencing token of sync task.
+// bulk batch checks them in one transaction before documents are written, they may change after that,
+// so raw ops which are committed at last should assert them again
+func (b *Batch) AddFence(ops ...txn.Op) {
+	b.fence = append(b.fence, ops...)
+}
+
+// add conditional write of one document, which can't be expressed by transaction ops,
+// e.g. upsert which only updates document when condition in selector holds.
+// ops are applied one by one after writes of batch are committed, so they are skipped when transaction
+// is aborted. upsert whose selector doesn't match existed document conflicts with it by unique index,
+// duplicate key error of it is ignored. ops aren't atomic with batch, they should be idempotent
+func (b *Batch) AddConditionalOps(collection string, ops ...BulkOp) {
+	for _, op := range ops {
+		b.conditional = append(b.conditional, conditionalOp{collection: collection, op: op})
+	}
+}
+
+// commit all pending writes in one transaction,
+// bulk batch checks fence and writes documents in bulk before raw ops are committed in transaction.
+// conditional ops are applied at last, failure of them is logged since other writes have been committed
+func (b *Batch) Commit() error {
+	if err := b.commit(); err != nil {
+		return err
+	}
+
+	for _, v := range b.conditional {
+		if err := b.store.Bulk(v.collection, []BulkOp{v.op}); err != nil && !mgo.IsDup(err) {
+			logger.Error("apply conditional op of batch fail", logger.String("collection", v.collection),
+				logger.Any("selector", v.op.Selector), logger.String("err", err.Error()))
+		}
+	}
+	return nil
+}
+
+func (b *Batch) commit() error {
+	var (
+		ops []txn.Op
+	)
+
+	if b.bulk {
+		if len(b.fence) > 0 {
+			if err := b.store.Txn(b.fence); err != nil {
+				return err
+			}
+		}
+		if err := b.commitBulk(); err != nil {
+			return err
+		}
+		if len(b.extra) == 0 {
+			return nil
+		}
+		return b.store.Txn(b.extra)
+	}
+
+	ops = append(ops, b.fence...)
+	for _, op := range b.ops {
+		switch op.kind {
+		case batchOpInsert:
+			ops = append(ops, txn.Op{
+				C:      op.doc.Name(),
+				Id:     op.id,
+				Assert: txn.DocMissing,
+				Insert: op.doc,
+			})
+		case batchOpUpdate:
+			ops = append(ops, txn.Op{
+				C:      op.doc.Name(),
+				Id:     op.id,
+				Assert: txn.DocExists,
+				Update: bson.M{"$set": op.doc},
+			})
+		case batchOpRemove:
+			ops = append(ops, txn.Op{
+				C:      op.doc.Name(),
+				Id:     op.id,
+				Assert: txn.DocExists,
+				Remove: true,
+			})
+		}
+	}
+	ops = append(ops, b.extra...)
+
+	if len(ops) == 0 {
+		return nil
+	}
+	logger.Debug("commit batch", logger.Int("ops", len(ops)))
+
+	return b.store.Txn(ops)
+}
+
+// write pending documents by bulk of each collection, documents are matched by primary key
+func (b *Batch) commitBulk() error {
+	var (
+		collections []string
+		bulks       = make(map[string][]BulkOp)
+	)
+
+	for _, op := range b.ops {
+		bulkOp := BulkOp{Selector: op.doc.PkKvPair()}
+		switch op.kind {
+		case batchOpInsert:
+			bulkOp.Update, bulkOp.Upsert = bson.M{"$setOnInsert": op.doc}, true
+		case batchOpUpsert:
+			bulkOp.Update, bulkOp.Upsert = bson.M{"$set": op.doc}, true
+		case batchOpUpdate:
+			bulkOp.Update = bson.M{"$set": op.doc}
+		case batchOpRemove:
+			bulkOp.Remove = true
+		}
+
+		c := op.doc.Name()
+		if _, ok := bulks[c]; !ok {
+			collections = append(collections, c)
+		}
+		bulks[c] = append(bulks[c], bulkOp)
+	}
+
+	for _, c := range collections {
+		logger.Debug("commit bulk", logger.String("collection", c), logger.Int("ops", len(bulks[c])))
+		if err := b.store.Bulk(c, bulks[c]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Batch) put(op *batchOp) {
+	b.ops = append(b.ops, op)
+	b.pending[batchKey(op.doc)] = op
+}
+
+func batchKey(h Docs) string {
+	return fmt.Sprintf("%v:%v", h.Name(), h.PkKvPair())
+}
+
+// get _id of document by it's primary key
+func (b *Batch) queryDocId(h Docs) (interface{}, error) {
+	var result bson.M
+	if err := b.store.Find(h.Name(), h.PkKvPair()).Select(bson.M{"_id": 1}).One(&result); err != nil {
+		return nil, err
+	}
+	return result["_id"], nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+type batchTestDoc struct {
+	Key   string `bson:"key"`
+	Value int    `bson:"value"`
+}
+
+func (d batchTestDoc) Name() string {
+	return "batch_test"
+}
+
+func (d batchTestDoc) PkKvPair() map[string]interface{} {
+	return bson.M{"key": d.Key}
+}
+
+func TestBatch_Commit(t *testing.T) {
+	Start()
+	defer Stop()
+
+	doc := batchTestDoc{Key: bson.NewObjectId().Hex(), Value: 1}
+
+	batch := NewBatch(Backend())
+	if err := batch.Save(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Save(doc); err == nil {
+		t.Fatal("save same doc twice in batch should fail")
+	}
+	doc.Value = 2
+	if err := batch.SaveOrUpdate(doc); err != nil {
+		t.Fatal(err)
+	}
+	if pending, ok := batch.Get(doc); !ok || pending.(batchTestDoc).Value != 2 {
+		t.Fatal("pending doc should be updated")
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	batch = NewBatch(Backend())
+	if err := batch.Delete(doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBatch_bulk(t *testing.T) {
+	s := NewMemStore()
+	s.EnsureUniqueIndex(batchTestDoc{}.Name(), "key")
+
+	if err := s.Save(batchTestDoc{Key: "a", Value: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// documents of same block are written twice, when block is synced again
+	for i := 0; i < 2; i++ {
+		batch := NewBulkBatch(s)
+		if err := batch.Save(batchTestDoc{Key: "a", Value: 2}); err != nil {
+			t.Errorf("save existed document in bulk batch, error = %v", err)
+		}
+		if err := batch.Save(batchTestDoc{Key: "b", Value: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if err := batch.Save(batchTestDoc{Key: "b", Value: 1}); err == nil {
+			t.Error("save same doc twice in batch should fail")
+		}
+		batch.SaveOrUpdate(batchTestDoc{Key: "c", Value: 1})
+		batch.SaveOrUpdate(batchTestDoc{Key: "c", Value: 3})
+		batch.Save(batchTestDoc{Key: "d", Value: 1})
+		batch.Delete(batchTestDoc{Key: "d"})
+		batch.AddOps(txn.Op{C: "batch_test_block", Id: i, Insert: bson.M{"height": i}})
+		if err := batch.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var docs []batchTestDoc
+	if err := s.Find(batchTestDoc{}.Name(), nil).Sort("key").All(&docs); err != nil {
+		t.Fatal(err)
+	}
+	want := []batchTestDoc{{"a", 1}, {"b", 1}, {"c", 3}}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("docs = %v, want %v", docs, want)
+	}
+	if n, _ := s.Find("batch_test_block", nil).Count(); n != 2 {
+		t.Errorf("got %v raw ops committed, want 2", n)
+	}
+}
+
+func TestBatch_fence(t *testing.T) {
+	s := NewMemStore()
+	if err := s.Txn([]txn.Op{{C: "batch_test_task", Id: 1, Insert: bson.M{"fencing_token": 2}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		bulk         bool
+		fencingToken int
+		wantErr      error
+		wantDoc      bool
+	}{
+		{name: "bulk batch of stale worker", bulk: true, fencingToken: 1, wantErr: txn.ErrAborted},
+		{name: "batch of stale worker", fencingToken: 1, wantErr: txn.ErrAborted},
+		{name: "bulk batch of task owner", bulk: true, fencingToken: 2, wantDoc: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := NewBatch(s)
+			if tt.bulk {
+				batch = NewBulkBatch(s)
+			}
+			if err := batch.Save(batchTestDoc{Key: "a", Value: tt.fencingToken}); err != nil {
+				t.Fatal(err)
+			}
+			batch.AddFence(txn.Op{C: "batch_test_task", Id: 1, Assert: bson.M{"fencing_token": tt.fencingToken}})
+			if err := batch.Commit(); err != tt.wantErr {
+				t.Fatalf("Commit() error = %v, want %v", err, tt.wantErr)
+			}
+			n, _ := s.Find(batchTestDoc{}.Name(), nil).Count()
+			if (n > 0) != tt.wantDoc {
+				t.Errorf("got %v docs written, want written %v", n, tt.wantDoc)
+			}
+		})
+	}
+}
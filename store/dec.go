@@ -0,0 +1,255 @@
+// exact decimal number used by amounts
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// num of digits after point of Dec
+	DecPrecision = 18
+	// max num of significant digits of decimal128 in db
+	maxDecimal128Digits = 34
+)
+
+var (
+	precisionMultiplier = pow10(DecPrecision)
+	decRegexp           = regexp.MustCompile(`^([-+]?)([0-9]*)(?:\.([0-9]*))?(?:[eE]([-+]?[0-9]+))?$`)
+)
+
+// Dec is exact decimal number which has DecPrecision digits after point, it's used for amounts of coins,
+// tokens and shares which are too large or too precise for float64. zero value of Dec is 0.
+// it's stored as decimal128 in db, so db can compare and sum amounts exactly,
+// and it's encoded as string in json to keep all digits
+type Dec struct {
+	i *big.Int
+}
+
+func NewDec(n int64) Dec {
+	return Dec{new(big.Int).Mul(big.NewInt(n), precisionMultiplier)}
+}
+
+// parse decimal string like "123.456" or "1.5E+3", digits beyond DecPrecision are rounded half away from zero
+func ParseDec(s string) (Dec, error) {
+	m := decRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || m[2]+m[3] == "" {
+		return Dec{}, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	i, _ := new(big.Int).SetString(m[2]+m[3], 10)
+	if m[1] == "-" {
+		i.Neg(i)
+	}
+	// value is i * 10^exp
+	exp := -len(m[3])
+	if m[4] != "" {
+		e, err := strconv.Atoi(m[4])
+		if err != nil {
+			return Dec{}, fmt.Errorf("invalid decimal %q", s)
+		}
+		exp += e
+	}
+
+	exp += DecPrecision
+	if exp >= 0 {
+		return Dec{i.Mul(i, pow10(exp))}, nil
+	}
+	return Dec{quoRound(i, pow10(-exp))}, nil
+}
+
+// convert float64 to Dec, it's used to convert amounts which were stored as float64
+func DecFromFloat64(f float64) (Dec, error) {
+	return ParseDec(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+func (d Dec) int() *big.Int {
+	if d.i == nil {
+		return new(big.Int)
+	}
+	return d.i
+}
+
+func (d Dec) IsZero() bool {
+	return d.int().Sign() == 0
+}
+
+func (d Dec) Sign() int {
+	return d.int().Sign()
+}
+
+// compare d and o, return -1, 0 or 1
+func (d Dec) Cmp(o Dec) int {
+	return d.int().Cmp(o.int())
+}
+
+func (d Dec) Equal(o Dec) bool {
+	return d.Cmp(o) == 0
+}
+
+func (d Dec) Add(o Dec) Dec {
+	return Dec{new(big.Int).Add(d.int(), o.int())}
+}
+
+func (d Dec) Sub(o Dec) Dec {
+	return Dec{new(big.Int).Sub(d.int(), o.int())}
+}
+
+func (d Dec) Mul(o Dec) Dec {
+	return Dec{quoRound(new(big.Int).Mul(d.int(), o.int()), precisionMultiplier)}
+}
+
+func (d Dec) MulInt64(n int64) Dec {
+	return Dec{new(big.Int).Mul(d.int(), big.NewInt(n))}
+}
+
+// divide d by o, result is rounded half away from zero. it panics when o is zero
+func (d Dec) Quo(o Dec) Dec {
+	return Dec{quoRound(new(big.Int).Mul(d.int(), precisionMultiplier), o.int())}
+}
+
+// divide d by n, result is rounded half away from zero. it panics when n is zero
+func (d Dec) QuoInt64(n int64) Dec {
+	return Dec{quoRound(d.int(), big.NewInt(n))}
+}
+
+// round d to places digits after point, half away from zero
+func (d Dec) Round(places int) Dec {
+	if places >= DecPrecision {
+		return d
+	}
+	unit := pow10(DecPrecision - places)
+	return Dec{new(big.Int).Mul(quoRound(d.int(), unit), unit)}
+}
+
+// float64 value of d, it's only used for display and logs because it may lose precision
+func (d Dec) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// shortest decimal string of d, e.g. "100", "-0.5"
+func (d Dec) String() string {
+	i := d.int()
+	digits := new(big.Int).Abs(i).String()
+	if len(digits) <= DecPrecision {
+		digits = strings.Repeat("0", DecPrecision-len(digits)+1) + digits
+	}
+	intPart, fracPart := digits[:len(digits)-DecPrecision], strings.TrimRight(digits[len(digits)-DecPrecision:], "0")
+
+	s := intPart
+	if fracPart != "" {
+		s += "." + fracPart
+	}
+	if i.Sign() < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+func (d Dec) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// decode json string or number
+func (d *Dec) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		*d = Dec{}
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	v, err := ParseDec(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// encode d as decimal128, digits after point are rounded when d has more than 34 significant digits
+func (d Dec) GetBSON() (interface{}, error) {
+	v := d
+	intDigits := len(new(big.Int).Quo(new(big.Int).Abs(d.int()), precisionMultiplier).String())
+	if intDigits > maxDecimal128Digits {
+		return nil, fmt.Errorf("decimal %v is out of range of decimal128", d)
+	}
+	if places := maxDecimal128Digits - intDigits; places < DecPrecision {
+		v = d.Round(places)
+	}
+	return bson.ParseDecimal128(v.String())
+}
+
+// decode decimal128, and double, integer and string which are stored by previous versions
+func (d *Dec) SetBSON(raw bson.Raw) error {
+	var (
+		v   Dec
+		err error
+	)
+	switch raw.Kind {
+	case 0x13: // decimal128
+		var n bson.Decimal128
+		if err := raw.Unmarshal(&n); err != nil {
+			return err
+		}
+		v, err = ParseDec(n.String())
+	case 0x01: // double
+		var f float64
+		if err := raw.Unmarshal(&f); err != nil {
+			return err
+		}
+		v, err = DecFromFloat64(f)
+	case 0x10, 0x12: // int32, int64
+		var n int64
+		if err := raw.Unmarshal(&n); err != nil {
+			return err
+		}
+		v = NewDec(n)
+	case 0x02: // string
+		var s string
+		if err := raw.Unmarshal(&s); err != nil {
+			return err
+		}
+		v, err = ParseDec(s)
+	case 0x06, 0x0A: // undefined, null
+	default:
+		return fmt.Errorf("can't decode bson kind %v into Dec", raw.Kind)
+	}
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// divide x by y, result is rounded half away from zero
+func quoRound(x, y *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(x, y, new(big.Int))
+	if r.Sign() == 0 {
+		return q
+	}
+	// |2r| >= |y| means remainder is at least half of y
+	r2 := new(big.Int).Abs(r)
+	r2.Lsh(r2, 1)
+	if r2.Cmp(new(big.Int).Abs(y)) >= 0 {
+		if x.Sign()*y.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
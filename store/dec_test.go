@@ -0,0 +1,110 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestParseDec(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    string
+		wantErr bool
+	}{
+		{s: "123", want: "123"},
+		{s: "1234567890123456789012345678.000000000000000001", want: "1234567890123456789012345678.000000000000000001"},
+		{s: "-0.5", want: "-0.5"},
+		{s: ".25", want: "0.25"},
+		{s: "1.5E+3", want: "1500"},
+		{s: "1E-18", want: "0.000000000000000001"},
+		{s: "0.0000000000000000005", want: "0.000000000000000001"},
+		{s: "-0.0000000000000000005", want: "-0.000000000000000001"},
+		{s: "0.0000000000000000004", want: "0"},
+		{s: "", wantErr: true},
+		{s: "1.2.3", wantErr: true},
+		{s: "iris", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseDec(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("ParseDec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDec_arithmetic(t *testing.T) {
+	a, _ := ParseDec("100000000000000000000000000.1")
+	b, _ := ParseDec("0.2")
+
+	if got := a.Add(b).String(); got != "100000000000000000000000000.3" {
+		t.Errorf("Add() = %v", got)
+	}
+	if got := b.Sub(a).String(); got != "-99999999999999999999999999.9" {
+		t.Errorf("Sub() = %v", got)
+	}
+	if got := a.Mul(b).String(); got != "20000000000000000000000000.02" {
+		t.Errorf("Mul() = %v", got)
+	}
+	if got := NewDec(2).Quo(NewDec(3)).String(); got != "0.666666666666666667" {
+		t.Errorf("Quo() = %v", got)
+	}
+	if got := NewDec(985).QuoInt64(10).Round(0).String(); got != "99" {
+		t.Errorf("Round() = %v", got)
+	}
+	if a.Cmp(b) != 1 || !NewDec(0).Equal(Dec{}) || !(Dec{}).IsZero() {
+		t.Error("Cmp() of decimals is wrong")
+	}
+}
+
+func TestDec_encoding(t *testing.T) {
+	type coin struct {
+		Amount Dec `bson:"amount" json:"amount"`
+	}
+	amount, _ := ParseDec("1234567890123456789012345678.5")
+
+	data, err := bson.Marshal(coin{amount})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw bson.M
+	bson.Unmarshal(data, &raw)
+	if _, ok := raw["amount"].(bson.Decimal128); !ok {
+		t.Errorf("amount is stored as %T, want decimal128", raw["amount"])
+	}
+	var decoded coin
+	if err := bson.Unmarshal(data, &decoded); err != nil || !decoded.Amount.Equal(amount) {
+		t.Errorf("decoded amount = %v, %v", decoded.Amount, err)
+	}
+
+	// amounts stored by previous versions
+	for _, v := range []interface{}{1.5, int64(3), "2.25", nil} {
+		data, _ := bson.Marshal(bson.M{"amount": v})
+		var c coin
+		if err := bson.Unmarshal(data, &c); err != nil {
+			t.Errorf("decode %v error = %v", v, err)
+		}
+	}
+
+	// digits after point are rounded to fit decimal128
+	large, _ := ParseDec("1234567890123456789012345678901234.5")
+	v, err := large.GetBSON()
+	if err != nil || v.(bson.Decimal128).String() != "1234567890123456789012345678901235" {
+		t.Errorf("GetBSON() = %v, %v", v, err)
+	}
+
+	js, _ := json.Marshal(coin{amount})
+	if string(js) != `{"amount":"1234567890123456789012345678.5"}` {
+		t.Errorf("json = %s", js)
+	}
+	var fromJs coin
+	if err := json.Unmarshal([]byte(`{"amount":12.5}`), &fromJs); err != nil || fromJs.Amount.String() != "12.5" {
+		t.Errorf("decode json number = %v, %v", fromJs.Amount, err)
+	}
+}
@@ -1,8 +1,8 @@
 package document
 
 import (
+	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"time"
 )
@@ -14,13 +14,19 @@ const (
 	Account_Field_Amount = "amount"
 	Account_Field_Time   = "time"
 	Account_Field_Height = "height"
+
+	Account_Field_PubKey       = "pub_key"
+	Account_Field_PubKeyHeight = "pub_key_height"
 )
 
+// pubkey of account is recorded when account signs tx first time
 type Account struct {
-	Address string      `bson:"address"`
-	Amount  store.Coins `bson:"amount"`
-	Time    time.Time   `bson:"time"`
-	Height  int64       `bson:"height"`
+	Address      string      `bson:"address"`
+	Amount       store.Coins `bson:"amount"`
+	Time         time.Time   `bson:"time"`
+	Height       int64       `bson:"height"`
+	PubKey       string      `bson:"pub_key"`
+	PubKeyHeight int64       `bson:"pub_key_height"`
 }
 
 func (a Account) Name() string {
@@ -31,14 +37,9 @@ func (a Account) PkKvPair() map[string]interface{} {
 	return bson.M{Account_Field_Addres: a.Address}
 }
 
-func QueryAccount(address string) (Account, error) {
+func QueryAccount(s store.Store, address string) (Account, error) {
 	var result Account
-	query := func(c *mgo.Collection) error {
-		err := c.Find(bson.M{Account_Field_Addres: address}).Sort("-amount.amount").One(&result)
-		return err
-	}
-
-	err := store.ExecCollection(CollectionNmAccount, query)
+	err := s.Find(CollectionNmAccount, bson.M{Account_Field_Addres: address}).Sort("-amount.amount").One(&result)
 
 	if err != nil {
 		return result, err
@@ -46,3 +47,26 @@ func QueryAccount(address string) (Account, error) {
 
 	return result, nil
 }
+
+// remove accounts which first appear in height range (startHeight, endHeight], documents are removed when batch is committed
+func (a Account) RemoveByHeightRange(startHeight, endHeight int64, batch *store.Batch) error {
+	query := bson.M{
+		Account_Field_Height: bson.M{"$gt": startHeight, "$lte": endHeight},
+	}
+	num, err := batch.DeleteAll(a.Name(), query)
+	if err != nil {
+		return err
+	}
+	logger.Info("remove accounts", logger.Int64("start_height", startHeight),
+		logger.Int64("end_height", endHeight), logger.Int("num", num))
+	return nil
+}
+
+// get accounts whose pubkey is first seen in height range (startHeight, endHeight]
+func (a Account) QueryByPubKeyHeightRange(s store.Store, startHeight, endHeight int64) ([]Account, error) {
+	var accounts []Account
+	query := bson.M{
+		Account_Field_PubKeyHeight: bson.M{"$gt": startHeight, "$lte": endHeight},
+	}
+	return accounts, s.Find(a.Name(), query).All(&accounts)
+}
@@ -1,9 +1,11 @@
 package document
 
 import (
+	"fmt"
+	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"sort"
 	"time"
 )
 
@@ -17,8 +19,15 @@ const (
 	Block_Field_Meta       = "meta"
 	Block_Field_Block      = "block"
 	Block_Field_Validators = "validators"
+	Block_Field_Results    = "results"
+	Block_Field_Archived   = "archived"
+
+	Block_Field_ValidatorsHash = "meta.header.validators_hash"
+	Block_Field_Signers        = "block.last_commit.signers"
 )
 
+// validators of block are stored in validator set of meta.header.validators_hash,
+// Validators is only set in blocks which were stored by previous versions
 type Block struct {
 	Height     int64        `bson:"height"`
 	Hash       string       `bson:"hash"`
@@ -28,6 +37,8 @@ type Block struct {
 	Block      BlockContent `bson:"block"`
 	Validators []Validator  `bson:"validators"`
 	Result     BlockResults `bson:"results"`
+	// archived block only has header in db, it's content is in archive file
+	Archived bool `bson:"archived,omitempty"`
 }
 
 type BlockMeta struct {
@@ -80,6 +91,9 @@ type Commit struct {
 	// active ValidatorSet.
 	BlockID    BlockID `bson:"block_id"`
 	Precommits []Vote  `bson:"precommits"`
+	// compact form of precommits, bit i is set when validator i of validator set of block signed.
+	// Precommits is empty when it's set
+	Signers []byte `bson:"signers,omitempty"`
 }
 
 // Represents a prevote, precommit, or commit vote from validators for consensus.
@@ -178,7 +192,7 @@ type ResValidatorPreCommits struct {
 	PreCommitsNum int64  `bson:"num"`
 }
 
-func (d Block) CalculateValidatorPreCommit(startBlock, endBlock int64) ([]ResValidatorPreCommits, error) {
+func (d Block) CalculateValidatorPreCommit(s store.Store, startBlock, endBlock int64) ([]ResValidatorPreCommits, error) {
 
 	var res []ResValidatorPreCommits
 	query := []bson.M{
@@ -203,15 +217,204 @@ func (d Block) CalculateValidatorPreCommit(startBlock, endBlock int64) ([]ResVal
 		},
 	}
 
-	fun := func(c *mgo.Collection) error {
-		return c.Pipe(query).All(&res)
-	}
+	err := s.Aggregate(d.Name(), query, &res)
 
-	err := store.ExecCollection(d.Name(), fun)
+	if err != nil {
+		return nil, err
+	}
 
+	// precommits of compact blocks are signer bitmaps which are counted with validator sets,
+	// and precommits of archived blocks are counted from archive files
+	var compact []Block
+	compactQuery := bson.M{
+		Block_Field_Height:  bson.M{"$gt": startBlock, "$lte": endBlock},
+		Block_Field_Signers: bson.M{"$exists": true},
+	}
+	fields := bson.M{Block_Field_Height: 1, Block_Field_ValidatorsHash: 1, Block_Field_Signers: 1}
+	if err := s.Find(d.Name(), compactQuery).Select(fields).All(&compact); err != nil {
+		return nil, err
+	}
+	archived, err := d.QueryArchivedBlocks(s, startBlock, endBlock)
 	if err != nil {
 		return nil, err
 	}
+	if len(compact) == 0 && len(archived) == 0 {
+		return res, nil
+	}
+
+	nums := make(map[string]int64, len(res))
+	for _, v := range res {
+		nums[v.Address] += v.PreCommitsNum
+	}
+	if err := countPreCommits(s, append(compact, archived...), nums); err != nil {
+		return nil, err
+	}
+	res = res[:0]
+	for address, num := range nums {
+		res = append(res, ResValidatorPreCommits{Address: address, PreCommitsNum: num})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].PreCommitsNum != res[j].PreCommitsNum {
+			return res[i].PreCommitsNum > res[j].PreCommitsNum
+		}
+		return res[i].Address < res[j].Address
+	})
 
 	return res, nil
 }
+
+// count precommits of blocks by validator address, blocks may be in full or compact form
+func countPreCommits(s store.Store, blocks []Block, nums map[string]int64) error {
+	var (
+		hashes []string
+		seen   = make(map[string]bool)
+	)
+	for _, b := range blocks {
+		if hash := b.Meta.Header.ValidatorsHash; len(b.Block.LastCommit.Signers) > 0 && !seen[hash] {
+			hashes = append(hashes, hash)
+			seen[hash] = true
+		}
+	}
+	var sets map[string]ValidatorSet
+	if len(hashes) > 0 {
+		var err error
+		if sets, err = (ValidatorSet{}).QueryByHashes(s, hashes); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range blocks {
+		if len(b.Block.LastCommit.Signers) == 0 {
+			for _, v := range b.Block.LastCommit.Precommits {
+				nums[v.ValidatorAddress]++
+			}
+			continue
+		}
+		set, ok := sets[b.Meta.Header.ValidatorsHash]
+		if !ok {
+			return fmt.Errorf("validator set %v of block %v is missing", b.Meta.Header.ValidatorsHash, b.Height)
+		}
+		for _, address := range set.Signers(b.Block.LastCommit.Signers) {
+			nums[address]++
+		}
+	}
+	return nil
+}
+
+// get block by height, content of archived block is read from archive file
+func (d Block) GetBlockByHeight(s store.Store, height int64) (Block, error) {
+	var block Block
+
+	err := s.Find(d.Name(), bson.M{Block_Field_Height: height}).One(&block)
+	if err != nil {
+		return block, err
+	}
+	if block.Archived {
+		return d.rehydrate(block)
+	}
+	return block, nil
+}
+
+// remove blocks which height in (startHeight, endHeight], documents are removed when batch is committed
+func (d Block) RemoveByHeightRange(startHeight, endHeight int64, batch *store.Batch) error {
+	query := bson.M{
+		Block_Field_Height: bson.M{"$gt": startHeight, "$lte": endHeight},
+	}
+	num, err := batch.DeleteAll(d.Name(), query)
+	if err != nil {
+		return err
+	}
+	logger.Info("remove blocks", logger.Int64("start_height", startHeight),
+		logger.Int64("end_height", endHeight), logger.Int("num", num))
+	return nil
+}
+
+// get max height of stored blocks, return 0 when there is no block
+func (d Block) GetMaxHeight(s store.Store) (int64, error) {
+	var block Block
+
+	err := s.Find(d.Name(), nil).Select(bson.M{Block_Field_Height: 1}).Sort("-" + Block_Field_Height).One(&block)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return block.Height, nil
+}
+
+// get stored block which has min height
+func (d Block) GetFirstBlock(s store.Store) (Block, error) {
+	var block Block
+
+	err := s.Find(d.Name(), nil).Select(bson.M{
+		Block_Field_Height:     1,
+		Block_Field_Hash:       1,
+		"meta.header.chain_id": 1,
+	}).Sort(Block_Field_Height).One(&block)
+	if err != nil {
+		return block, err
+	}
+	return block, nil
+}
+
+// get num of blocks and total num of txs in blocks which height in (startHeight, endHeight]
+func (d Block) StatByHeightRange(s store.Store, startHeight, endHeight int64) (numBlocks, numTxs int64, err error) {
+	type statRes struct {
+		NumBlocks int64 `bson:"num_blocks"`
+		NumTxs    int64 `bson:"num_txs"`
+	}
+	var res []statRes
+
+	query := []bson.M{
+		{
+			"$match": bson.M{
+				Block_Field_Height: bson.M{"$gt": startHeight, "$lte": endHeight},
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id":        nil,
+				"num_blocks": bson.M{"$sum": 1},
+				"num_txs":    bson.M{"$sum": "$" + Block_Field_NumTxs},
+			},
+		},
+	}
+
+	if err := s.Aggregate(d.Name(), query, &res); err != nil {
+		return 0, 0, err
+	}
+	if len(res) > 0 {
+		return res[0].NumBlocks, res[0].NumTxs, nil
+	}
+	return 0, 0, nil
+}
+
+// query height and num_txs of blocks which height in (startHeight, endHeight], sorted by height
+func (d Block) QueryNumTxsByHeightRange(s store.Store, startHeight, endHeight int64) ([]Block, error) {
+	var blocks []Block
+
+	query := bson.M{
+		Block_Field_Height: bson.M{"$gt": startHeight, "$lte": endHeight},
+	}
+	fields := bson.M{
+		Block_Field_Height: 1,
+		Block_Field_NumTxs: 1,
+	}
+	return blocks, s.Find(d.Name(), query).Select(fields).Sort(Block_Field_Height).All(&blocks)
+}
+
+// get max height of blocks which time is before t, return 0 when there is no such block
+func (d Block) GetMaxHeightBefore(s store.Store, t time.Time) (int64, error) {
+	var block Block
+
+	query := bson.M{Block_Field_Time: bson.M{"$lt": t}}
+	err := s.Find(d.Name(), query).Select(bson.M{Block_Field_Height: 1}).Sort("-" + Block_Field_Height).One(&block)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return block.Height, nil
+}
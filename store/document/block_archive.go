@@ -0,0 +1,270 @@
+// archive of old blocks, full blocks are moved into compressed files on local disk
+// and slim blocks which only have header are kept in db
+
+package document
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	conf "github.com/irisnet/irishub-sync/conf/db"
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// blocks in every ArchiveFileBlockNum heights are archived into one file
+	ArchiveFileBlockNum = int64(1000)
+)
+
+// dir of archive files of blocks in current database
+func blockArchiveDir() string {
+	return filepath.Join(conf.ArchiveDir, conf.Database, CollectionNmBlock)
+}
+
+// file of blocks which height in [start, start+ArchiveFileBlockNum)
+func blockArchiveFile(height int64) string {
+	start := archiveFileStart(height)
+	return filepath.Join(blockArchiveDir(), fmt.Sprintf("block_%012d_%012d.bson.gz", start, start+ArchiveFileBlockNum-1))
+}
+
+func archiveFileStart(height int64) int64 {
+	return (height-1)/ArchiveFileBlockNum*ArchiveFileBlockNum + 1
+}
+
+// archive blocks which height <= endHeight and haven't been archived, return num of archived blocks.
+// blocks are written into archive files before they are slimmed in db, so blocks are never lost
+// when process exits during archiving, and blocks which are archived again replace them in files
+func (d Block) Archive(s store.Store, endHeight int64) (int, error) {
+	var (
+		first Block
+		num   int
+	)
+
+	notArchived := bson.M{
+		Block_Field_Height:   bson.M{"$lte": endHeight},
+		Block_Field_Archived: bson.M{"$exists": false},
+	}
+	err := s.Find(d.Name(), notArchived).Select(bson.M{Block_Field_Height: 1}).Sort(Block_Field_Height).One(&first)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	for start := archiveFileStart(first.Height); start <= endHeight; start += ArchiveFileBlockNum {
+		end := start + ArchiveFileBlockNum - 1
+		if end > endHeight {
+			end = endHeight
+		}
+		n, err := d.archiveRange(s, start, end)
+		if err != nil {
+			return num, err
+		}
+		num += n
+	}
+	return num, nil
+}
+
+// archive blocks in [start, end] which are in same archive file
+func (d Block) archiveRange(s store.Store, start, end int64) (int, error) {
+	var blocks []Block
+
+	query := bson.M{
+		Block_Field_Height:   bson.M{"$gte": start, "$lte": end},
+		Block_Field_Archived: bson.M{"$exists": false},
+	}
+	if err := s.Find(d.Name(), query).Sort(Block_Field_Height).All(&blocks); err != nil {
+		return 0, err
+	}
+	if len(blocks) == 0 {
+		return 0, nil
+	}
+
+	file := blockArchiveFile(start)
+	archived, err := readArchiveFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	byHeight := make(map[int64]Block, len(archived)+len(blocks))
+	for _, v := range append(archived, blocks...) {
+		byHeight[v.Height] = v
+	}
+	merged := make([]Block, 0, len(byHeight))
+	for _, v := range byHeight {
+		merged = append(merged, v)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Height < merged[j].Height
+	})
+	if err := writeArchiveFile(file, merged); err != nil {
+		return 0, err
+	}
+
+	// keep header of block, content of block is read from archive file when it's needed
+	ops := make([]store.BulkOp, 0, len(blocks))
+	for _, v := range blocks {
+		ops = append(ops, store.BulkOp{
+			Selector: bson.M{Block_Field_Height: v.Height},
+			Update: bson.M{
+				"$set":   bson.M{Block_Field_Archived: true},
+				"$unset": bson.M{Block_Field_Block: "", Block_Field_Validators: "", Block_Field_Results: ""},
+			},
+		})
+	}
+	if err := s.Bulk(d.Name(), ops); err != nil {
+		return 0, err
+	}
+
+	logger.Info("archive blocks", logger.Int64("start_height", start), logger.Int64("end_height", end),
+		logger.Int("num", len(blocks)), logger.String("file", file))
+	return len(blocks), nil
+}
+
+// get archived blocks which height in (startHeight, endHeight] from archive files, sorted by height
+func (d Block) QueryArchivedBlocks(s store.Store, startHeight, endHeight int64) ([]Block, error) {
+	var (
+		slims  []Block
+		blocks []Block
+	)
+
+	query := bson.M{
+		Block_Field_Height:   bson.M{"$gt": startHeight, "$lte": endHeight},
+		Block_Field_Archived: true,
+	}
+	if err := s.Find(d.Name(), query).Select(bson.M{Block_Field_Height: 1}).Sort(Block_Field_Height).All(&slims); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]Block)
+	for _, v := range slims {
+		file := blockArchiveFile(v.Height)
+		if _, ok := files[file]; !ok {
+			archived, err := readArchiveFile(file)
+			if err != nil {
+				return nil, err
+			}
+			files[file] = archived
+		}
+		block, ok := findArchivedBlock(files[file], v.Height)
+		if !ok {
+			return nil, fmt.Errorf("block %v is missing in archive file %v", v.Height, file)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// read full block from archive file
+func (d Block) rehydrate(slim Block) (Block, error) {
+	file := blockArchiveFile(slim.Height)
+	archived, err := readArchiveFile(file)
+	if err != nil {
+		return slim, err
+	}
+	block, ok := findArchivedBlock(archived, slim.Height)
+	if !ok || block.Hash != slim.Hash {
+		return slim, fmt.Errorf("block %v is missing in archive file %v", slim.Height, file)
+	}
+	block.Archived = true
+	return block, nil
+}
+
+// blocks in archive file are sorted by height
+func findArchivedBlock(blocks []Block, height int64) (Block, bool) {
+	i := sort.Search(len(blocks), func(i int) bool {
+		return blocks[i].Height >= height
+	})
+	if i < len(blocks) && blocks[i].Height == height {
+		return blocks[i], true
+	}
+	return Block{}, false
+}
+
+// archive file is gzip of bson documents of blocks
+func readArchiveFile(file string) ([]Block, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var (
+		blocks []Block
+		br     = bufio.NewReader(r)
+	)
+	for {
+		var size int32
+		if err := binary.Read(br, binary.LittleEndian, &size); err != nil {
+			if err == io.EOF {
+				return blocks, nil
+			}
+			return nil, err
+		}
+		if size < 5 {
+			return nil, fmt.Errorf("invalid document size %v in archive file %v", size, file)
+		}
+		data := make([]byte, size)
+		binary.LittleEndian.PutUint32(data, uint32(size))
+		if _, err := io.ReadFull(br, data[4:]); err != nil {
+			return nil, err
+		}
+		var block Block
+		if err := bson.Unmarshal(data, &block); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+// write file into temp file then rename it, so archive file is never partially written
+func writeArchiveFile(file string, blocks []Block) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(file), filepath.Base(file)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := gzip.NewWriter(tmp)
+	for _, v := range blocks {
+		data, err := bson.Marshal(v)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -38,7 +38,7 @@ func TestBlock_CountValidatorPreCommits(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := Block{}
-			res, err := d.CalculateValidatorPreCommit(tt.args.startBlock, tt.args.endBlock)
+			res, err := d.CalculateValidatorPreCommit(store.Backend(), tt.args.startBlock, tt.args.endBlock)
 			if err != nil {
 				logger.Error(err.Error())
 			}
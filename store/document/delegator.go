@@ -2,7 +2,6 @@ package document
 
 import (
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -18,11 +17,11 @@ const (
 )
 
 type Delegator struct {
-	Address        string  `bson:"address"`
-	ValidatorAddr  string  `bson:"validator_addr"` // validatorAddr
-	Shares         float64 `bson:"shares"`
-	OriginalShares string  `bson:"original_shares"`
-	BondedHeight   int64   `bson:"height"`
+	Address        string    `bson:"address"`
+	ValidatorAddr  string    `bson:"validator_addr"` // validatorAddr
+	Shares         store.Dec `bson:"shares"`
+	OriginalShares string    `bson:"original_shares"`
+	BondedHeight   int64     `bson:"height"`
 
 	UnbondingDelegation UnbondingDelegation `bson:"unbonding_delegation"`
 }
@@ -43,15 +42,12 @@ func (d Delegator) PkKvPair() map[string]interface{} {
 	return bson.M{Delegator_Field_Addres: d.Address, Delegator_Field_ValidatorAddr: d.ValidatorAddr}
 }
 
-func (d Delegator) QueryUnbonding() (results []Delegator) {
+func (d Delegator) QueryUnbonding(s store.Store) (results []Delegator) {
 	condition := bson.M{
 		"unbonding_delegation.balance.amount": bson.M{
 			"$gt": 0,
 		},
 	}
-	query := func(c *mgo.Collection) error {
-		return c.Find(condition).All(&results)
-	}
-	store.ExecCollection(d.Name(), query)
+	s.Find(d.Name(), condition).All(&results)
 	return results
 }
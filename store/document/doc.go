@@ -14,6 +14,10 @@ func init() {
 	store.RegisterDocs(new(ValidatorUpTime))
 	store.RegisterDocs(new(TxGas))
 	store.RegisterDocs(new(TxMsg))
+	store.RegisterDocs(new(ValidatorSet))
+	store.RegisterDocs(new(UndecodedTx))
 	store.RegisterDocs(new(SyncTask))
 	store.RegisterDocs(new(SyncConf))
+	store.RegisterDocs(new(SyncConfHistory))
+	store.RegisterDocs(new(SyncStatus))
 }
@@ -2,7 +2,7 @@ package document
 
 import (
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
+	"github.com/irisnet/irishub-sync/util/constant"
 	"gopkg.in/mgo.v2/bson"
 	"time"
 )
@@ -21,6 +21,7 @@ const (
 	Proposal_Field_VotingEndTime   = "voting_end_time"
 	Proposal_Field_TotalDeposit    = "total_deposit"
 	Proposal_Field_Votes           = "votes"
+	Proposal_Field_SwitchHeight    = "switch_height"
 )
 
 type Proposal struct {
@@ -35,6 +36,9 @@ type Proposal struct {
 	VotingEndTime   time.Time   `bson:"voting_end_time"`
 	TotalDeposit    store.Coins `bson:"total_deposit"`
 	Votes           []PVote     `bson:"votes"`
+	// protocol version and switch height of software upgrade proposal
+	Version      uint64 `bson:"version,omitempty"`
+	SwitchHeight int64  `bson:"switch_height,omitempty"`
 }
 
 type PVote struct {
@@ -51,14 +55,9 @@ func (m Proposal) PkKvPair() map[string]interface{} {
 	return bson.M{Proposal_Field_ProposalId: m.ProposalId}
 }
 
-func QueryProposal(proposalId uint64) (Proposal, error) {
+func QueryProposal(s store.Store, proposalId uint64) (Proposal, error) {
 	var result Proposal
-	query := func(c *mgo.Collection) error {
-		err := c.Find(bson.M{Proposal_Field_ProposalId: proposalId}).Sort("-submit_block").One(&result)
-		return err
-	}
-
-	err := store.ExecCollection(CollectionNmProposal, query)
+	err := s.Find(CollectionNmProposal, bson.M{Proposal_Field_ProposalId: proposalId}).Sort("-submit_block").One(&result)
 
 	if err != nil {
 		return result, err
@@ -66,16 +65,23 @@ func QueryProposal(proposalId uint64) (Proposal, error) {
 
 	return result, nil
 }
-func QueryByStatus(status []string) ([]Proposal, error) {
+func QueryByStatus(s store.Store, status []string) ([]Proposal, error) {
 	var result []Proposal
-	query := func(c *mgo.Collection) error {
-		err := c.Find(bson.M{Proposal_Field_Status: bson.M{"$in": status}}).All(&result)
-		return err
-	}
-	err := store.ExecCollection(CollectionNmProposal, query)
+	err := s.Find(CollectionNmProposal, bson.M{Proposal_Field_Status: bson.M{"$in": status}}).All(&result)
 
 	if err != nil {
 		return result, err
 	}
 	return result, nil
 }
+
+// query passed software upgrade proposals, sorted by switch height
+func QuerySoftwareUpgrades(s store.Store) ([]Proposal, error) {
+	var result []Proposal
+	query := bson.M{
+		Proposal_Field_Status:       constant.StatusPassed,
+		Proposal_Field_SwitchHeight: bson.M{"$gt": 0},
+	}
+	err := s.Find(CollectionNmProposal, query).Sort(Proposal_Field_SwitchHeight).All(&result)
+	return result, err
+}
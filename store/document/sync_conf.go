@@ -1,8 +1,9 @@
 package document
 
 import (
+	"fmt"
+
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -13,6 +14,8 @@ const (
 type SyncConf struct {
 	BlockNumPerWorkerHandle int64 `bson:"block_num_per_worker_handle"`
 	MaxWorkerSleepTime      int64 `bson:"max_worker_sleep_time"`
+	StartHeight             int64 `bson:"start_height"` // blocks below start height are not synced, 0 means sync from first block
+	HaltHeight              int64 `bson:"halt_height"`  // blocks above halt height are not synced, 0 means no halt height
 }
 
 func (d SyncConf) Name() string {
@@ -23,15 +26,11 @@ func (d SyncConf) PkKvPair() map[string]interface{} {
 	return bson.M{}
 }
 
-func (d SyncConf) GetConf() (SyncConf, error) {
+func (d SyncConf) GetConf(s store.Store) (SyncConf, error) {
 	var syncConf SyncConf
 
 	q := bson.M{}
-	fn := func(c *mgo.Collection) error {
-		return c.Find(q).One(&syncConf)
-	}
-
-	err := store.ExecCollection(d.Name(), fn)
+	err := s.Find(d.Name(), q).One(&syncConf)
 
 	if err != nil {
 		return syncConf, err
@@ -39,3 +38,20 @@ func (d SyncConf) GetConf() (SyncConf, error) {
 
 	return syncConf, nil
 }
+
+// validate sync conf, invalid conf shouldn't be applied
+func (d SyncConf) Validate() error {
+	if d.BlockNumPerWorkerHandle <= 0 {
+		return fmt.Errorf("block_num_per_worker_handle should greater than 0")
+	}
+	if d.MaxWorkerSleepTime <= 0 {
+		return fmt.Errorf("max_worker_sleep_time should greater than 0")
+	}
+	if d.StartHeight < 0 || d.HaltHeight < 0 {
+		return fmt.Errorf("start_height and halt_height should not less than 0")
+	}
+	if d.HaltHeight > 0 && d.HaltHeight < d.StartHeight {
+		return fmt.Errorf("halt_height should not less than start_height")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package document
+
+import (
+	"github.com/irisnet/irishub-sync/store"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	CollectionNmSyncConfHistory = "sync_conf_history"
+
+	SyncConfHistory_Field_Seq = "seq"
+
+	// value of status
+	SyncConfHistoryStatusApplied  = "applied"
+	SyncConfHistoryStatusRejected = "rejected"
+)
+
+// audit trail of sync conf, one record is saved for each change of sync conf.
+// seq is unique, so same change detected by several instances is only recorded once
+type SyncConfHistory struct {
+	Seq          int64    `bson:"seq"`
+	PreviousConf SyncConf `bson:"previous_conf"`
+	Conf         SyncConf `bson:"conf"`
+	Status       string   `bson:"status"` // applied or rejected
+	Err          string   `bson:"err"`    // reason of rejected
+	ChangeTime   int64    `bson:"change_time"`
+}
+
+func (d SyncConfHistory) Name() string {
+	return CollectionNmSyncConfHistory
+}
+
+func (d SyncConfHistory) PkKvPair() map[string]interface{} {
+	return bson.M{SyncConfHistory_Field_Seq: d.Seq}
+}
+
+// get latest change of sync conf
+func (d SyncConfHistory) GetLatest(s store.Store) (SyncConfHistory, error) {
+	var res SyncConfHistory
+
+	err := s.Find(d.Name(), nil).Sort("-" + SyncConfHistory_Field_Seq).One(&res)
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -2,6 +2,7 @@ package document
 
 import (
 	"encoding/json"
+	"github.com/irisnet/irishub-sync/store"
 	"testing"
 )
 
@@ -16,7 +17,7 @@ func TestSyncConf_GetConf(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := SyncConf{}
-			res, err := d.GetConf()
+			res, err := d.GetConf(store.Backend())
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -28,3 +29,39 @@ func TestSyncConf_GetConf(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncConf_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    SyncConf
+		wantErr bool
+	}{
+		{
+			name:    "test valid conf",
+			conf:    SyncConf{BlockNumPerWorkerHandle: 50, MaxWorkerSleepTime: 120, StartHeight: 100, HaltHeight: 200},
+			wantErr: false,
+		},
+		{
+			name:    "test invalid block num per worker handle",
+			conf:    SyncConf{BlockNumPerWorkerHandle: 0, MaxWorkerSleepTime: 120},
+			wantErr: true,
+		},
+		{
+			name:    "test invalid max worker sleep time",
+			conf:    SyncConf{BlockNumPerWorkerHandle: 50, MaxWorkerSleepTime: -1},
+			wantErr: true,
+		},
+		{
+			name:    "test halt height less than start height",
+			conf:    SyncConf{BlockNumPerWorkerHandle: 50, MaxWorkerSleepTime: 120, StartHeight: 200, HaltHeight: 100},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.conf.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package document
+
+import (
+	"github.com/irisnet/irishub-sync/store"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	CollectionNmSyncGap = "sync_gap"
+
+	SyncGap_Field_StartHeight = "start_height"
+	SyncGap_Field_EndHeight   = "end_height"
+	SyncGap_Field_Type        = "type"
+
+	// block in gap is missing
+	SyncGapTypeBlock = "block"
+	// block in gap exists, but num of txs mismatch num_txs of block
+	SyncGapTypeTx = "tx"
+)
+
+// gap is range of heights which is missing in block or tx_common,
+// gap is repaired by sync task which has same range
+type SyncGap struct {
+	StartHeight  int64  `bson:"start_height"`
+	EndHeight    int64  `bson:"end_height"`
+	Type         string `bson:"type"`
+	RepairTimes  int    `bson:"repair_times"`   // times of gap has been repaired
+	DetectTime   int64  `bson:"detect_time"`    // unix timestamp of first detected
+	LastRepairAt int64  `bson:"last_repair_at"` // unix timestamp of last repaired
+}
+
+func (d SyncGap) Name() string {
+	return CollectionNmSyncGap
+}
+
+func (d SyncGap) PkKvPair() map[string]interface{} {
+	return bson.M{
+		SyncGap_Field_StartHeight: d.StartHeight,
+		SyncGap_Field_EndHeight:   d.EndHeight,
+		SyncGap_Field_Type:        d.Type,
+	}
+}
+
+// get recorded gap which has same range and type
+func (d SyncGap) GetGap(s store.Store, gap SyncGap) (SyncGap, error) {
+	var res SyncGap
+
+	err := s.Find(d.Name(), gap.PkKvPair()).One(&res)
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
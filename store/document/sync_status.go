@@ -0,0 +1,51 @@
+package document
+
+import (
+	"github.com/irisnet/irishub-sync/store"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	CollectionNmSyncStatus = "sync_status"
+)
+
+// progress of sync, there is only one sync status document,
+// it's updated periodically and can be read by dashboards and health checks
+type SyncStatus struct {
+	LatestHeight    int64              `bson:"latest_height"`    // latest height of blockchain
+	SyncedBlocks    int64              `bson:"synced_blocks"`    // num of blocks synced by all tasks
+	RemainingBlocks int64              `bson:"remaining_blocks"` // num of blocks not synced below latest height
+	SyncRate        float64            `bson:"sync_rate"`        // blocks per second since last update
+	Eta             int64              `bson:"eta"`              // seconds to sync remaining blocks, -1 when unknown
+	Tasks           []SyncTaskProgress `bson:"tasks"`            // progress of unfinished tasks
+	UpdateTime      int64              `bson:"update_time"`      // unix timestamp
+}
+
+type SyncTaskProgress struct {
+	TaskId          string  `bson:"task_id"`
+	Type            string  `bson:"type"`
+	StartHeight     int64   `bson:"start_height"`
+	EndHeight       int64   `bson:"end_height"`
+	CurrentHeight   int64   `bson:"current_height"`
+	WorkerId        string  `bson:"worker_id"`
+	RemainingBlocks int64   `bson:"remaining_blocks"`
+	Progress        float64 `bson:"progress"` // percent of synced blocks in task
+}
+
+func (d SyncStatus) Name() string {
+	return CollectionNmSyncStatus
+}
+
+func (d SyncStatus) PkKvPair() map[string]interface{} {
+	return bson.M{}
+}
+
+func (d SyncStatus) GetStatus(s store.Store) (SyncStatus, error) {
+	var status SyncStatus
+
+	err := s.Find(d.Name(), nil).One(&status)
+	if err != nil {
+		return status, err
+	}
+	return status, nil
+}
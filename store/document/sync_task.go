@@ -2,7 +2,6 @@ package document
 
 import (
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"time"
 )
@@ -38,6 +37,13 @@ type SyncTask struct {
 	WorkerId       string        `bson:"worker_id"`        // worker id
 	WorkerLogs     []WorkerLog   `bson:"worker_logs"`      // worker logs
 	LastUpdateTime int64         `bson:"last_update_time"` // unix timestamp
+
+	// task is leased to worker which takes over it, lease is renewed by worker periodically,
+	// and task can be taken over by other worker after lease expired.
+	// fencing token increases every time task is taken over, every write made on behalf of task
+	// asserts it, so write of stale worker is rejected by db.
+	LeaseExpireTime int64 `bson:"lease_expire_time"` // unix timestamp
+	FencingToken    int64 `bson:"fencing_token"`
 }
 
 func (d SyncTask) Name() string {
@@ -49,7 +55,7 @@ func (d SyncTask) PkKvPair() map[string]interface{} {
 }
 
 // get max block height in sync task
-func (d SyncTask) GetMaxBlockHeight() (int64, error) {
+func (d SyncTask) GetMaxBlockHeight(s store.Store) (int64, error) {
 	type maxHeightRes struct {
 		MaxHeight int64 `bson:"max"`
 	}
@@ -64,10 +70,7 @@ func (d SyncTask) GetMaxBlockHeight() (int64, error) {
 		},
 	}
 
-	getMaxBlockHeightFn := func(c *mgo.Collection) error {
-		return c.Pipe(q).All(&res)
-	}
-	err := store.ExecCollection(d.Name(), getMaxBlockHeightFn)
+	err := s.Aggregate(d.Name(), q, &res)
 
 	if err != nil {
 		return 0, err
@@ -80,7 +83,7 @@ func (d SyncTask) GetMaxBlockHeight() (int64, error) {
 }
 
 // query record by status
-func (d SyncTask) QueryAll(status []string, taskType string) ([]SyncTask, error) {
+func (d SyncTask) QueryAll(s store.Store, status []string, taskType string) ([]SyncTask, error) {
 	var syncTasks []SyncTask
 	q := bson.M{}
 
@@ -103,11 +106,7 @@ func (d SyncTask) QueryAll(status []string, taskType string) ([]SyncTask, error)
 		break
 	}
 
-	fn := func(c *mgo.Collection) error {
-		return c.Find(q).All(&syncTasks)
-	}
-
-	err := store.ExecCollection(d.Name(), fn)
+	err := s.Find(d.Name(), q).All(&syncTasks)
 
 	if err != nil {
 		return syncTasks, err
@@ -116,7 +115,8 @@ func (d SyncTask) QueryAll(status []string, taskType string) ([]SyncTask, error)
 	return syncTasks, nil
 }
 
-func (d SyncTask) GetExecutableTask(maxWorkerSleepTime int64) ([]SyncTask, error) {
+// get tasks which are unhandled or lease of them expired
+func (d SyncTask) GetExecutableTask(s store.Store, maxWorkerSleepTime int64) ([]SyncTask, error) {
 	var tasks []SyncTask
 
 	t := time.Now().Add(time.Duration(-maxWorkerSleepTime) * time.Second).Unix()
@@ -127,6 +127,16 @@ func (d SyncTask) GetExecutableTask(maxWorkerSleepTime int64) ([]SyncTask, error
 			},
 			{
 				"status": SyncTaskStatusUnderway,
+				"lease_expire_time": bson.M{
+					"$lt": time.Now().Unix(),
+				},
+			},
+			// task which was taken over before lease introduced
+			{
+				"status": SyncTaskStatusUnderway,
+				"lease_expire_time": bson.M{
+					"$exists": false,
+				},
 				"last_update_time": bson.M{
 					"$lt": t,
 				},
@@ -134,11 +144,7 @@ func (d SyncTask) GetExecutableTask(maxWorkerSleepTime int64) ([]SyncTask, error
 		},
 	}
 
-	fn := func(c *mgo.Collection) error {
-		return c.Find(q).Sort("-status", "start_height").All(&tasks)
-	}
-
-	err := store.ExecCollection(d.Name(), fn)
+	err := s.Find(d.Name(), q).Sort("-status", "start_height").All(&tasks)
 
 	if err != nil {
 		return tasks, err
@@ -147,76 +153,156 @@ func (d SyncTask) GetExecutableTask(maxWorkerSleepTime int64) ([]SyncTask, error
 	return tasks, nil
 }
 
-func (d SyncTask) GetTaskById(id bson.ObjectId) (SyncTask, error) {
+func (d SyncTask) GetTaskById(s store.Store, id bson.ObjectId) (SyncTask, error) {
 	var task SyncTask
 
-	fn := func(c *mgo.Collection) error {
-		return c.FindId(id).One(&task)
-	}
-
-	err := store.ExecCollection(d.Name(), fn)
+	err := s.Find(d.Name(), bson.M{"_id": id}).One(&task)
 	if err != nil {
 		return task, err
 	}
 	return task, nil
 }
 
-func (d SyncTask) GetTaskByIdAndWorker(id bson.ObjectId, worker string) (SyncTask, error) {
+func (d SyncTask) GetTaskByIdAndWorker(s store.Store, id bson.ObjectId, worker string) (SyncTask, error) {
 	var task SyncTask
 
-	fn := func(c *mgo.Collection) error {
-		q := bson.M{
-			"_id":       id,
-			"worker_id": worker,
-		}
-
-		return c.Find(q).One(&task)
+	q := bson.M{
+		"_id":       id,
+		"worker_id": worker,
 	}
 
-	err := store.ExecCollection(d.Name(), fn)
+	err := s.Find(d.Name(), q).One(&task)
 	if err != nil {
 		return task, err
 	}
 	return task, nil
 }
 
-// take over a task
-// update status, worker_id, worker_logs and last_update_time
-func (d SyncTask) TakeOverTask(task SyncTask, workerId string) error {
+// take over a task, task is leased to worker for leaseDuration seconds
+// update status, worker_id, worker_logs, last_update_time, lease and fencing token.
+// return task owned by worker
+func (d SyncTask) TakeOverTask(s store.Store, task SyncTask, workerId string, leaseDuration int64) (SyncTask, error) {
 	// multiple goroutine attempt to update same record,
-	// use this selector to ensure only one goroutine can update success at same time
-	fn := func(c *mgo.Collection) error {
-		selector := bson.M{
-			"_id":              task.ID,
-			"last_update_time": task.LastUpdateTime,
-		}
+	// use this selector to ensure only one goroutine can update success at same time,
+	// and lease which has been renewed won't be taken over
+	selector := bson.M{
+		"_id":               task.ID,
+		"fencing_token":     zeroOrMissing(task.FencingToken),
+		"lease_expire_time": zeroOrMissing(task.LeaseExpireTime),
+	}
 
-		task.Status = SyncTaskStatusUnderway
-		task.WorkerId = workerId
-		task.LastUpdateTime = time.Now().Unix()
-		task.WorkerLogs = append(task.WorkerLogs, WorkerLog{
-			WorkerId:  workerId,
-			BeginTime: time.Now(),
-		})
+	now := time.Now()
+	task.Status = SyncTaskStatusUnderway
+	task.WorkerId = workerId
+	task.LastUpdateTime = now.Unix()
+	task.LeaseExpireTime = now.Unix() + leaseDuration
+	task.FencingToken++
+	task.WorkerLogs = append(task.WorkerLogs, WorkerLog{
+		WorkerId:  workerId,
+		BeginTime: now,
+	})
+
+	if err := s.UpdateOne(d.Name(), selector, task); err != nil {
+		return task, err
+	}
+	return task, nil
+}
 
-		return c.Update(selector, task)
+// renew lease of task which is owned by worker holding fencing token of task,
+// return store.ErrNotFound when task has been taken over by other worker
+func (d SyncTask) RenewLease(s store.Store, task SyncTask, leaseDuration int64) error {
+	selector := bson.M{
+		"_id":           task.ID,
+		"fencing_token": task.FencingToken,
+	}
+	now := time.Now().Unix()
+	update := bson.M{
+		"$set": bson.M{
+			"last_update_time":  now,
+			"lease_expire_time": now + leaseDuration,
+		},
 	}
 
-	return store.ExecCollection(d.Name(), fn)
+	return s.UpdateOne(d.Name(), selector, update)
 }
 
-// update task last update time
-func (d SyncTask) UpdateLastUpdateTime(task SyncTask) error {
-	fn := func(c *mgo.Collection) error {
-		selector := bson.M{
-			"_id":       task.ID,
-			"worker_id": task.WorkerId,
+// release task which is owned by worker,
+// released task can be taken over by other worker immediately
+func (d SyncTask) ReleaseTask(s store.Store, task SyncTask) error {
+	selector := bson.M{
+		"_id":           task.ID,
+		"fencing_token": task.FencingToken,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":            SyncTaskStatusUnHandled,
+			"worker_id":         "",
+			"last_update_time":  time.Now().Unix(),
+			"lease_expire_time": 0,
+		},
+	}
+
+	return s.UpdateOne(d.Name(), selector, update)
+}
+
+// complete follow task which reached halt height,
+// end_height of task is set to it's synced height, so new follow task won't be created
+func (d SyncTask) CompleteFollowTask(s store.Store, task SyncTask) error {
+	endHeight := task.CurrentHeight
+	if endHeight == 0 {
+		endHeight = task.StartHeight - 1
+	}
+
+	selector := bson.M{
+		"_id":           task.ID,
+		"fencing_token": task.FencingToken,
+		"end_height":    0,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":           SyncTaskStatusCompleted,
+			"end_height":       endHeight,
+			"last_update_time": time.Now().Unix(),
+		},
+	}
+
+	return s.UpdateOne(d.Name(), selector, update)
+}
+
+// get min start height of sync tasks, return 0 when there is no task
+func (d SyncTask) GetMinStartHeight(s store.Store) (int64, error) {
+	var task SyncTask
+
+	err := s.Find(d.Name(), nil).Sort("start_height").One(&task)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return 0, nil
 		}
+		return 0, err
+	}
+	return task.StartHeight, nil
+}
 
-		task.LastUpdateTime = time.Now().Unix()
+// get task by start height and end height
+func (d SyncTask) GetTaskByRange(s store.Store, startHeight, endHeight int64) (SyncTask, error) {
+	var task SyncTask
 
-		return c.Update(selector, task)
+	q := bson.M{
+		"start_height": startHeight,
+		"end_height":   endHeight,
 	}
 
-	return store.ExecCollection(d.Name(), fn)
+	err := s.Find(d.Name(), q).One(&task)
+	if err != nil {
+		return task, err
+	}
+	return task, nil
+}
+
+// field which is zero may be missing in task created before it's introduced
+func zeroOrMissing(v int64) interface{} {
+	if v == 0 {
+		return bson.M{"$in": []interface{}{0, nil}}
+	}
+	return v
 }
@@ -2,7 +2,6 @@ package document
 
 import (
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"time"
 )
@@ -31,14 +30,9 @@ func (c SyncTaskBak) PkKvPair() map[string]interface{} {
 	return bson.M{SyncTask_Field_ChainID: c.ChainID}
 }
 
-func QuerySyncTask() (SyncTaskBak, error) {
+func QuerySyncTask(s store.Store) (SyncTaskBak, error) {
 	result := SyncTaskBak{}
 
-	query := func(c *mgo.Collection) error {
-		err := c.Find(bson.M{}).One(&result)
-		return err
-	}
-
-	err := store.ExecCollection(CollectionNmSyncTask, query)
+	err := s.Find(CollectionNmSyncTask, bson.M{}).One(&result)
 	return result, err
 }
@@ -2,6 +2,7 @@ package document
 
 import (
 	"encoding/json"
+	"github.com/irisnet/irishub-sync/store"
 	"testing"
 	"time"
 
@@ -20,7 +21,7 @@ func TestSyncTask_GetMaxBlockHeight(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := SyncTask{}
-			res, err := d.GetMaxBlockHeight()
+			res, err := d.GetMaxBlockHeight(store.Backend())
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -45,7 +46,7 @@ func TestSyncTask_QueryAll(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := SyncTask{}
-			res, err := d.QueryAll(tt.args.status, tt.args.taskType)
+			res, err := d.QueryAll(store.Backend(), tt.args.status, tt.args.taskType)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -76,7 +77,7 @@ func TestSyncTask_GetExecutableTask(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := SyncTask{}
-			res, err := d.GetExecutableTask(tt.args.t)
+			res, err := d.GetExecutableTask(store.Backend(), tt.args.t)
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -98,7 +99,7 @@ func TestSyncTask_TakeOverTask(t *testing.T) {
 		syncTaskModel SyncTask
 	)
 
-	task1, _ := syncTaskModel.GetTaskById(bson.ObjectIdHex("5c176dc63b6c5c4027b8fb92"))
+	task1, _ := syncTaskModel.GetTaskById(store.Backend(), bson.ObjectIdHex("5c176dc63b6c5c4027b8fb92"))
 
 	tests := []struct {
 		name string
@@ -115,7 +116,7 @@ func TestSyncTask_TakeOverTask(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := SyncTask{}
-			err := d.TakeOverTask(tt.args.task, tt.args.workerId)
+			_, err := d.TakeOverTask(store.Backend(), tt.args.task, tt.args.workerId, 120)
 			if err != nil {
 				if err == mgo.ErrNotFound {
 					t.Log("this task has been take over by other goroutine")
@@ -148,7 +149,7 @@ func TestSyncTask_GetTaskByIdAndWorker(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := SyncTask{}
-			res, err := d.GetTaskByIdAndWorker(tt.args.id, tt.args.worker)
+			res, err := d.GetTaskByIdAndWorker(store.Backend(), tt.args.id, tt.args.worker)
 			if err != nil {
 				if err == mgo.ErrNotFound {
 					t.Fatalf("can't find task, err is %v\n", err)
@@ -161,9 +162,9 @@ func TestSyncTask_GetTaskByIdAndWorker(t *testing.T) {
 	}
 }
 
-func TestSyncTask_UpdateLastUpdateTime(t *testing.T) {
+func TestSyncTask_RenewLease(t *testing.T) {
 	d := SyncTask{}
-	task, err := d.GetTaskById(bson.ObjectIdHex("5c3bf4ee8bd9750001d0165f"))
+	task, err := d.GetTaskById(store.Backend(), bson.ObjectIdHex("5c3bf4ee8bd9750001d0165f"))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -175,7 +176,7 @@ func TestSyncTask_UpdateLastUpdateTime(t *testing.T) {
 		args args
 	}{
 		{
-			name: "test update last update time",
+			name: "test renew lease",
 			args: args{
 				task: task,
 			},
@@ -183,7 +184,7 @@ func TestSyncTask_UpdateLastUpdateTime(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := d.UpdateLastUpdateTime(tt.args.task); err != nil {
+			if err := d.RenewLease(store.Backend(), tt.args.task, 120); err != nil {
 				t.Fatal(err)
 			}
 			t.Log("success")
@@ -1,8 +1,8 @@
 package document
 
 import (
+	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"time"
 )
@@ -31,8 +31,12 @@ const (
 	Tx_Field_Tags                 = "tags"
 	Tx_Field_StakeCreateValidator = "stake_create_validator"
 	Tx_Field_StakeEditValidator   = "stake_edit_validator"
+	Tx_Field_Msgs                 = "msgs"
+	Tx_Field_Signers              = "signers"
 )
 
+// fields of first msg (from, to, amount, type ...) are kept in tx for compatibility,
+// all msgs of tx are stored in msgs in order
 type CommonTx struct {
 	Time       time.Time         `bson:"time"`
 	Height     int64             `bson:"height"`
@@ -47,11 +51,40 @@ type CommonTx struct {
 	Code       uint32            `bson:"code"`
 	Log        string            `bson:"log"`
 	GasUsed    int64             `bson:"gas_used"`
-	GasPrice   float64           `bson:"gas_price"`
+	GasPrice   store.Dec         `bson:"gas_price"`
 	ActualFee  store.ActualFee   `bson:"actual_fee"`
 	ProposalId uint64            `bson:"proposal_id"`
 	Tags       map[string]string `bson:"tags"`
 
+	StakeCreateValidator StakeCreateValidator `bson:"stake_create_validator"`
+	StakeEditValidator   StakeEditValidator   `bson:"stake_edit_validator"`
+	Msg                  store.Msg            `bson:"-"`
+	Msgs                 []CommonMsg          `bson:"msgs"`
+	Signers              []Signer             `bson:"signers"`
+}
+
+// signer of tx, it's parsed from signature of tx.
+// pubkeys of multisig signer are set when signer is multisig account
+type Signer struct {
+	Address           string   `bson:"address"`
+	PubKey            string   `bson:"pub_key"`
+	Sequence          uint64   `bson:"sequence"`
+	AccountNumber     uint64   `bson:"account_number"`
+	MultisigThreshold uint     `bson:"multisig_threshold,omitempty"`
+	MultisigPubKeys   []string `bson:"multisig_pub_keys,omitempty"`
+}
+
+// msg of tx, each msg has it's own from, to and amount
+type CommonMsg struct {
+	Index      int         `bson:"index"`
+	Type       string      `bson:"type"`
+	From       string      `bson:"from"`
+	To         string      `bson:"to"`
+	Amount     store.Coins `bson:"amount"`
+	ProposalId uint64      `bson:"proposal_id"`
+	// addresses of accounts which msg touches
+	Accounts []string `bson:"accounts,omitempty"`
+
 	StakeCreateValidator StakeCreateValidator `bson:"stake_create_validator"`
 	StakeEditValidator   StakeEditValidator   `bson:"stake_edit_validator"`
 	Msg                  store.Msg            `bson:"-"`
@@ -82,15 +115,55 @@ func (d CommonTx) PkKvPair() map[string]interface{} {
 	return bson.M{Tx_Field_Hash: d.TxHash}
 }
 
-func (d CommonTx) Query(query, fields bson.M, sort []string, skip, limit int) (
-	results []CommonTx, err error) {
-	exop := func(c *mgo.Collection) error {
-		return c.Find(query).Sort(sort...).Select(fields).Skip(skip).Limit(limit).All(&results)
+// split tx into txs which only contain one msg,
+// msg fields of tx are replaced by fields of msg, so handlers can handle msgs one by one
+func (d CommonTx) SplitMsgs() []CommonTx {
+	var txs []CommonTx
+
+	if len(d.Msgs) == 0 {
+		return []CommonTx{d}
+	}
+
+	for _, msg := range d.Msgs {
+		tx := d
+		tx.Type = msg.Type
+		tx.From = msg.From
+		tx.To = msg.To
+		tx.Amount = msg.Amount
+		tx.ProposalId = msg.ProposalId
+		tx.StakeCreateValidator = msg.StakeCreateValidator
+		tx.StakeEditValidator = msg.StakeEditValidator
+		tx.Msg = msg.Msg
+		tx.Msgs = []CommonMsg{msg}
+		txs = append(txs, tx)
 	}
-	return results, store.ExecCollection(d.Name(), exop)
+
+	return txs
+}
+
+// get addresses of accounts which msgs of tx touch, duplicated addresses are removed
+func (d CommonTx) Accounts() []string {
+	var (
+		accounts []string
+		seen     = make(map[string]bool)
+	)
+	for _, msg := range d.Msgs {
+		for _, address := range msg.Accounts {
+			if address != "" && !seen[address] {
+				accounts = append(accounts, address)
+				seen[address] = true
+			}
+		}
+	}
+	return accounts
 }
 
-func (d CommonTx) CalculateTxGasAndGasPrice(txType string, limit int) (
+func (d CommonTx) Query(s store.Store, query, fields bson.M, sort []string, skip, limit int) (
+	results []CommonTx, err error) {
+	return results, s.Find(d.Name(), query).Sort(sort...).Select(fields).Skip(skip).Limit(limit).All(&results)
+}
+
+func (d CommonTx) CalculateTxGasAndGasPrice(s store.Store, txType string, limit int) (
 	[]CommonTx, error) {
 	query := bson.M{
 		Tx_Field_Type:   txType,
@@ -100,5 +173,78 @@ func (d CommonTx) CalculateTxGasAndGasPrice(txType string, limit int) (
 	sort := []string{"-height"}
 	skip := 0
 
-	return d.Query(query, fields, sort, skip, limit)
+	return d.Query(s, query, fields, sort, skip, limit)
+}
+
+// query txs which height in (startHeight, endHeight]
+func (d CommonTx) QueryByHeightRange(s store.Store, startHeight, endHeight int64) ([]CommonTx, error) {
+	query := bson.M{
+		Tx_Field_Height: bson.M{"$gt": startHeight, "$lte": endHeight},
+	}
+	sort := []string{"-height"}
+
+	return d.Query(s, query, bson.M{}, sort, 0, 0)
+}
+
+// remove txs which height in (startHeight, endHeight], documents are removed when batch is committed
+func (d CommonTx) RemoveByHeightRange(startHeight, endHeight int64, batch *store.Batch) error {
+	query := bson.M{
+		Tx_Field_Height: bson.M{"$gt": startHeight, "$lte": endHeight},
+	}
+	num, err := batch.DeleteAll(d.Name(), query)
+	if err != nil {
+		return err
+	}
+	logger.Info("remove txs", logger.Int64("start_height", startHeight),
+		logger.Int64("end_height", endHeight), logger.Int("num", num))
+	return nil
+}
+
+// count txs which height in (startHeight, endHeight]
+func (d CommonTx) CountByHeightRange(s store.Store, startHeight, endHeight int64) (int64, error) {
+	query := bson.M{
+		Tx_Field_Height: bson.M{"$gt": startHeight, "$lte": endHeight},
+	}
+	num, err := s.Find(d.Name(), query).Count()
+
+	return int64(num), err
+}
+
+// count txs group by height which height in (startHeight, endHeight],
+// return map of height to num of txs
+func (d CommonTx) CountGroupByHeight(s store.Store, startHeight, endHeight int64) (map[int64]int64, error) {
+	return countGroupByHeight(s, d.Name(), Tx_Field_Height, startHeight, endHeight)
+}
+
+// count documents of collection group by height field which in (startHeight, endHeight]
+func countGroupByHeight(s store.Store, collection, heightField string, startHeight, endHeight int64) (map[int64]int64, error) {
+	type countRes struct {
+		Height int64 `bson:"_id"`
+		Num    int64 `bson:"num"`
+	}
+	var res []countRes
+
+	query := []bson.M{
+		{
+			"$match": bson.M{
+				heightField: bson.M{"$gt": startHeight, "$lte": endHeight},
+			},
+		},
+		{
+			"$group": bson.M{
+				"_id": "$" + heightField,
+				"num": bson.M{"$sum": 1},
+			},
+		},
+	}
+
+	if err := s.Aggregate(collection, query, &res); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int64]int64, len(res))
+	for _, v := range res {
+		counts[v.Height] = v.Num
+	}
+	return counts, nil
 }
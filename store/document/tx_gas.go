@@ -2,7 +2,6 @@ package document
 
 import (
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 
 	"github.com/irisnet/irishub-sync/logger"
@@ -23,16 +22,16 @@ type TxGas struct {
 }
 
 type GasUsed struct {
-	MinGasUsed float64 `bson:"min_gas_used"`
-	MaxGasUsed float64 `bson:"max_gas_used"`
-	AvgGasUsed float64 `bson:"avg_gas_used"`
+	MinGasUsed float64   `bson:"min_gas_used"`
+	MaxGasUsed float64   `bson:"max_gas_used"`
+	AvgGasUsed store.Dec `bson:"avg_gas_used"`
 }
 
 type GasPrice struct {
-	Denom       string  `bson:"denom"`
-	MinGasPrice float64 `bson:"min_gas_price"`
-	MaxGasPrice float64 `bson:"max_gas_price"`
-	AvgGasPrice float64 `bson:"avg_gas_price"`
+	Denom       string    `bson:"denom"`
+	MinGasPrice store.Dec `bson:"min_gas_price"`
+	MaxGasPrice store.Dec `bson:"max_gas_price"`
+	AvgGasPrice store.Dec `bson:"avg_gas_price"`
 }
 
 func (d TxGas) Name() string {
@@ -43,17 +42,14 @@ func (d TxGas) PkKvPair() map[string]interface{} {
 	return bson.M{TxGas_Field_TxType: d.TxType}
 }
 
-func (d TxGas) RemoveAll() error {
+func (d TxGas) RemoveAll(s store.Store) error {
 	query := bson.M{}
-	remove := func(c *mgo.Collection) error {
-		changeInfo, err := c.RemoveAll(query)
-		logger.Info("remove all tx gas data", logger.Any("changeInfo", changeInfo))
-		return err
-	}
-	return store.ExecCollection(d.Name(), remove)
+	removed, err := s.RemoveAll(d.Name(), query)
+	logger.Info("remove all tx gas data", logger.Int("removed", removed))
+	return err
 }
 
-func (d TxGas) SaveAll(txGases []TxGas) error {
+func (d TxGas) SaveAll(s store.Store, txGases []TxGas) error {
 	var docs []interface{}
 
 	if len(txGases) == 0 {
@@ -64,7 +60,7 @@ func (d TxGas) SaveAll(txGases []TxGas) error {
 		docs = append(docs, v)
 	}
 
-	err := store.SaveAll(d.Name(), docs)
+	err := s.Insert(d.Name(), docs...)
 
 	return err
 }
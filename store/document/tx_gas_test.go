@@ -2,6 +2,8 @@ package document
 
 import (
 	"testing"
+
+	"github.com/irisnet/irishub-sync/store"
 )
 
 func TestTxGas_RemoveAll(t *testing.T) {
@@ -15,7 +17,7 @@ func TestTxGas_RemoveAll(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := TxGas{}
-			if err := d.RemoveAll(); err != nil {
+			if err := d.RemoveAll(store.Backend()); err != nil {
 				t.Errorf("error = %v\n", err)
 			}
 		})
@@ -39,13 +41,13 @@ func TestTxGas_SaveAll(t *testing.T) {
 						GasUsed: GasUsed{
 							MinGasUsed: 1.0,
 							MaxGasUsed: 2.0,
-							AvgGasUsed: 1.2,
+							AvgGasUsed: dec("1.2"),
 						},
 						GasPrice: GasPrice{
 							Denom:       "iris",
-							MinGasPrice: 1.1,
-							MaxGasPrice: 1.2,
-							AvgGasPrice: 1.15,
+							MinGasPrice: dec("1.1"),
+							MaxGasPrice: dec("1.2"),
+							AvgGasPrice: dec("1.15"),
 						},
 					},
 					{
@@ -53,13 +55,13 @@ func TestTxGas_SaveAll(t *testing.T) {
 						GasUsed: GasUsed{
 							MinGasUsed: 1.0,
 							MaxGasUsed: 2.0,
-							AvgGasUsed: 1.2,
+							AvgGasUsed: dec("1.2"),
 						},
 						GasPrice: GasPrice{
 							Denom:       "iris",
-							MinGasPrice: 1.1,
-							MaxGasPrice: 1.2,
-							AvgGasPrice: 1.15,
+							MinGasPrice: dec("1.1"),
+							MaxGasPrice: dec("1.2"),
+							AvgGasPrice: dec("1.15"),
 						},
 					},
 				},
@@ -69,9 +71,17 @@ func TestTxGas_SaveAll(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := TxGas{}
-			if err := d.SaveAll(tt.args.txGases); err != nil {
+			if err := d.SaveAll(store.Backend(), tt.args.txGases); err != nil {
 				t.Errorf("TxGas.SaveAll() error = %v\n", err)
 			}
 		})
 	}
 }
+
+func dec(s string) store.Dec {
+	d, err := store.ParseDec(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
@@ -1,14 +1,20 @@
 package document
 
-import "gopkg.in/mgo.v2/bson"
+import (
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
+	"gopkg.in/mgo.v2/bson"
+)
 
 const (
 	CollectionNmTxMsg = "tx_msg"
 	TxMsg_Field_Hash  = "hash"
+	TxMsg_Field_Index = "index"
 )
 
 type TxMsg struct {
 	Hash    string `bson:"hash"`
+	Index   int    `bson:"index"` // index of msg in tx
 	Type    string `bson:"type"`
 	Content string `bson:"content"`
 }
@@ -18,5 +24,24 @@ func (m TxMsg) Name() string {
 }
 
 func (m TxMsg) PkKvPair() map[string]interface{} {
-	return bson.M{TxMsg_Field_Hash: m.Hash}
+	return bson.M{TxMsg_Field_Hash: m.Hash, TxMsg_Field_Index: m.Index}
+}
+
+func (m TxMsg) QueryByHashes(s store.Store, hashes []string) (results []TxMsg, err error) {
+	query := bson.M{
+		TxMsg_Field_Hash: bson.M{"$in": hashes},
+	}
+	return results, s.Find(m.Name(), query).All(&results)
+}
+
+func (m TxMsg) RemoveByHashes(hashes []string, batch *store.Batch) error {
+	query := bson.M{
+		TxMsg_Field_Hash: bson.M{"$in": hashes},
+	}
+	num, err := batch.DeleteAll(m.Name(), query)
+	if err != nil {
+		return err
+	}
+	logger.Info("remove tx msgs", logger.Int("num", num))
+	return nil
 }
@@ -2,6 +2,7 @@ package document
 
 import (
 	"encoding/json"
+	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/util/constant"
 	"testing"
 )
@@ -26,7 +27,7 @@ func TestCommonTx_CalculateTxGasAndGasPrice(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := CommonTx{}
-			res, err := d.CalculateTxGasAndGasPrice(tt.args.txType, tt.args.limit)
+			res, err := d.CalculateTxGasAndGasPrice(store.Backend(), tt.args.txType, tt.args.limit)
 			if err != nil {
 				t.Error(err)
 			} else {
@@ -36,3 +37,45 @@ func TestCommonTx_CalculateTxGasAndGasPrice(t *testing.T) {
 		})
 	}
 }
+
+func TestCommonTx_SplitMsgs(t *testing.T) {
+	tests := []struct {
+		name string
+		tx   CommonTx
+		want int
+	}{
+		{
+			name: "test split tx without msgs",
+			tx: CommonTx{
+				TxHash: "hash",
+				Type:   constant.TxTypeTransfer,
+			},
+			want: 1,
+		},
+		{
+			name: "test split tx with multiple msgs",
+			tx: CommonTx{
+				TxHash: "hash",
+				Type:   constant.TxTypeTransfer,
+				Msgs: []CommonMsg{
+					{Index: 0, Type: constant.TxTypeTransfer},
+					{Index: 1, Type: constant.TxTypeStakeDelegate},
+				},
+			},
+			want: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs := tt.tx.SplitMsgs()
+			if len(txs) != tt.want {
+				t.Fatalf("want %v txs, got %v", tt.want, len(txs))
+			}
+			for i, tx := range tt.tx.Msgs {
+				if txs[i].Type != tx.Type || txs[i].TxHash != tt.tx.TxHash {
+					t.Errorf("msg %v not split into tx", i)
+				}
+			}
+		})
+	}
+}
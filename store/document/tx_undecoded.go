@@ -0,0 +1,81 @@
+package document
+
+import (
+	"time"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	CollectionNmUndecodedTx = "tx_undecoded"
+
+	UndecodedTx_Field_Hash   = "tx_hash"
+	UndecodedTx_Field_Height = "height"
+)
+
+// tx which can't be decoded by current codec, e.g. it has msg of new module after chain upgrade.
+// raw bytes and result of tx are kept, so it can be decoded again after newer codec is deployed
+type UndecodedTx struct {
+	TxHash  string    `bson:"tx_hash"`
+	Height  int64     `bson:"height"`
+	Time    time.Time `bson:"time"`
+	Tx      []byte    `bson:"tx"`
+	Code    uint32    `bson:"code"`
+	Log     string    `bson:"log"`
+	GasUsed int64     `bson:"gas_used"`
+	Tags    []KvPair  `bson:"tags"`
+	Error   string    `bson:"error"`   // error of last decoding
+	Retries int       `bson:"retries"` // num of decoding retries which failed
+}
+
+func (d UndecodedTx) Name() string {
+	return CollectionNmUndecodedTx
+}
+
+func (d UndecodedTx) PkKvPair() map[string]interface{} {
+	return bson.M{UndecodedTx_Field_Hash: d.TxHash}
+}
+
+// get undecoded txs which height in (startHeight, endHeight], sorted by height.
+// endHeight 0 means no upper limit
+func (d UndecodedTx) QueryByHeightRange(s store.Store, startHeight, endHeight int64) ([]UndecodedTx, error) {
+	var txs []UndecodedTx
+
+	heightQuery := bson.M{"$gt": startHeight}
+	if endHeight > 0 {
+		heightQuery["$lte"] = endHeight
+	}
+	query := bson.M{UndecodedTx_Field_Height: heightQuery}
+	return txs, s.Find(d.Name(), query).Sort(UndecodedTx_Field_Height).All(&txs)
+}
+
+// count undecoded txs which height in (startHeight, endHeight]
+func (d UndecodedTx) CountByHeightRange(s store.Store, startHeight, endHeight int64) (int64, error) {
+	query := bson.M{
+		UndecodedTx_Field_Height: bson.M{"$gt": startHeight, "$lte": endHeight},
+	}
+	num, err := s.Find(d.Name(), query).Count()
+
+	return int64(num), err
+}
+
+// count undecoded txs group by height which height in (startHeight, endHeight],
+// return map of height to num of undecoded txs
+func (d UndecodedTx) CountGroupByHeight(s store.Store, startHeight, endHeight int64) (map[int64]int64, error) {
+	return countGroupByHeight(s, d.Name(), UndecodedTx_Field_Height, startHeight, endHeight)
+}
+
+func (d UndecodedTx) RemoveByHeightRange(startHeight, endHeight int64, batch *store.Batch) error {
+	query := bson.M{
+		UndecodedTx_Field_Height: bson.M{"$gt": startHeight, "$lte": endHeight},
+	}
+	num, err := batch.DeleteAll(d.Name(), query)
+	if err != nil {
+		return err
+	}
+	logger.Info("remove undecoded txs", logger.Int64("start_height", startHeight),
+		logger.Int64("end_height", endHeight), logger.Int("num", num))
+	return nil
+}
@@ -4,8 +4,8 @@ import (
 	"fmt"
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+	"sort"
 )
 
 const (
@@ -26,6 +26,7 @@ const (
 	Candidate_Field_Description     = "description"
 	Candidate_Field_BondHeight      = "bond_height"
 	Candidate_Field_Status          = "status"
+	Candidate_Field_Rank            = "rank"
 )
 
 type (
@@ -34,10 +35,10 @@ type (
 		PubKey          string         `bson:"pub_key"`
 		PubKeyAddr      string         `bson:"pub_key_addr"`
 		Jailed          bool           `bson:"jailed"` // has the validator been revoked from bonded status
-		Tokens          float64        `bson:"tokens"`
+		Tokens          store.Dec      `bson:"tokens"`
 		OriginalTokens  string         `bson:"original_tokens"`
-		DelegatorShares float64        `bson:"delegator_shares"`
-		VotingPower     float64        `bson:"voting_power"` // Voting power if pubKey is a considered a validator
+		DelegatorShares store.Dec      `bson:"delegator_shares"`
+		VotingPower     store.Dec      `bson:"voting_power"` // Voting power if pubKey is a considered a validator
 		Description     ValDescription `bson:"description"`  // Description terms for the candidate
 		BondHeight      int64          `bson:"bond_height"`
 		Status          string         `bson:"status"`
@@ -53,31 +54,25 @@ func (d Candidate) PkKvPair() map[string]interface{} {
 	return bson.M{Candidate_Field_Address: d.Address}
 }
 
-func (d Candidate) Query(query bson.M, sorts ...string) (
+func (d Candidate) Query(s store.Store, query bson.M, sorts ...string) (
 	results []Candidate, err error) {
-	exop := func(c *mgo.Collection) error {
-		return c.Find(query).Sort(sorts...).All(&results)
-	}
-	return results, store.ExecCollection(d.Name(), exop)
+	return results, s.Find(d.Name(), query).Sort(sorts...).All(&results)
 }
 
-func (d Candidate) Remove(query bson.M) error {
-	remove := func(c *mgo.Collection) error {
-		changeInfo, err := c.RemoveAll(query)
-		logger.Info("Remove candidates", logger.Any("changeInfo", changeInfo))
-		return err
-	}
-	return store.ExecCollection(d.Name(), remove)
+func (d Candidate) Remove(s store.Store, query bson.M) error {
+	removed, err := s.RemoveAll(d.Name(), query)
+	logger.Info("Remove candidates", logger.Int("removed", removed))
+	return err
 }
 
-func (d Candidate) GetValidator(address string) (candidate Candidate) {
+func (d Candidate) GetValidator(s store.Store, address string) (candidate Candidate) {
 	query := bson.M{
 		Candidate_Field_Address: address,
 	}
 
 	sorts := make([]string, 0)
 
-	candidates, err := d.Query(query, sorts...)
+	candidates, err := d.Query(s, query, sorts...)
 
 	if err != nil || len(candidates) != 0 {
 		logger.Error("candidate don't find", logger.String("address", address))
@@ -88,9 +83,9 @@ func (d Candidate) GetValidator(address string) (candidate Candidate) {
 	return candidate
 }
 
-func (d Candidate) QueryAll() (candidates []Candidate) {
+func (d Candidate) QueryAll(s store.Store) (candidates []Candidate) {
 	sort := fmt.Sprintf("-%s", Candidate_Field_Tokens)
-	candidates, err := d.Query(nil, sort)
+	candidates, err := d.Query(s, nil, sort)
 
 	if err != nil {
 		logger.Error("candidate collection is empty")
@@ -98,12 +93,12 @@ func (d Candidate) QueryAll() (candidates []Candidate) {
 	return candidates
 }
 
-func (d Candidate) RemoveCandidates() error {
+func (d Candidate) RemoveCandidates(s store.Store) error {
 	query := bson.M{}
-	return d.Remove(query)
+	return d.Remove(s, query)
 }
 
-func (d Candidate) SaveAll(candidates []Candidate) error {
+func (d Candidate) SaveAll(s store.Store, candidates []Candidate) error {
 	var docs []interface{}
 
 	if len(candidates) == 0 {
@@ -114,7 +109,26 @@ func (d Candidate) SaveAll(candidates []Candidate) error {
 		docs = append(docs, v)
 	}
 
-	err := store.SaveAll(d.Name(), docs)
+	err := s.Insert(d.Name(), docs...)
 
 	return err
 }
+
+// sort candidates by tokens in descending order and set their rank,
+// candidates which have same tokens have same rank
+func RankCandidates(candidates []Candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Tokens.Cmp(candidates[j].Tokens) > 0
+	})
+
+	var rank int
+	for index := range candidates {
+		rank = index + 1
+		if index >= 1 {
+			if candidates[index-1].Tokens.Equal(candidates[index].Tokens) {
+				rank = candidates[index-1].Rank
+			}
+		}
+		candidates[index].Rank = rank
+	}
+}
@@ -2,7 +2,6 @@ package document
 
 import (
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 	"time"
 )
@@ -22,15 +21,12 @@ func (v ValidatorHistory) PkKvPair() map[string]interface{} {
 	return bson.M{ValidatorUpTime_Field_ValAddress: v.Address}
 }
 
-func (v ValidatorHistory) RemoveAll() error {
-	remove := func(c *mgo.Collection) error {
-		_, err := c.RemoveAll(nil)
-		return err
-	}
-	return store.ExecCollection(v.Name(), remove)
+func (v ValidatorHistory) RemoveAll(s store.Store) error {
+	_, err := s.RemoveAll(v.Name(), nil)
+	return err
 }
 
-func (v ValidatorHistory) SaveAll(history []ValidatorHistory) error {
+func (v ValidatorHistory) SaveAll(s store.Store, history []ValidatorHistory) error {
 	var docs []interface{}
 
 	if len(history) == 0 {
@@ -41,15 +37,12 @@ func (v ValidatorHistory) SaveAll(history []ValidatorHistory) error {
 		docs = append(docs, v)
 	}
 
-	err := store.SaveAll(v.Name(), docs)
+	err := s.Insert(v.Name(), docs...)
 
 	return err
 }
 
-func (v ValidatorHistory) QueryAll() (vs []ValidatorHistory) {
-	queryOp := func(c *mgo.Collection) error {
-		return c.Find(nil).All(&vs)
-	}
-	store.ExecCollection(v.Name(), queryOp)
+func (v ValidatorHistory) QueryAll(s store.Store) (vs []ValidatorHistory) {
+	s.Find(v.Name(), nil).All(&vs)
 	return vs
 }
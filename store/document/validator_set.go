@@ -0,0 +1,75 @@
+package document
+
+import (
+	"github.com/irisnet/irishub-sync/store"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	CollectionNmValidatorSet = "validator_set"
+
+	ValidatorSet_Field_Hash = "hash"
+)
+
+// validator set is stored once for each validators hash of block header,
+// blocks refer to it by meta.header.validators_hash instead of storing validators
+type ValidatorSet struct {
+	Hash       string      `bson:"hash"`
+	Validators []Validator `bson:"validators"`
+}
+
+func (d ValidatorSet) Name() string {
+	return CollectionNmValidatorSet
+}
+
+func (d ValidatorSet) PkKvPair() map[string]interface{} {
+	return bson.M{ValidatorSet_Field_Hash: d.Hash}
+}
+
+// get validator sets by hashes, key of result is hash
+func (d ValidatorSet) QueryByHashes(s store.Store, hashes []string) (map[string]ValidatorSet, error) {
+	var sets []ValidatorSet
+
+	query := bson.M{ValidatorSet_Field_Hash: bson.M{"$in": hashes}}
+	if err := s.Find(d.Name(), query).All(&sets); err != nil {
+		return nil, err
+	}
+	res := make(map[string]ValidatorSet, len(sets))
+	for _, v := range sets {
+		res[v.Hash] = v
+	}
+	return res, nil
+}
+
+// build bitmap of precommits, bit i is set when validator i of set signed,
+// return false when any signer isn't in set, e.g. validator set changed at height of block
+func (d ValidatorSet) SignerBitmap(precommits []Vote) ([]byte, bool) {
+	if len(d.Validators) == 0 {
+		return nil, false
+	}
+	indexes := make(map[string]int, len(d.Validators))
+	for i, v := range d.Validators {
+		indexes[v.Address] = i
+	}
+
+	bitmap := make([]byte, (len(d.Validators)+7)/8)
+	for _, v := range precommits {
+		i, ok := indexes[v.ValidatorAddress]
+		if !ok {
+			return nil, false
+		}
+		bitmap[i/8] |= 1 << uint(i%8)
+	}
+	return bitmap, true
+}
+
+// get addresses of validators which are set in bitmap
+func (d ValidatorSet) Signers(bitmap []byte) []string {
+	var signers []string
+	for i, v := range d.Validators {
+		if i/8 < len(bitmap) && bitmap[i/8]&(1<<uint(i%8)) != 0 {
+			signers = append(signers, v.Address)
+		}
+	}
+	return signers
+}
@@ -3,7 +3,6 @@ package document
 import (
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
-	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -15,8 +14,8 @@ const (
 )
 
 type ValidatorUpTime struct {
-	ValAddress string  `bson:"val_address"`
-	UpTime     float64 `bson:"up_time"`
+	ValAddress string    `bson:"val_address"`
+	UpTime     store.Dec `bson:"up_time"`
 }
 
 func (d ValidatorUpTime) Name() string {
@@ -27,17 +26,14 @@ func (d ValidatorUpTime) PkKvPair() map[string]interface{} {
 	return bson.M{ValidatorUpTime_Field_ValAddress: d.ValAddress}
 }
 
-func (d ValidatorUpTime) RemoveAll() error {
+func (d ValidatorUpTime) RemoveAll(s store.Store) error {
 	query := bson.M{}
-	remove := func(c *mgo.Collection) error {
-		changeInfo, err := c.RemoveAll(query)
-		logger.Info("remove all validator uptime data", logger.Any("changeInfo", changeInfo))
-		return err
-	}
-	return store.ExecCollection(d.Name(), remove)
+	removed, err := s.RemoveAll(d.Name(), query)
+	logger.Info("remove all validator uptime data", logger.Int("removed", removed))
+	return err
 }
 
-func (d ValidatorUpTime) SaveAll(validatorUpTimes []ValidatorUpTime) error {
+func (d ValidatorUpTime) SaveAll(s store.Store, validatorUpTimes []ValidatorUpTime) error {
 	var docs []interface{}
 
 	if len(validatorUpTimes) == 0 {
@@ -48,7 +44,7 @@ func (d ValidatorUpTime) SaveAll(validatorUpTimes []ValidatorUpTime) error {
 		docs = append(docs, v)
 	}
 
-	err := store.SaveAll(d.Name(), docs)
+	err := s.Insert(d.Name(), docs...)
 
 	return err
 }
@@ -1,6 +1,7 @@
 package document
 
 import (
+	"github.com/irisnet/irishub-sync/store"
 	"testing"
 )
 
@@ -15,7 +16,7 @@ func TestValidatorUpTime_RemoveAll(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := ValidatorUpTime{}
-			err := d.RemoveAll()
+			err := d.RemoveAll(store.Backend())
 			if err != nil {
 				t.Error(err)
 			}
@@ -37,11 +38,11 @@ func TestValidatorUpTime_SaveAll(t *testing.T) {
 				validatorUpTimes: []ValidatorUpTime{
 					{
 						ValAddress: "1",
-						UpTime:     98.3,
+						UpTime:     dec("98.3"),
 					},
 					{
 						ValAddress: "2",
-						UpTime:     96.3,
+						UpTime:     dec("96.3"),
 					},
 				},
 			},
@@ -50,7 +51,7 @@ func TestValidatorUpTime_SaveAll(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := ValidatorUpTime{}
-			if err := d.SaveAll(tt.args.validatorUpTimes); err != nil {
+			if err := d.SaveAll(store.Backend(), tt.args.validatorUpTimes); err != nil {
 				t.Errorf("err is %v\n", err)
 			}
 		})
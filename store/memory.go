@@ -0,0 +1,533 @@
+// in-memory backend of store, documents are kept in process,
+// it's used to run sync pipeline in tests without mongodb
+
+package store
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+// MemStore keeps documents of every collection in memory as bson.M,
+// documents are copied when they are written or read, so callers never share them with store
+type MemStore struct {
+	mutex       sync.RWMutex
+	collections map[string][]bson.M
+	// unique indexes of collection, every index is list of fields
+	uniqueIndexes map[string][][]string
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		collections:   make(map[string][]bson.M),
+		uniqueIndexes: make(map[string][][]string),
+	}
+}
+
+// add unique index on fields of collection like unique index of mongodb,
+// write which violates it fails with duplicate key error
+func (s *MemStore) EnsureUniqueIndex(collection string, fields ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.uniqueIndexes[collection] = append(s.uniqueIndexes[collection], fields)
+}
+
+func (s *MemStore) Close() {
+	logger.Info("release resource :memory store")
+}
+
+func (s *MemStore) Save(h Docs) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.findIndex(h.Name(), h.PkKvPair()) >= 0 {
+		return errors.New("Record exists")
+	}
+	return s.insert(h.Name(), h)
+}
+
+func (s *MemStore) SaveOrUpdate(h Docs) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.findIndex(h.Name(), h.PkKvPair()) >= 0 {
+		return s.updateOne(h.Name(), h.PkKvPair(), h)
+	}
+	return s.insert(h.Name(), h)
+}
+
+func (s *MemStore) Update(h Docs) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.updateOne(h.Name(), h.PkKvPair(), h)
+}
+
+func (s *MemStore) Delete(h Docs) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	i := s.findIndex(h.Name(), h.PkKvPair())
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.removeAt(h.Name(), i)
+	return nil
+}
+
+func (s *MemStore) Insert(collection string, docs ...interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, v := range docs {
+		if err := s.insert(collection, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *MemStore) Find(collection string, selector interface{}) Query {
+	return &memQuery{store: s, collection: collection, selector: selector}
+}
+
+func (s *MemStore) UpdateOne(collection string, selector, update interface{}) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.updateOne(collection, selector, update)
+}
+
+func (s *MemStore) RemoveAll(collection string, selector interface{}) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	q, err := toDoc(selector)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		kept    []bson.M
+		removed int
+	)
+	for _, doc := range s.collections[collection] {
+		if matchDoc(doc, q) {
+			removed++
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	s.collections[collection] = kept
+	return removed, nil
+}
+
+func (s *MemStore) Aggregate(collection string, pipeline interface{}, result interface{}) error {
+	s.mutex.RLock()
+	docs := append([]bson.M(nil), s.collections[collection]...)
+	s.mutex.RUnlock()
+
+	res, err := runPipeline(docs, pipeline)
+	if err != nil {
+		return err
+	}
+	return decodeDocs(res, result)
+}
+
+// apply ops like mgo txn: when assertion of any op fails, none of ops is applied and txn.ErrAborted is returned.
+// insert of existed document, update and remove of missing document are ignored
+func (s *MemStore) Txn(ops []txn.Op) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// ops are applied on copy of collections, copy replaces collections only if all of them succeed
+	working := make(map[string][]bson.M)
+	docsOf := func(c string) []bson.M {
+		if docs, ok := working[c]; ok {
+			return docs
+		}
+		docs := append([]bson.M(nil), s.collections[c]...)
+		working[c] = docs
+		return docs
+	}
+	indexOf := func(c string, id interface{}) int {
+		for i, doc := range docsOf(c) {
+			if valuesEqual(doc["_id"], normalize(id)) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for _, op := range ops {
+		i := indexOf(op.C, op.Id)
+		switch op.Assert {
+		case nil:
+		case txn.DocExists:
+			if i < 0 {
+				return txn.ErrAborted
+			}
+		case txn.DocMissing:
+			if i >= 0 {
+				return txn.ErrAborted
+			}
+		default:
+			q, err := toDoc(op.Assert)
+			if err != nil {
+				return err
+			}
+			if i < 0 || !matchDoc(working[op.C][i], q) {
+				return txn.ErrAborted
+			}
+		}
+	}
+
+	for _, op := range ops {
+		i := indexOf(op.C, op.Id)
+		switch {
+		case op.Insert != nil:
+			if i >= 0 {
+				continue
+			}
+			doc, err := toDoc(op.Insert)
+			if err != nil {
+				return err
+			}
+			doc["_id"] = normalize(op.Id)
+			if err := s.checkUnique(op.C, working[op.C], doc, -1); err != nil {
+				return err
+			}
+			working[op.C] = append(working[op.C], doc)
+		case op.Update != nil:
+			if i < 0 {
+				continue
+			}
+			doc, err := applyUpdate(working[op.C][i], op.Update)
+			if err != nil {
+				return err
+			}
+			if err := s.checkUnique(op.C, working[op.C], doc, i); err != nil {
+				return err
+			}
+			working[op.C][i] = doc
+		case op.Remove:
+			if i < 0 {
+				continue
+			}
+			docs := working[op.C]
+			working[op.C] = append(docs[:i:i], docs[i+1:]...)
+		}
+	}
+
+	for c, docs := range working {
+		s.collections[c] = docs
+	}
+	return nil
+}
+
+func (s *MemStore) Bulk(collection string, ops []BulkOp) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, op := range ops {
+		i := s.findIndex(collection, op.Selector)
+		var err error
+		switch {
+		case op.Remove:
+			if i >= 0 {
+				s.removeAt(collection, i)
+			}
+		case i >= 0:
+			err = s.updateOne(collection, op.Selector, op.Update)
+		case op.Upsert:
+			var doc bson.M
+			if doc, err = upsertDoc(op.Selector, op.Update); err == nil {
+				err = s.insert(collection, doc)
+			}
+		}
+		if err != nil && !mgo.IsDup(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// collections of memory store exist once they are used
+func (s *MemStore) EnsureCollection(collection string) error {
+	return nil
+}
+
+// only unique index affects memory store, it's added by EnsureUniqueIndex
+func (s *MemStore) EnsureIndex(collection string, index Index) error {
+	if !index.Unique {
+		return nil
+	}
+	var fields []string
+	for _, k := range index.Key {
+		fields = append(fields, strings.TrimLeft(k, "+-"))
+	}
+
+	s.mutex.RLock()
+	for _, v := range s.uniqueIndexes[collection] {
+		if strings.Join(v, ",") == strings.Join(fields, ",") {
+			s.mutex.RUnlock()
+			return nil
+		}
+	}
+	s.mutex.RUnlock()
+	s.EnsureUniqueIndex(collection, fields...)
+	return nil
+}
+
+func (s *MemStore) DropIndex(collection string, index Index) error {
+	var fields []string
+	for _, k := range index.Key {
+		fields = append(fields, strings.TrimLeft(k, "+-"))
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	indexes := s.uniqueIndexes[collection]
+	for i, v := range indexes {
+		if strings.Join(v, ",") == strings.Join(fields, ",") {
+			s.uniqueIndexes[collection] = append(indexes[:i:i], indexes[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (s *MemStore) insert(collection string, v interface{}) error {
+	doc, err := toDoc(v)
+	if err != nil {
+		return err
+	}
+	if id, ok := doc["_id"]; !ok || id == nil {
+		doc["_id"] = bson.NewObjectId()
+	}
+	if err := s.checkUnique(collection, s.collections[collection], doc, -1); err != nil {
+		return err
+	}
+	s.collections[collection] = append(s.collections[collection], doc)
+	return nil
+}
+
+func (s *MemStore) updateOne(collection string, selector, update interface{}) error {
+	i := s.findIndex(collection, selector)
+	if i < 0 {
+		return ErrNotFound
+	}
+	docs := s.collections[collection]
+	doc, err := applyUpdate(docs[i], update)
+	if err != nil {
+		return err
+	}
+	if err := s.checkUnique(collection, docs, doc, i); err != nil {
+		return err
+	}
+	docs[i] = doc
+	return nil
+}
+
+func (s *MemStore) removeAt(collection string, i int) {
+	docs := s.collections[collection]
+	s.collections[collection] = append(docs[:i:i], docs[i+1:]...)
+}
+
+// get index of first document which matches selector, return -1 when there is no such document
+func (s *MemStore) findIndex(collection string, selector interface{}) int {
+	q, err := toDoc(selector)
+	if err != nil {
+		return -1
+	}
+	for i, doc := range s.collections[collection] {
+		if matchDoc(doc, q) {
+			return i
+		}
+	}
+	return -1
+}
+
+// check doc doesn't violate unique indexes of collection, document at skip is ignored
+func (s *MemStore) checkUnique(collection string, docs []bson.M, doc bson.M, skip int) error {
+	indexes := append([][]string{{"_id"}}, s.uniqueIndexes[collection]...)
+	for _, fields := range indexes {
+		for i, v := range docs {
+			if i == skip {
+				continue
+			}
+			dup := true
+			for _, f := range fields {
+				if !valuesEqual(lookupValue(v, f), lookupValue(doc, f)) {
+					dup = false
+					break
+				}
+			}
+			if dup {
+				return &mgo.LastError{
+					Code: 11000,
+					Err: fmt.Sprintf("E11000 duplicate key error collection: %v index: %v",
+						collection, strings.Join(fields, "_")),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// memQuery holds options of query, it's executed when One, All or Count is called
+type memQuery struct {
+	store      *MemStore
+	collection string
+	selector   interface{}
+	sort       []string
+	fields     interface{}
+	skip       int
+	limit      int
+}
+
+func (q *memQuery) Sort(fields ...string) Query {
+	q.sort = fields
+	return q
+}
+
+func (q *memQuery) Select(fields interface{}) Query {
+	q.fields = fields
+	return q
+}
+
+func (q *memQuery) Skip(n int) Query {
+	q.skip = n
+	return q
+}
+
+func (q *memQuery) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+func (q *memQuery) One(result interface{}) error {
+	q.limit = 1
+	docs, err := q.run()
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return ErrNotFound
+	}
+	return decodeDoc(docs[0], result)
+}
+
+func (q *memQuery) All(result interface{}) error {
+	docs, err := q.run()
+	if err != nil {
+		return err
+	}
+	return decodeDocs(docs, result)
+}
+
+func (q *memQuery) Count() (int, error) {
+	docs, err := q.run()
+	return len(docs), err
+}
+
+func (q *memQuery) run() ([]bson.M, error) {
+	selector, err := toDoc(q.selector)
+	if err != nil {
+		return nil, err
+	}
+
+	q.store.mutex.RLock()
+	var docs []bson.M
+	for _, doc := range q.store.collections[q.collection] {
+		if matchDoc(doc, selector) {
+			docs = append(docs, doc)
+		}
+	}
+	q.store.mutex.RUnlock()
+
+	if len(q.sort) > 0 {
+		var keys []bson.DocElem
+		for _, f := range q.sort {
+			if strings.HasPrefix(f, "-") {
+				keys = append(keys, bson.DocElem{Name: f[1:], Value: -1})
+			} else {
+				keys = append(keys, bson.DocElem{Name: strings.TrimPrefix(f, "+"), Value: 1})
+			}
+		}
+		sortDocs(docs, keys)
+	}
+
+	if q.skip > 0 {
+		if q.skip >= len(docs) {
+			docs = nil
+		} else {
+			docs = docs[q.skip:]
+		}
+	}
+	if q.limit > 0 && len(docs) > q.limit {
+		docs = docs[:q.limit]
+	}
+
+	if q.fields != nil {
+		fields, err := toDoc(q.fields)
+		if err != nil {
+			return nil, err
+		}
+		for i, doc := range docs {
+			docs[i] = projectDoc(doc, fields)
+		}
+	}
+	return docs, nil
+}
+
+// sort documents by keys, value of key is 1 or -1
+func sortDocs(docs []bson.M, keys []bson.DocElem) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, k := range keys {
+			c := compareValues(lookupValue(docs[i], k.Name), lookupValue(docs[j], k.Name))
+			if c == 0 {
+				continue
+			}
+			if n, ok := toFloat(k.Value); ok && n < 0 {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+// decode document into result, result should be pointer
+func decodeDoc(doc bson.M, result interface{}) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, result)
+}
+
+// decode documents into result, result should be pointer of slice
+func decodeDocs(docs []bson.M, result interface{}) error {
+	resultv := reflect.ValueOf(result)
+	if resultv.Kind() != reflect.Ptr || resultv.Elem().Kind() != reflect.Slice {
+		return errors.New("result argument must be a slice address")
+	}
+	slicev := resultv.Elem().Slice(0, 0)
+	elemt := slicev.Type().Elem()
+	for _, doc := range docs {
+		elemp := reflect.New(elemt)
+		if err := decodeDoc(doc, elemp.Interface()); err != nil {
+			return err
+		}
+		slicev = reflect.Append(slicev, elemp.Elem())
+	}
+	resultv.Elem().Set(slicev)
+	return nil
+}
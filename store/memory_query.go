@@ -0,0 +1,803 @@
+// evaluate selector, projection, update and aggregation pipeline of mongodb syntax on documents of memory store,
+// only operators used by documents are supported
+
+package store
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// convert v to bson.M by marshal and unmarshal it, so values in it are in same types with them
+// read from db: nested document is bson.M, array is []interface{} and integer is int or int64
+func toDoc(v interface{}) (bson.M, error) {
+	doc := bson.M{}
+	if v == nil {
+		return doc, nil
+	}
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// convert single value like toDoc
+func normalize(v interface{}) interface{} {
+	doc, err := toDoc(bson.M{"v": v})
+	if err != nil {
+		return v
+	}
+	return doc["v"]
+}
+
+// check whether doc matches query, query should be converted by toDoc
+func matchDoc(doc bson.M, query bson.M) bool {
+	for k, cond := range query {
+		switch k {
+		case "$or", "$and", "$nor":
+			subs, _ := cond.([]interface{})
+			matched := 0
+			for _, v := range subs {
+				if sub, ok := v.(bson.M); ok && matchDoc(doc, sub) {
+					matched++
+				}
+			}
+			switch {
+			case k == "$or" && matched == 0:
+				return false
+			case k == "$and" && matched < len(subs):
+				return false
+			case k == "$nor" && matched > 0:
+				return false
+			}
+		default:
+			if !matchField(lookupValues(doc, k), cond) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchField(values []interface{}, cond interface{}) bool {
+	ops, ok := cond.(bson.M)
+	if !ok || !isOperatorDoc(ops) {
+		return matchEq(values, cond)
+	}
+
+	for op, arg := range ops {
+		var matched bool
+		switch op {
+		case "$eq":
+			matched = matchEq(values, arg)
+		case "$ne":
+			matched = !matchEq(values, arg)
+		case "$in":
+			list, _ := arg.([]interface{})
+			for _, v := range list {
+				if matchEq(values, v) {
+					matched = true
+					break
+				}
+			}
+		case "$nin":
+			matched = true
+			list, _ := arg.([]interface{})
+			for _, v := range list {
+				if matchEq(values, v) {
+					matched = false
+					break
+				}
+			}
+		case "$gt", "$gte", "$lt", "$lte":
+			for _, v := range flatten(values) {
+				if typeOrder(v) != typeOrder(arg) {
+					continue
+				}
+				c := compareValues(v, arg)
+				if (op == "$gt" && c > 0) || (op == "$gte" && c >= 0) ||
+					(op == "$lt" && c < 0) || (op == "$lte" && c <= 0) {
+					matched = true
+					break
+				}
+			}
+		case "$exists":
+			matched = truthy(arg) == (len(values) > 0)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// missing field equals to nil, array field equals to value which is equal to any of it's elements
+func matchEq(values []interface{}, v interface{}) bool {
+	if len(values) == 0 {
+		return v == nil
+	}
+	for _, value := range values {
+		if valuesEqual(value, v) {
+			return true
+		}
+		if arr, ok := value.([]interface{}); ok {
+			for _, e := range arr {
+				if valuesEqual(e, v) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isOperatorDoc(doc bson.M) bool {
+	for k := range doc {
+		return strings.HasPrefix(k, "$")
+	}
+	return false
+}
+
+func flatten(values []interface{}) []interface{} {
+	var res []interface{}
+	for _, v := range values {
+		if arr, ok := v.([]interface{}); ok {
+			res = append(res, arr...)
+			continue
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+// get values of dotted path in doc, arrays in path are expanded
+func lookupValues(doc interface{}, path string) []interface{} {
+	return lookupParts(doc, strings.Split(path, "."))
+}
+
+func lookupParts(v interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		return []interface{}{v}
+	}
+	switch t := v.(type) {
+	case bson.M:
+		child, ok := t[parts[0]]
+		if !ok {
+			return nil
+		}
+		return lookupParts(child, parts[1:])
+	case []interface{}:
+		if i, err := strconv.Atoi(parts[0]); err == nil {
+			if i < len(t) {
+				return lookupParts(t[i], parts[1:])
+			}
+			return nil
+		}
+		var res []interface{}
+		for _, e := range t {
+			res = append(res, lookupParts(e, parts)...)
+		}
+		return res
+	}
+	return nil
+}
+
+// get first value of dotted path in doc, return nil when it's missing
+func lookupValue(doc interface{}, path string) interface{} {
+	values := lookupValues(doc, path)
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+func setPath(doc bson.M, path string, v interface{}) error {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		child, ok := doc[p]
+		if !ok || child == nil {
+			child = bson.M{}
+			doc[p] = child
+		}
+		sub, ok := child.(bson.M)
+		if !ok {
+			return fmt.Errorf("can't set field %v in non-document value", path)
+		}
+		doc = sub
+	}
+	doc[parts[len(parts)-1]] = v
+	return nil
+}
+
+func unsetPath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		sub, ok := doc[p].(bson.M)
+		if !ok {
+			return
+		}
+		doc = sub
+	}
+	delete(doc, parts[len(parts)-1])
+}
+
+// apply update to copy of doc, update is operators or replacement document
+func applyUpdate(doc bson.M, update interface{}) (bson.M, error) {
+	u, err := toDoc(update)
+	if err != nil {
+		return nil, err
+	}
+	if !isOperatorDoc(u) {
+		if id, ok := doc["_id"]; ok {
+			u["_id"] = id
+		}
+		return u, nil
+	}
+
+	out, err := toDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+	for op, arg := range u {
+		fields, ok := arg.(bson.M)
+		if !ok {
+			return nil, fmt.Errorf("value of %v should be document", op)
+		}
+		for k, v := range fields {
+			switch op {
+			case "$set":
+				err = setPath(out, k, v)
+			case "$setOnInsert":
+				// fields are set only when document is inserted by upsert, see upsertDoc
+			case "$unset":
+				unsetPath(out, k)
+			case "$inc":
+				sum, ok := addNumbers(lookupValue(out, k), v)
+				if !ok {
+					return nil, fmt.Errorf("can't apply $inc to non-numeric field %v", k)
+				}
+				err = setPath(out, k, sum)
+			case "$push":
+				arr, _ := lookupValue(out, k).([]interface{})
+				err = setPath(out, k, append(append([]interface{}(nil), arr...), v))
+			default:
+				return nil, fmt.Errorf("unsupported update operator %v", op)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// build document which is inserted by upsert,
+// it has equality fields of selector, and fields set by $set and $setOnInsert of update
+func upsertDoc(selector, update interface{}) (bson.M, error) {
+	q, err := toDoc(selector)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.M{}
+	for k, v := range q {
+		if strings.HasPrefix(k, "$") {
+			continue
+		}
+		if cond, ok := v.(bson.M); ok && isOperatorDoc(cond) {
+			continue
+		}
+		if err := setPath(doc, k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := toDoc(update)
+	if err != nil {
+		return nil, err
+	}
+	if doc, err = applyUpdate(doc, u); err != nil {
+		return nil, err
+	}
+	if fields, ok := u["$setOnInsert"]; ok {
+		return applyUpdate(doc, bson.M{"$set": fields})
+	}
+	return doc, nil
+}
+
+// apply projection to doc, fields include or exclude fields
+func projectDoc(doc bson.M, fields bson.M) bson.M {
+	if len(fields) == 0 {
+		return doc
+	}
+
+	include := false
+	for _, v := range fields {
+		if truthy(v) {
+			include = true
+			break
+		}
+	}
+
+	if !include {
+		out, _ := toDoc(doc)
+		for k := range fields {
+			unsetPath(out, k)
+		}
+		return out
+	}
+
+	out := bson.M{}
+	if v, ok := fields["_id"]; !ok || truthy(v) {
+		if id, ok := doc["_id"]; ok {
+			out["_id"] = id
+		}
+	}
+	for k, v := range fields {
+		if k != "_id" && truthy(v) {
+			copyPath(doc, out, strings.Split(k, "."))
+		}
+	}
+	return out
+}
+
+func copyPath(src, dst bson.M, parts []string) {
+	v, ok := src[parts[0]]
+	if !ok {
+		return
+	}
+	sub, isDoc := v.(bson.M)
+	if len(parts) == 1 || !isDoc {
+		dst[parts[0]] = v
+		return
+	}
+	d, ok := dst[parts[0]].(bson.M)
+	if !ok {
+		d = bson.M{}
+		dst[parts[0]] = d
+	}
+	copyPath(sub, d, parts[1:])
+}
+
+// run aggregation pipeline, supported stages are $match, $group, $unwind, $sort, $skip, $limit and $project
+func runPipeline(docs []bson.M, pipeline interface{}) ([]bson.M, error) {
+	stages := reflect.ValueOf(pipeline)
+	if stages.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("pipeline should be slice of stages")
+	}
+
+	for i := 0; i < stages.Len(); i++ {
+		elems := docElems(stages.Index(i).Interface())
+		if len(elems) != 1 {
+			return nil, fmt.Errorf("stage should have exactly one field")
+		}
+		stage := elems[0]
+
+		var res []bson.M
+		switch stage.Name {
+		case "$match":
+			q, err := toDoc(stage.Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, doc := range docs {
+				if matchDoc(doc, q) {
+					res = append(res, doc)
+				}
+			}
+		case "$unwind":
+			path, _ := stage.Value.(string)
+			path = strings.TrimPrefix(path, "$")
+			for _, doc := range docs {
+				arr, ok := lookupValue(doc, path).([]interface{})
+				if !ok {
+					continue
+				}
+				for _, e := range arr {
+					d, _ := toDoc(doc)
+					if err := setPath(d, path, e); err != nil {
+						return nil, err
+					}
+					res = append(res, d)
+				}
+			}
+		case "$group":
+			var err error
+			if res, err = groupDocs(docs, stage.Value); err != nil {
+				return nil, err
+			}
+		case "$sort":
+			res = append(res, docs...)
+			sortDocs(res, docElems(stage.Value))
+		case "$skip", "$limit":
+			n, _ := toFloat(stage.Value)
+			res = docs
+			if stage.Name == "$skip" {
+				if int(n) >= len(docs) {
+					res = nil
+				} else {
+					res = docs[int(n):]
+				}
+			} else if int(n) < len(docs) {
+				res = docs[:int(n)]
+			}
+		case "$project":
+			fields, err := toDoc(stage.Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, doc := range docs {
+				res = append(res, projectDoc(doc, fields))
+			}
+		default:
+			return nil, fmt.Errorf("unsupported pipeline stage %v", stage.Name)
+		}
+		docs = res
+	}
+	return docs, nil
+}
+
+type group struct {
+	id     interface{}
+	values map[string]interface{}
+	counts map[string]int
+}
+
+// group docs by _id expression of spec, supported accumulators are $sum, $max, $min, $avg, $first, $last and $push
+func groupDocs(docs []bson.M, spec interface{}) ([]bson.M, error) {
+	fields := docElems(spec)
+
+	var groups []*group
+	for _, doc := range docs {
+		var (
+			g  *group
+			id interface{}
+		)
+		for _, f := range fields {
+			if f.Name == "_id" {
+				id = evalExpr(doc, f.Value)
+			}
+		}
+		for _, v := range groups {
+			if valuesEqual(v.id, id) {
+				g = v
+				break
+			}
+		}
+		if g == nil {
+			g = &group{id: id, values: make(map[string]interface{}), counts: make(map[string]int)}
+			groups = append(groups, g)
+		}
+
+		for _, f := range fields {
+			if f.Name == "_id" {
+				continue
+			}
+			acc := docElems(f.Value)
+			if len(acc) != 1 {
+				return nil, fmt.Errorf("accumulator of %v should have exactly one field", f.Name)
+			}
+			v := evalExpr(doc, acc[0].Value)
+			cur, seen := g.values[f.Name]
+			switch acc[0].Name {
+			case "$sum", "$avg":
+				if _, ok := toFloat(v); !ok {
+					v = 0
+				}
+				if !seen {
+					cur = 0
+				}
+				g.values[f.Name], _ = addNumbers(cur, v)
+				g.counts[f.Name]++
+			case "$max", "$min":
+				if v == nil {
+					continue
+				}
+				c := compareValues(v, cur)
+				if !seen || cur == nil || (acc[0].Name == "$max" && c > 0) || (acc[0].Name == "$min" && c < 0) {
+					g.values[f.Name] = v
+				}
+			case "$first":
+				if !seen {
+					g.values[f.Name] = v
+				}
+			case "$last":
+				g.values[f.Name] = v
+			case "$push":
+				arr, _ := cur.([]interface{})
+				g.values[f.Name] = append(arr, v)
+			default:
+				return nil, fmt.Errorf("unsupported accumulator %v", acc[0].Name)
+			}
+		}
+	}
+
+	var res []bson.M
+	for _, g := range groups {
+		doc := bson.M{"_id": g.id}
+		for _, f := range fields {
+			if f.Name == "_id" {
+				continue
+			}
+			v := g.values[f.Name]
+			if acc := docElems(f.Value); len(acc) == 1 && acc[0].Name == "$avg" && g.counts[f.Name] > 0 {
+				sum, _ := toFloat(v)
+				v = sum / float64(g.counts[f.Name])
+			}
+			doc[f.Name] = v
+		}
+		res = append(res, doc)
+	}
+	return res, nil
+}
+
+// evaluate expression of pipeline, "$field" is value of field in doc
+func evalExpr(doc bson.M, expr interface{}) interface{} {
+	switch e := expr.(type) {
+	case string:
+		if strings.HasPrefix(e, "$") {
+			return lookupValue(doc, e[1:])
+		}
+		return e
+	case bson.M, bson.D:
+		res := bson.M{}
+		for _, v := range docElems(e) {
+			res[v.Name] = evalExpr(doc, v.Value)
+		}
+		return res
+	}
+	return normalize(expr)
+}
+
+// get fields of document in order, document is bson.M, bson.D or map
+func docElems(v interface{}) []bson.DocElem {
+	switch d := v.(type) {
+	case bson.D:
+		return d
+	case bson.M:
+		var elems []bson.DocElem
+		for k, v := range d {
+			elems = append(elems, bson.DocElem{Name: k, Value: v})
+		}
+		return elems
+	case map[string]interface{}:
+		return docElems(bson.M(d))
+	}
+	return nil
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if _, ok := toFloat(a); ok {
+		if _, ok := toFloat(b); ok {
+			return compareValues(a, b) == 0
+		}
+	}
+	switch x := a.(type) {
+	case bson.M:
+		y, ok := b.(bson.M)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !valuesEqual(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !valuesEqual(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case time.Time:
+		y, ok := b.(time.Time)
+		return ok && x.Equal(y)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// order of types when values of different types are compared, it's same with mongodb
+func typeOrder(v interface{}) int {
+	if _, ok := toFloat(v); ok {
+		return 2
+	}
+	switch v.(type) {
+	case nil:
+		return 1
+	case string:
+		return 3
+	case bson.M:
+		return 4
+	case []interface{}:
+		return 5
+	case []byte:
+		return 6
+	case bson.ObjectId:
+		return 7
+	case bool:
+		return 8
+	case time.Time:
+		return 9
+	}
+	return 10
+}
+
+// compare a and b, return -1, 0 or 1
+func compareValues(a, b interface{}) int {
+	if ta, tb := typeOrder(a), typeOrder(b); ta != tb {
+		return sign(int64(ta - tb))
+	}
+
+	if x, ok := toInt(a); ok {
+		if y, ok := toInt(b); ok {
+			return sign(x - y)
+		}
+	}
+	if isDecimal128(a) || isDecimal128(b) {
+		x, _ := toRat(a)
+		y, _ := toRat(b)
+		return x.Cmp(y)
+	}
+	if x, ok := toFloat(a); ok {
+		y, _ := toFloat(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+
+	switch x := a.(type) {
+	case string:
+		return strings.Compare(x, b.(string))
+	case bson.ObjectId:
+		return strings.Compare(string(x), string(b.(bson.ObjectId)))
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if y {
+			return -1
+		}
+		return 1
+	case time.Time:
+		y := b.(time.Time)
+		switch {
+		case x.Before(y):
+			return -1
+		case x.After(y):
+			return 1
+		}
+		return 0
+	case []interface{}:
+		return sign(int64(len(x) - len(b.([]interface{}))))
+	}
+	return 0
+}
+
+func sign(n int64) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func toInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	if n, ok := toInt(v); ok {
+		return float64(n), true
+	}
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case bson.Decimal128:
+		f, err := strconv.ParseFloat(n.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func isDecimal128(v interface{}) bool {
+	_, ok := v.(bson.Decimal128)
+	return ok
+}
+
+// convert number to big.Rat, so decimal128 is compared and summed exactly
+func toRat(v interface{}) (*big.Rat, bool) {
+	if n, ok := toInt(v); ok {
+		return new(big.Rat).SetInt64(n), true
+	}
+	switch n := v.(type) {
+	case bson.Decimal128:
+		return new(big.Rat).SetString(n.String())
+	case float64:
+		r := new(big.Rat)
+		if r.SetFloat64(n) == nil {
+			return r, false
+		}
+		return r, true
+	case float32:
+		return toRat(float64(n))
+	}
+	return new(big.Rat), false
+}
+
+// add numbers, result is int64 if both of them are integer, and it's decimal128 if any of them is decimal128
+func addNumbers(a, b interface{}) (interface{}, bool) {
+	if a == nil {
+		a = 0
+	}
+	if x, ok := toInt(a); ok {
+		if y, ok := toInt(b); ok {
+			return x + y, true
+		}
+	}
+	if isDecimal128(a) || isDecimal128(b) {
+		x, ok := toRat(a)
+		if !ok {
+			return nil, false
+		}
+		y, ok := toRat(b)
+		if !ok {
+			return nil, false
+		}
+		sum, err := ParseDec(new(big.Rat).Add(x, y).FloatString(DecPrecision))
+		if err != nil {
+			return nil, false
+		}
+		res, err := sum.GetBSON()
+		return res, err == nil
+	}
+	x, ok := toFloat(a)
+	if !ok {
+		return nil, false
+	}
+	y, ok := toFloat(b)
+	if !ok {
+		return nil, false
+	}
+	return x + y, true
+}
+
+func truthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	n, ok := toFloat(v)
+	return ok && n != 0
+}
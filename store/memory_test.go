@@ -0,0 +1,326 @@
+package store
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+type memTestTask struct {
+	ID           bson.ObjectId `bson:"_id"`
+	StartHeight  int64         `bson:"start_height"`
+	EndHeight    int64         `bson:"end_height"`
+	Status       string        `bson:"status"`
+	FencingToken int64         `bson:"fencing_token"`
+}
+
+func (d memTestTask) Name() string {
+	return "mem_test_task"
+}
+
+func (d memTestTask) PkKvPair() map[string]interface{} {
+	return bson.M{"start_height": d.StartHeight, "end_height": d.EndHeight}
+}
+
+func newMemTestStore(t *testing.T) *MemStore {
+	s := NewMemStore()
+	s.EnsureUniqueIndex(memTestTask{}.Name(), "start_height", "end_height")
+	tasks := []memTestTask{
+		{ID: bson.NewObjectId(), StartHeight: 1, EndHeight: 100, Status: "completed"},
+		{ID: bson.NewObjectId(), StartHeight: 101, EndHeight: 200, Status: "underway", FencingToken: 2},
+		{ID: bson.NewObjectId(), StartHeight: 201, EndHeight: 0, Status: "unhandled"},
+	}
+	for _, v := range tasks {
+		if err := s.Save(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestMemStore_Find(t *testing.T) {
+	s := newMemTestStore(t)
+	name := memTestTask{}.Name()
+
+	tests := []struct {
+		name     string
+		selector bson.M
+		sort     []string
+		skip     int
+		limit    int
+		want     []int64
+	}{
+		{
+			name:     "range",
+			selector: bson.M{"start_height": bson.M{"$gt": 1, "$lte": 201}},
+			sort:     []string{"start_height"},
+			want:     []int64{101, 201},
+		},
+		{
+			name:     "in and ne",
+			selector: bson.M{"status": bson.M{"$in": []string{"underway", "unhandled"}}, "end_height": bson.M{"$ne": 0}},
+			want:     []int64{101},
+		},
+		{
+			name: "or",
+			selector: bson.M{"$or": []bson.M{
+				{"status": "completed"},
+				{"end_height": bson.M{"$eq": 0}},
+			}},
+			sort: []string{"-start_height"},
+			want: []int64{201, 1},
+		},
+		{
+			name:     "missing field equals to nil",
+			selector: bson.M{"lease_expire_time": bson.M{"$in": []interface{}{0, nil}}, "fencing_token": 0},
+			sort:     []string{"start_height"},
+			want:     []int64{1, 201},
+		},
+		{
+			name:     "exists",
+			selector: bson.M{"lease_expire_time": bson.M{"$exists": true}},
+		},
+		{
+			name:  "skip and limit",
+			sort:  []string{"-start_height"},
+			skip:  1,
+			limit: 1,
+			want:  []int64{101},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tasks []memTestTask
+			if err := s.Find(name, tt.selector).Sort(tt.sort...).Skip(tt.skip).Limit(tt.limit).All(&tasks); err != nil {
+				t.Fatal(err)
+			}
+			if len(tasks) != len(tt.want) {
+				t.Fatalf("got %v tasks, want %v", len(tasks), len(tt.want))
+			}
+			for i, v := range tasks {
+				if v.StartHeight != tt.want[i] {
+					t.Errorf("task %v start height = %v, want %v", i, v.StartHeight, tt.want[i])
+				}
+			}
+		})
+	}
+
+	var task memTestTask
+	if err := s.Find(name, bson.M{"status": "invalid"}).One(&task); err != mgo.ErrNotFound {
+		t.Errorf("One() error = %v, want not found", err)
+	}
+	if n, err := s.Find(name, nil).Count(); err != nil || n != 3 {
+		t.Errorf("Count() = %v, %v, want 3", n, err)
+	}
+
+	var res bson.M
+	if err := s.Find(name, bson.M{"start_height": 1}).Select(bson.M{"status": 1}).One(&res); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := res["end_height"]; ok || res["status"] != "completed" || res["_id"] == nil {
+		t.Errorf("projection result = %v", res)
+	}
+}
+
+func TestMemStore_Write(t *testing.T) {
+	s := newMemTestStore(t)
+	name := memTestTask{}.Name()
+
+	var task memTestTask
+	if err := s.Find(name, bson.M{"start_height": 101}).One(&task); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Save(task); err == nil {
+		t.Error("save existed document should fail")
+	}
+	if err := s.Insert(name, memTestTask{ID: bson.NewObjectId(), StartHeight: 101, EndHeight: 200}); !mgo.IsDup(err) {
+		t.Errorf("insert document violating unique index, error = %v", err)
+	}
+
+	// selector with stale fencing token matches nothing
+	update := bson.M{"$set": bson.M{"status": "unhandled"}, "$inc": bson.M{"fencing_token": 1}}
+	if err := s.UpdateOne(name, bson.M{"_id": task.ID, "fencing_token": 1}, update); err != mgo.ErrNotFound {
+		t.Errorf("update with stale token, error = %v", err)
+	}
+	if err := s.UpdateOne(name, bson.M{"_id": task.ID, "fencing_token": 2}, update); err != nil {
+		t.Fatal(err)
+	}
+	var updated memTestTask
+	s.Find(name, bson.M{"_id": task.ID}).One(&updated)
+	if updated.Status != "unhandled" || updated.FencingToken != 3 || updated.EndHeight != 200 {
+		t.Errorf("updated task = %+v", updated)
+	}
+
+	// replacement keeps _id
+	task.Status = "completed"
+	if err := s.Update(task); err != nil {
+		t.Fatal(err)
+	}
+	s.Find(name, bson.M{"_id": task.ID}).One(&updated)
+	if updated.Status != "completed" || updated.FencingToken != 2 {
+		t.Errorf("replaced task = %+v", updated)
+	}
+
+	if err := s.Delete(task); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(task); err != mgo.ErrNotFound {
+		t.Errorf("delete missing document, error = %v", err)
+	}
+	if n, _ := s.RemoveAll(name, bson.M{"end_height": 0}); n != 1 {
+		t.Errorf("RemoveAll() = %v, want 1", n)
+	}
+}
+
+func TestMemStore_Txn(t *testing.T) {
+	s := newMemTestStore(t)
+	name := memTestTask{}.Name()
+
+	var task memTestTask
+	if err := s.Find(name, bson.M{"start_height": 101}).One(&task); err != nil {
+		t.Fatal(err)
+	}
+	newId := bson.NewObjectId()
+	ops := func(token int64) []txn.Op {
+		return []txn.Op{
+			{
+				C:      name,
+				Id:     newId,
+				Assert: txn.DocMissing,
+				Insert: memTestTask{ID: newId, StartHeight: 301, EndHeight: 400, Status: "unhandled"},
+			},
+			{
+				C:      name,
+				Id:     task.ID,
+				Assert: bson.M{"fencing_token": token},
+				Update: bson.M{"$set": bson.M{"status": "completed"}},
+			},
+		}
+	}
+
+	if err := s.Txn(ops(1)); err != txn.ErrAborted {
+		t.Fatalf("Txn() with failed assertion, error = %v", err)
+	}
+	if n, _ := s.Find(name, nil).Count(); n != 3 {
+		t.Fatalf("ops of aborted txn shouldn't be applied, got %v docs", n)
+	}
+
+	if err := s.Txn(ops(2)); err != nil {
+		t.Fatal(err)
+	}
+	var inserted memTestTask
+	if err := s.Find(name, bson.M{"_id": newId}).One(&inserted); err != nil || inserted.StartHeight != 301 {
+		t.Errorf("inserted task = %+v, %v", inserted, err)
+	}
+	if n, _ := s.Find(name, bson.M{"status": "completed"}).Count(); n != 2 {
+		t.Errorf("got %v completed tasks, want 2", n)
+	}
+
+	// documents are removed by batch in same way
+	batch := NewBatch(s)
+	if _, err := batch.DeleteAll(name, bson.M{"status": "completed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := s.Find(name, nil).Count(); n != 2 {
+		t.Errorf("got %v tasks after batch commit, want 2", n)
+	}
+}
+
+func TestMemStore_Bulk(t *testing.T) {
+	s := newMemTestStore(t)
+	name := memTestTask{}.Name()
+
+	ops := []BulkOp{
+		// existed document is kept by $setOnInsert
+		{Selector: bson.M{"start_height": 1, "end_height": 100}, Update: bson.M{"$setOnInsert": bson.M{"status": "unhandled"}}, Upsert: true},
+		{Selector: bson.M{"start_height": 101, "end_height": 200}, Update: bson.M{"$set": bson.M{"status": "completed"}}},
+		{Selector: bson.M{"start_height": 301, "end_height": 400}, Update: bson.M{"$set": bson.M{"status": "unhandled"}}, Upsert: true},
+		{Selector: bson.M{"start_height": 401, "end_height": 500}, Update: bson.M{"$set": bson.M{"status": "unhandled"}}},
+		{Selector: bson.M{"start_height": 201}, Remove: true},
+	}
+	// ops are idempotent, so they can be applied twice
+	for i := 0; i < 2; i++ {
+		if err := s.Bulk(name, ops); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tasks []memTestTask
+	if err := s.Find(name, nil).Sort("start_height").All(&tasks); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range tasks {
+		got = append(got, fmt.Sprintf("%v-%v:%v", v.StartHeight, v.EndHeight, v.Status))
+	}
+	want := []string{"1-100:completed", "101-200:completed", "301-400:unhandled"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tasks after bulk = %v, want %v", got, want)
+	}
+
+	// duplicate key error is ignored
+	dup := []BulkOp{{Selector: bson.M{"status": "missing"}, Update: bson.M{"$set": bson.M{"start_height": 1, "end_height": 100}}, Upsert: true}}
+	if err := s.Bulk(name, dup); err != nil {
+		t.Errorf("bulk with duplicate key error, error = %v", err)
+	}
+}
+
+func TestMemStore_Aggregate(t *testing.T) {
+	s := NewMemStore()
+	blocks := []interface{}{
+		bson.M{"height": 1, "num_txs": 2, "precommits": []bson.M{{"validator_address": "a"}, {"validator_address": "b"}}},
+		bson.M{"height": 2, "num_txs": 0, "precommits": []bson.M{{"validator_address": "a"}}},
+		bson.M{"height": 3, "num_txs": 5, "precommits": []bson.M{{"validator_address": "a"}, {"validator_address": "b"}}},
+	}
+	if err := s.Insert("mem_test_block", blocks...); err != nil {
+		t.Fatal(err)
+	}
+
+	var stat []struct {
+		Max       int64 `bson:"max"`
+		NumBlocks int64 `bson:"num_blocks"`
+		NumTxs    int64 `bson:"num_txs"`
+	}
+	pipeline := []bson.M{
+		{"$match": bson.M{"height": bson.M{"$gt": 1}}},
+		{"$group": bson.M{
+			"_id":        nil,
+			"max":        bson.M{"$max": "$height"},
+			"num_blocks": bson.M{"$sum": 1},
+			"num_txs":    bson.M{"$sum": "$num_txs"},
+		}},
+	}
+	if err := s.Aggregate("mem_test_block", pipeline, &stat); err != nil {
+		t.Fatal(err)
+	}
+	if len(stat) != 1 || stat[0].Max != 3 || stat[0].NumBlocks != 2 || stat[0].NumTxs != 5 {
+		t.Errorf("stat = %+v", stat)
+	}
+
+	var precommits []struct {
+		Address string `bson:"_id"`
+		Num     int64  `bson:"num"`
+	}
+	pipeline = []bson.M{
+		{"$unwind": "$precommits"},
+		{"$group": bson.M{
+			"_id": "$precommits.validator_address",
+			"num": bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"num": -1}},
+	}
+	if err := s.Aggregate("mem_test_block", pipeline, &precommits); err != nil {
+		t.Fatal(err)
+	}
+	if len(precommits) != 2 || precommits[0].Address != "a" || precommits[0].Num != 3 || precommits[1].Num != 2 {
+		t.Errorf("precommits = %+v", precommits)
+	}
+}
@@ -0,0 +1,122 @@
+// versioned migrations of db, they create collections and indexes, seed data and backfill documents.
+// applied versions are recorded in db, so every migration is applied once
+
+package migration
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
+	"gopkg.in/mgo.v2"
+)
+
+const (
+	CollectionNmMigration = "sync_migration"
+
+	Migration_Field_Version = "version"
+)
+
+// Migration changes schema or documents of db. it should be idempotent,
+// because it's applied again when process exits before it's version is recorded,
+// and processes which start at same time may apply it concurrently
+type Migration struct {
+	Version int
+	Name    string
+	Up      func() error
+}
+
+// record of applied migration
+type Record struct {
+	Version   int    `bson:"version"`
+	Name      string `bson:"name"`
+	AppliedAt int64  `bson:"applied_at"` // unix timestamp
+}
+
+var (
+	migrations []Migration
+)
+
+func register(m Migration) {
+	migrations = append(migrations, m)
+}
+
+// get registered migrations in order of version
+func Migrations() ([]Migration, error) {
+	res := append([]Migration(nil), migrations...)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Version < res[j].Version
+	})
+	for i, m := range res {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("version of migration %v should greater than 0", m.Name)
+		}
+		if i > 0 && res[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migration %v and %v have same version %v", res[i-1].Name, m.Name, m.Version)
+		}
+	}
+	return res, nil
+}
+
+// get applied migrations, key is version
+func Applied() (map[int]Record, error) {
+	var records []Record
+	if err := store.Find(CollectionNmMigration, nil).All(&records); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]Record, len(records))
+	for _, v := range records {
+		applied[v.Version] = v
+	}
+	return applied, nil
+}
+
+// apply migrations which haven't been applied in order of version,
+// migrations which version is greater than to are skipped, to <= 0 means no limit
+func Run(to int) error {
+	list, err := Migrations()
+	if err != nil {
+		return err
+	}
+	if err := prepare(); err != nil {
+		return err
+	}
+	applied, err := Applied()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range list {
+		if to > 0 && m.Version > to {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		logger.Info("apply migration", logger.Int("version", m.Version), logger.String("name", m.Name))
+		start := time.Now()
+		if err := m.Up(); err != nil {
+			return fmt.Errorf("apply migration %v %v fail: %v", m.Version, m.Name, err)
+		}
+
+		record := Record{Version: m.Version, Name: m.Name, AppliedAt: time.Now().Unix()}
+		// version is recorded by other process which applies it concurrently
+		if err := store.SaveAll(CollectionNmMigration, []interface{}{record}); err != nil && !mgo.IsDup(err) {
+			return fmt.Errorf("record migration %v fail: %v", m.Version, err)
+		}
+		logger.Info("migration applied", logger.Int("version", m.Version), logger.String("name", m.Name),
+			logger.Duration("cost", time.Since(start)))
+	}
+	return nil
+}
+
+// create collection of records before migrations are applied
+func prepare() error {
+	if err := store.EnsureCollection(CollectionNmMigration); err != nil {
+		return err
+	}
+	return store.EnsureIndex(CollectionNmMigration, store.Index{Key: []string{Migration_Field_Version}, Unique: true})
+}
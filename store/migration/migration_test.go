@@ -0,0 +1,183 @@
+package migration
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestRun(t *testing.T) {
+	s := store.NewMemStore()
+	store.Use(s)
+
+	candidates := []interface{}{
+		bson.M{"address": "a", "tokens": 10.0},
+		bson.M{"address": "b", "tokens": 30.0},
+		bson.M{"address": "c", "tokens": 10.0},
+	}
+	if err := s.Insert(document.CollectionNmStakeRoleCandidate, candidates...); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Run(2); err != nil {
+		t.Fatal(err)
+	}
+	applied, err := Applied()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 2 || applied[1].Name != "create collections" || applied[3].Version != 0 {
+		t.Fatalf("applied migrations = %v, want version 1 and 2", applied)
+	}
+	if err := s.Insert(document.CollectionNmBlock, bson.M{"height": 1}, bson.M{"height": 1}); err == nil {
+		t.Error("unique index of block height isn't created")
+	}
+
+	if err := Run(0); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := document.SyncConf{}.GetConf(store.Backend())
+	if err != nil || conf.BlockNumPerWorkerHandle != 50 {
+		t.Errorf("seeded sync conf = %+v, %v", conf, err)
+	}
+	ranks := map[string]int{"a": 2, "b": 1, "c": 2}
+	for _, v := range (document.Candidate{}).QueryAll(store.Backend()) {
+		if v.Rank != ranks[v.Address] {
+			t.Errorf("rank of %v = %v, want %v", v.Address, v.Rank, ranks[v.Address])
+		}
+	}
+
+	// applied migrations aren't applied again
+	if err := s.Insert(document.CollectionNameSyncConf, document.SyncConf{BlockNumPerWorkerHandle: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Run(0); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := store.Find(document.CollectionNameSyncConf, nil).Count(); n != 2 {
+		t.Errorf("got %v sync confs, want 2", n)
+	}
+	if n, _ := store.Find(CollectionNmMigration, nil).Count(); n != len(migrations) {
+		t.Errorf("got %v records, want %v", n, len(migrations))
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	defer func(list []Migration) {
+		migrations = list
+	}(migrations)
+
+	list, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i].Version <= list[i-1].Version {
+			t.Errorf("migrations aren't in order of version: %v", list)
+		}
+	}
+
+	register(Migration{Version: 1, Name: "duplicated"})
+	if _, err := Migrations(); err == nil {
+		t.Error("migrations which have same version should be rejected")
+	}
+}
+
+func TestConvertAmountsToDecimal(t *testing.T) {
+	s := store.NewMemStore()
+	store.Use(s)
+
+	txs := []interface{}{
+		bson.M{
+			"tx_hash":    "a",
+			"amount":     []interface{}{bson.M{"denom": "iris-atto", "amount": 1.5e+21}},
+			"fee":        bson.M{"amount": []interface{}{bson.M{"denom": "iris-atto", "amount": int64(4)}}, "gas": int64(2)},
+			"gas_price":  2.0,
+			"actual_fee": bson.M{"denom": "iris-atto", "amount": 0.25},
+			"msgs":       []interface{}{bson.M{"amount": []interface{}{bson.M{"amount": "0.1"}}}},
+		},
+		// txs stored by current version
+		document.CommonTx{TxHash: "b", GasPrice: store.NewDec(3)},
+	}
+	if err := s.Insert(document.CollectionNmCommonTx, txs...); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := convertAmountsToDecimal(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var raw bson.M
+	if err := store.Find(document.CollectionNmCommonTx, bson.M{"tx_hash": "a"}).One(&raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw["gas_price"].(bson.Decimal128); !ok {
+		t.Errorf("gas_price is stored as %T, want decimal128", raw["gas_price"])
+	}
+	var tx document.CommonTx
+	if err := store.Find(document.CollectionNmCommonTx, bson.M{"tx_hash": "a"}).One(&tx); err != nil {
+		t.Fatal(err)
+	}
+	got := []string{tx.Amount[0].Amount.String(), tx.Fee.Amount[0].Amount.String(), tx.ActualFee.Amount.String(), tx.Msgs[0].Amount[0].Amount.String()}
+	want := []string{"1500000000000000000000", "4", "0.25", "0.1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("converted amounts = %v, want %v", got, want)
+	}
+}
+
+func TestConvertAmountsToDecimal_pages(t *testing.T) {
+	s := store.NewMemStore()
+	store.Use(s)
+
+	var candidates []interface{}
+	for i := 0; i < convertBatchSize+1; i++ {
+		candidates = append(candidates, bson.M{"address": fmt.Sprintf("c%d", i), "tokens": float64(i)})
+	}
+	if err := s.Insert(document.CollectionNmStakeRoleCandidate, candidates...); err != nil {
+		t.Fatal(err)
+	}
+	if err := convertAmountsToDecimal(); err != nil {
+		t.Fatal(err)
+	}
+
+	var docs []bson.M
+	if err := store.Find(document.CollectionNmStakeRoleCandidate, nil).All(&docs); err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range docs {
+		if _, ok := doc["tokens"].(bson.Decimal128); !ok {
+			t.Fatalf("tokens of %v is stored as %T, want decimal128", doc["address"], doc["tokens"])
+		}
+	}
+}
+
+func TestDropTxMsgHashIndex(t *testing.T) {
+	s := store.NewMemStore()
+	store.Use(s)
+
+	// tx_msg of previous versions has unique index of hash
+	s.EnsureUniqueIndex(document.CollectionNmTxMsg, "hash")
+	if err := s.Insert(document.CollectionNmTxMsg, bson.M{"hash": "a", "index": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Insert(document.CollectionNmTxMsg, bson.M{"hash": "a", "index": 1}); err == nil {
+		t.Fatal("unique index of hash isn't created")
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := dropTxMsgHashIndex(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Insert(document.CollectionNmTxMsg, bson.M{"hash": "a", "index": 1}); err != nil {
+		t.Errorf("second msg of tx is rejected, err = %v", err)
+	}
+	if err := s.Insert(document.CollectionNmTxMsg, bson.M{"hash": "a", "index": 1}); err == nil {
+		t.Error("unique index of hash and index isn't created")
+	}
+}
@@ -0,0 +1,298 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// migrations are never changed or removed once they are released, add new migration with greater version instead
+func init() {
+	register(Migration{Version: 1, Name: "create collections", Up: createCollections})
+	register(Migration{Version: 2, Name: "create indexes", Up: createIndexes})
+	register(Migration{Version: 3, Name: "seed sync conf", Up: seedSyncConf})
+	register(Migration{Version: 4, Name: "backfill rank of candidates", Up: backfillCandidateRank})
+	register(Migration{Version: 5, Name: "convert amounts to decimal", Up: convertAmountsToDecimal})
+	register(Migration{Version: 6, Name: "create validator set", Up: createValidatorSet})
+	register(Migration{Version: 7, Name: "create undecoded tx", Up: createUndecodedTx})
+	register(Migration{Version: 8, Name: "index signers and account pubkey", Up: indexSigners})
+	register(Migration{Version: 9, Name: "drop unique hash index of tx msg", Up: dropTxMsgHashIndex})
+}
+
+// collections which are created by script/mongodb.js
+func createCollections() error {
+	collections := []string{
+		document.CollectionNmAccount,
+		document.CollectionNmBlock,
+		document.CollectionNmStakeRoleCandidate,
+		document.CollectionNmStakeRoleDelegator,
+		document.CollectionNameSyncTask,
+		document.CollectionNmCommonTx,
+		document.CollectionName,
+		document.CollectionNmTxGas,
+		document.CollectionNmProposal,
+		document.CollectionNmTxMsg,
+		"power_change",
+		"uptime_change",
+		document.CollectionNameSyncConf,
+		document.CollectionNmSyncConfHistory,
+		document.CollectionNmSyncGap,
+		document.CollectionNmSyncStatus,
+		document.CollectionNmValidatorHistory,
+	}
+	for _, c := range collections {
+		if err := store.EnsureCollection(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexes which are created by script/mongodb.js
+func createIndexes() error {
+	indexes := []struct {
+		collection string
+		index      store.Index
+	}{
+		{document.CollectionNmAccount, store.Index{Key: []string{"address"}, Unique: true}},
+		{document.CollectionNmBlock, store.Index{Key: []string{"-height"}, Unique: true}},
+
+		{document.CollectionNmStakeRoleCandidate, store.Index{Key: []string{"address"}, Unique: true}},
+		{document.CollectionNmStakeRoleCandidate, store.Index{Key: []string{"pub_key"}}},
+
+		{document.CollectionNmStakeRoleDelegator, store.Index{Key: []string{"validator_addr"}}},
+		{document.CollectionNmStakeRoleDelegator, store.Index{Key: []string{"address"}}},
+		{document.CollectionNmStakeRoleDelegator, store.Index{Key: []string{"address", "validator_addr"}, Unique: true}},
+
+		{document.CollectionNameSyncTask, store.Index{Key: []string{"start_height", "end_height"}, Unique: true}},
+		{document.CollectionNmSyncConfHistory, store.Index{Key: []string{"seq"}, Unique: true}},
+		{document.CollectionNmSyncGap, store.Index{Key: []string{"start_height", "end_height", "type"}, Unique: true}},
+
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"-height"}}},
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"-time"}}},
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"tx_hash"}, Unique: true}},
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"from"}}},
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"to"}}},
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"type"}}},
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"status"}}},
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"msgs.from"}}},
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"msgs.to"}}},
+		{document.CollectionNmCommonTx, store.Index{Key: []string{"msgs.type"}}},
+
+		{"power_change", store.Index{Key: []string{"height", "address"}, Unique: true}},
+		{"uptime_change", store.Index{Key: []string{"time", "address"}, Unique: true}},
+
+		{document.CollectionName, store.Index{Key: []string{"val_address"}, Unique: true}},
+
+		{document.CollectionNmTxGas, store.Index{Key: []string{"tx_type"}, Unique: true}},
+		{document.CollectionNmProposal, store.Index{Key: []string{"proposal_id"}, Unique: true}},
+		{document.CollectionNmTxMsg, store.Index{Key: []string{"hash", "index"}, Unique: true}},
+	}
+	for _, v := range indexes {
+		if err := store.EnsureIndex(v.collection, v.index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insert default sync conf when there is no sync conf
+func seedSyncConf() error {
+	_, err := document.SyncConf{}.GetConf(store.Backend())
+	if err == nil {
+		return nil
+	}
+	if err != store.ErrNotFound {
+		return err
+	}
+
+	conf := document.SyncConf{
+		BlockNumPerWorkerHandle: 50,
+		MaxWorkerSleepTime:      120,
+	}
+	return store.SaveAll(conf.Name(), []interface{}{conf})
+}
+
+// rank is added to candidate after candidates are stored, set rank of stored candidates
+func backfillCandidateRank() error {
+	var model document.Candidate
+
+	n, err := store.Find(model.Name(), bson.M{document.Candidate_Field_Rank: bson.M{"$exists": false}}).Count()
+	if err != nil || n == 0 {
+		return err
+	}
+
+	var candidates []document.Candidate
+	if err := store.Find(model.Name(), nil).All(&candidates); err != nil {
+		return err
+	}
+	document.RankCandidates(candidates)
+	for _, v := range candidates {
+		update := bson.M{"$set": bson.M{document.Candidate_Field_Rank: v.Rank}}
+		if err := store.UpdateOne(model.Name(), v.PkKvPair(), update); err != nil {
+			return err
+		}
+	}
+	logger.Info("backfill rank of candidates", logger.Int("num", len(candidates)))
+	return nil
+}
+
+// fields of amounts which were stored as double, path of field in array is same with path of query
+var decimalFields = map[string][]string{
+	document.CollectionNmCommonTx: {
+		"amount.amount", "fee.amount.amount", "gas_price", "actual_fee.amount", "msgs.amount.amount",
+	},
+	document.CollectionNmAccount:            {"amount.amount"},
+	document.CollectionNmStakeRoleCandidate: {"tokens", "delegator_shares", "voting_power"},
+	document.CollectionNmStakeRoleDelegator: {"shares", "unbonding_delegation.initial_balance.amount", "unbonding_delegation.balance.amount"},
+	document.CollectionNmProposal:           {"total_deposit.amount"},
+	document.CollectionNmTxGas:              {"gas_used.avg_gas_used", "gas_price.min_gas_price", "gas_price.max_gas_price", "gas_price.avg_gas_price"},
+	document.CollectionName:                 {"up_time"},
+}
+
+const convertBatchSize = 1000
+
+// amounts were stored as double which loses precision, convert them to decimal128.
+// documents which amounts are decimal128 already are skipped.
+// documents are read in pages of _id greater than last _id of previous page, so large collections aren't scanned again for skip
+func convertAmountsToDecimal() error {
+	for collection, fields := range decimalFields {
+		var (
+			converted int
+			lastId    interface{}
+		)
+		for {
+			var (
+				docs     []bson.M
+				selector bson.M
+			)
+			if lastId != nil {
+				selector = bson.M{"_id": bson.M{"$gt": lastId}}
+			}
+			if err := store.Find(collection, selector).Sort("_id").Limit(convertBatchSize).All(&docs); err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				set := bson.M{}
+				for _, field := range fields {
+					path := strings.Split(field, ".")
+					v, changed, err := toDecimal(doc[path[0]], path[1:])
+					if err != nil {
+						return fmt.Errorf("convert %v of %v in %v: %v", field, doc["_id"], collection, err)
+					}
+					if changed {
+						doc[path[0]] = v
+						set[path[0]] = v
+					}
+				}
+				if len(set) == 0 {
+					continue
+				}
+				if err := store.UpdateOne(collection, bson.M{"_id": doc["_id"]}, bson.M{"$set": set}); err != nil {
+					return err
+				}
+				converted++
+			}
+			if len(docs) < convertBatchSize {
+				break
+			}
+			lastId = docs[len(docs)-1]["_id"]
+		}
+		logger.Info("convert amounts to decimal", logger.String("collection", collection), logger.Int("num", converted))
+	}
+	return nil
+}
+
+// convert number at path of v to decimal128, arrays are traversed like path of query
+func toDecimal(v interface{}, path []string) (interface{}, bool, error) {
+	switch val := v.(type) {
+	case []interface{}:
+		changed := false
+		for i, elem := range val {
+			res, ok, err := toDecimal(elem, path)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				val[i], changed = res, true
+			}
+		}
+		return val, changed, nil
+	case bson.M:
+		if len(path) == 0 {
+			return v, false, nil
+		}
+		res, ok, err := toDecimal(val[path[0]], path[1:])
+		if err != nil || !ok {
+			return v, false, err
+		}
+		val[path[0]] = res
+		return val, true, nil
+	}
+	if len(path) > 0 {
+		return v, false, nil
+	}
+
+	var (
+		d   store.Dec
+		err error
+	)
+	switch val := v.(type) {
+	case float64:
+		d, err = store.DecFromFloat64(val)
+	case int:
+		d = store.NewDec(int64(val))
+	case int64:
+		d = store.NewDec(val)
+	case string:
+		d, err = store.ParseDec(val)
+	default:
+		// decimal128 already, or field doesn't exist
+		return v, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	res, err := d.GetBSON()
+	return res, err == nil, err
+}
+
+// validator sets are stored once for each validators hash instead of in every block
+func createValidatorSet() error {
+	if err := store.EnsureCollection(document.CollectionNmValidatorSet); err != nil {
+		return err
+	}
+	return store.EnsureIndex(document.CollectionNmValidatorSet, store.Index{Key: []string{"hash"}, Unique: true})
+}
+
+// txs which can't be decoded are kept until they are decoded by retry-tx command
+func createUndecodedTx() error {
+	if err := store.EnsureCollection(document.CollectionNmUndecodedTx); err != nil {
+		return err
+	}
+	if err := store.EnsureIndex(document.CollectionNmUndecodedTx, store.Index{Key: []string{"tx_hash"}, Unique: true}); err != nil {
+		return err
+	}
+	return store.EnsureIndex(document.CollectionNmUndecodedTx, store.Index{Key: []string{"height"}})
+}
+
+// txs are queried by signer, and accounts are queried by height which pubkey is first seen during rollback
+func indexSigners() error {
+	if err := store.EnsureIndex(document.CollectionNmCommonTx, store.Index{Key: []string{"signers.address"}}); err != nil {
+		return err
+	}
+	return store.EnsureIndex(document.CollectionNmAccount, store.Index{Key: []string{"pub_key_height"}})
+}
+
+// every msg of tx is stored in tx_msg, unique index of hash which is created by previous versions
+// rejects second msg of tx, it's replaced by unique index of hash and index
+func dropTxMsgHashIndex() error {
+	if err := store.DropIndex(document.CollectionNmTxMsg, store.Index{Key: []string{"hash"}}); err != nil {
+		return err
+	}
+	return store.EnsureIndex(document.CollectionNmTxMsg, store.Index{Key: []string{"hash", "index"}, Unique: true})
+}
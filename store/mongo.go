@@ -0,0 +1,303 @@
+// mongodb backend of store
+
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	conf "github.com/irisnet/irishub-sync/conf/db"
+	"github.com/irisnet/irishub-sync/logger"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+var (
+	session *mgo.Session
+)
+
+type mongoStore struct{}
+
+func newMongoStore() mongoStore {
+	addrs := strings.Split(conf.Addrs, ",")
+	dialInfo := &mgo.DialInfo{
+		Addrs:     addrs,
+		Database:  conf.Database,
+		Username:  conf.User,
+		Password:  conf.Passwd,
+		Direct:    true,
+		Timeout:   time.Second * 10,
+		PoolLimit: 4096, // Session.SetPoolLimit
+	}
+
+	var err error
+	session, err = mgo.DialWithInfo(dialInfo)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+	session.SetMode(mgo.Monotonic, true)
+
+	return mongoStore{}
+}
+
+func (s mongoStore) Close() {
+	logger.Info("release resource :mongoDb")
+	session.Close()
+}
+
+func getSession() *mgo.Session {
+	// max session num is 4096
+	return session.Clone()
+}
+
+// get collection object
+func execCollection(collection string, s func(*mgo.Collection) error) error {
+	session := getSession()
+	defer session.Close()
+	c := session.DB(conf.Database).C(collection)
+	return s(c)
+}
+
+func (s mongoStore) Save(h Docs) error {
+	save := func(c *mgo.Collection) error {
+		pk := h.PkKvPair()
+		n, _ := c.Find(pk).Count()
+		if n >= 1 {
+			errMsg := fmt.Sprintf("Record exists")
+			return errors.New(errMsg)
+		}
+		logger.Debug("Save document", logger.String("table", h.Name()), logger.Any("content", h))
+		return c.Insert(h)
+	}
+	return execCollection(h.Name(), save)
+}
+
+func (s mongoStore) SaveOrUpdate(h Docs) error {
+	save := func(c *mgo.Collection) error {
+		n, err := c.Find(h.PkKvPair()).Count()
+		if err != nil {
+			logger.Error("Store Find error", logger.String("err", err.Error()))
+		}
+
+		if n >= 1 {
+			return s.Update(h)
+		}
+		logger.Debug("Save document", logger.String("table", h.Name()), logger.Any("content", h))
+		return c.Insert(h)
+	}
+
+	return execCollection(h.Name(), save)
+}
+
+func (s mongoStore) Update(h Docs) error {
+	update := func(c *mgo.Collection) error {
+		key := h.PkKvPair()
+		logger.Debug("update document", logger.String("table", h.Name()), logger.Any("conditions", h.PkKvPair()))
+		return c.Update(key, h)
+	}
+	return execCollection(h.Name(), update)
+}
+
+func (s mongoStore) Delete(h Docs) error {
+	remove := func(c *mgo.Collection) error {
+		key := h.PkKvPair()
+		logger.Debug("delete document", logger.String("table", h.Name()), logger.Any("conditions", h.PkKvPair()))
+		return c.Remove(key)
+	}
+	return execCollection(h.Name(), remove)
+}
+
+func (s mongoStore) Insert(collection string, docs ...interface{}) error {
+	insert := func(c *mgo.Collection) error {
+		return c.Insert(docs...)
+	}
+	return execCollection(collection, insert)
+}
+
+func (s mongoStore) Find(collection string, selector interface{}) Query {
+	return &mongoQuery{collection: collection, selector: selector}
+}
+
+func (s mongoStore) UpdateOne(collection string, selector, update interface{}) error {
+	fn := func(c *mgo.Collection) error {
+		return c.Update(selector, update)
+	}
+	return execCollection(collection, fn)
+}
+
+func (s mongoStore) RemoveAll(collection string, selector interface{}) (int, error) {
+	var info *mgo.ChangeInfo
+	fn := func(c *mgo.Collection) error {
+		var err error
+		info, err = c.RemoveAll(selector)
+		return err
+	}
+	if err := execCollection(collection, fn); err != nil {
+		return 0, err
+	}
+	return info.Removed, nil
+}
+
+func (s mongoStore) Aggregate(collection string, pipeline interface{}, result interface{}) error {
+	fn := func(c *mgo.Collection) error {
+		return c.Pipe(pipeline).All(result)
+	}
+	return execCollection(collection, fn)
+}
+
+// mgo transaction method
+// detail to see: https://godoc.org/gopkg.in/mgo.v2/txn
+func (s mongoStore) Txn(ops []txn.Op) error {
+	session := getSession()
+	defer session.Close()
+
+	c := session.DB(conf.Database).C(CollectionNameTxn)
+	runner := txn.NewRunner(c)
+
+	txObjectId := bson.NewObjectId()
+	err := runner.Run(ops, txObjectId, nil)
+	if err != nil {
+		if err == txn.ErrAborted {
+			err = runner.Resume(txObjectId)
+			if err != nil {
+				return err
+			}
+		} else {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run ops with unordered bulk of mgo, mgo splits ops into batches which are accepted by mongodb
+func (s mongoStore) Bulk(collection string, ops []BulkOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	fn := func(c *mgo.Collection) error {
+		bulk := c.Bulk()
+		bulk.Unordered()
+		for _, op := range ops {
+			switch {
+			case op.Remove:
+				bulk.Remove(op.Selector)
+			case op.Upsert:
+				bulk.Upsert(op.Selector, op.Update)
+			default:
+				bulk.Update(op.Selector, op.Update)
+			}
+		}
+		_, err := bulk.Run()
+		if err != nil && mgo.IsDup(err) {
+			logger.Debug("ignore duplicate key error of bulk", logger.String("collection", collection), logger.String("err", err.Error()))
+			return nil
+		}
+		return err
+	}
+	return execCollection(collection, fn)
+}
+
+func (s mongoStore) EnsureCollection(collection string) error {
+	fn := func(c *mgo.Collection) error {
+		names, err := c.Database.CollectionNames()
+		if err != nil {
+			return err
+		}
+		for _, v := range names {
+			if v == collection {
+				return nil
+			}
+		}
+		return c.Create(&mgo.CollectionInfo{})
+	}
+	return execCollection(collection, fn)
+}
+
+func (s mongoStore) EnsureIndex(collection string, index Index) error {
+	fn := func(c *mgo.Collection) error {
+		return c.EnsureIndex(mgo.Index{Key: index.Key, Unique: index.Unique})
+	}
+	return execCollection(collection, fn)
+}
+
+func (s mongoStore) DropIndex(collection string, index Index) error {
+	fn := func(c *mgo.Collection) error {
+		indexes, err := c.Indexes()
+		if err != nil {
+			return err
+		}
+		for _, v := range indexes {
+			if strings.Join(v.Key, ",") == strings.Join(index.Key, ",") {
+				return c.DropIndexName(v.Name)
+			}
+		}
+		return nil
+	}
+	return execCollection(collection, fn)
+}
+
+// mongoQuery holds options of query, it's executed when One, All or Count is called
+type mongoQuery struct {
+	collection string
+	selector   interface{}
+	sort       []string
+	fields     interface{}
+	skip       int
+	limit      int
+}
+
+func (q *mongoQuery) Sort(fields ...string) Query {
+	q.sort = fields
+	return q
+}
+
+func (q *mongoQuery) Select(fields interface{}) Query {
+	q.fields = fields
+	return q
+}
+
+func (q *mongoQuery) Skip(n int) Query {
+	q.skip = n
+	return q
+}
+
+func (q *mongoQuery) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+func (q *mongoQuery) One(result interface{}) error {
+	return execCollection(q.collection, func(c *mgo.Collection) error {
+		return q.build(c).One(result)
+	})
+}
+
+func (q *mongoQuery) All(result interface{}) error {
+	return execCollection(q.collection, func(c *mgo.Collection) error {
+		return q.build(c).All(result)
+	})
+}
+
+func (q *mongoQuery) Count() (n int, err error) {
+	err = execCollection(q.collection, func(c *mgo.Collection) error {
+		n, err = q.build(c).Count()
+		return err
+	})
+	return n, err
+}
+
+func (q *mongoQuery) build(c *mgo.Collection) *mgo.Query {
+	query := c.Find(q.selector)
+	if len(q.sort) > 0 {
+		query = query.Sort(q.sort...)
+	}
+	if q.fields != nil {
+		query = query.Select(q.fields)
+	}
+	return query.Skip(q.skip).Limit(q.limit)
+}
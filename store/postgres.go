@@ -0,0 +1,541 @@
+// postgres backend of store
+
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	conf "github.com/irisnet/irishub-sync/conf/db"
+	"github.com/irisnet/irishub-sync/logger"
+
+	"github.com/lib/pq"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+	"gopkg.in/mgo.v2/txn"
+)
+
+const (
+	pgCodeUniqueViolation = "23505"
+)
+
+// every collection is stored in table which has same name with it, table has columns:
+//
+//	id:   _id of document
+//	doc:  document encoded in bson, documents are decoded from it
+//	data: document encoded in json, selector and pipeline are translated into sql on it
+//
+// tables are created by script/postgres.sql, relational columns generated from data are defined there.
+// updates and txn assertions are evaluated in process on locked rows, so they have same semantics with memory store
+type postgresStore struct {
+	db *sql.DB
+}
+
+func newPostgresStore() *postgresStore {
+	db, err := sql.Open("postgres", conf.PgUrl)
+	if err != nil {
+		logger.Error(err.Error())
+	}
+	if err := db.Ping(); err != nil {
+		logger.Error("ping postgres fail", logger.String("err", err.Error()))
+	}
+	return &postgresStore{db: db}
+}
+
+func (s *postgresStore) Close() {
+	logger.Info("release resource :postgres")
+	s.db.Close()
+}
+
+// sqlExecutor is implemented by both *sql.DB and *sql.Tx
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+type pgRow struct {
+	id  string
+	doc bson.M
+}
+
+func (s *postgresStore) Save(h Docs) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		rows, err := findRows(tx, h.Name(), h.PkKvPair(), 1)
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			return errors.New("Record exists")
+		}
+		logger.Debug("Save document", logger.String("table", h.Name()), logger.Any("content", h))
+		return insertRow(tx, h.Name(), h)
+	})
+}
+
+func (s *postgresStore) SaveOrUpdate(h Docs) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		rows, err := findRows(tx, h.Name(), h.PkKvPair(), 1)
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			return updateRow(tx, h.Name(), rows[0], h)
+		}
+		logger.Debug("Save document", logger.String("table", h.Name()), logger.Any("content", h))
+		return insertRow(tx, h.Name(), h)
+	})
+}
+
+func (s *postgresStore) Update(h Docs) error {
+	logger.Debug("update document", logger.String("table", h.Name()), logger.Any("conditions", h.PkKvPair()))
+	return s.UpdateOne(h.Name(), h.PkKvPair(), h)
+}
+
+func (s *postgresStore) Delete(h Docs) error {
+	logger.Debug("delete document", logger.String("table", h.Name()), logger.Any("conditions", h.PkKvPair()))
+	return s.withTx(func(tx *sql.Tx) error {
+		rows, err := findRows(tx, h.Name(), h.PkKvPair(), 1)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return ErrNotFound
+		}
+		return deleteRow(tx, h.Name(), rows[0].id)
+	})
+}
+
+func (s *postgresStore) Insert(collection string, docs ...interface{}) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		for _, v := range docs {
+			if err := insertRow(tx, collection, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *postgresStore) Find(collection string, selector interface{}) Query {
+	return &pgQuery{store: s, collection: collection, selector: selector}
+}
+
+func (s *postgresStore) UpdateOne(collection string, selector, update interface{}) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		rows, err := findRows(tx, collection, selector, 1)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return ErrNotFound
+		}
+		return updateRow(tx, collection, rows[0], update)
+	})
+}
+
+func (s *postgresStore) RemoveAll(collection string, selector interface{}) (int, error) {
+	q, err := toDoc(selector)
+	if err != nil {
+		return 0, err
+	}
+	b := &sqlBuilder{}
+	where, err := b.where(q, b.dataField)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.Exec(fmt.Sprintf("DELETE FROM %v t WHERE %v", quoteIdent(collection), where), b.args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *postgresStore) Aggregate(collection string, pipeline interface{}, result interface{}) error {
+	b := &sqlBuilder{}
+	query, err := b.pipeline(collection, pipeline)
+	if err != nil {
+		return err
+	}
+
+	rows, err := s.db.Query(query, b.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var docs []bson.M
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return err
+		}
+		doc, err := fromJSON(data)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return decodeDocs(docs, result)
+}
+
+// apply ops in one sql transaction, rows of ops are locked before assertions are checked.
+// when assertion of any op fails, transaction is rolled back and txn.ErrAborted is returned.
+// insert of existed document, update and remove of missing document are ignored like mgo txn
+func (s *postgresStore) Txn(ops []txn.Op) error {
+	return s.withTx(func(tx *sql.Tx) error {
+		for _, op := range ops {
+			row, found, err := findRowById(tx, op.C, op.Id)
+			if err != nil {
+				return err
+			}
+			switch op.Assert {
+			case nil:
+			case txn.DocExists:
+				if !found {
+					return txn.ErrAborted
+				}
+			case txn.DocMissing:
+				if found {
+					return txn.ErrAborted
+				}
+			default:
+				q, err := toDoc(op.Assert)
+				if err != nil {
+					return err
+				}
+				if !found || !matchDoc(row.doc, q) {
+					return txn.ErrAborted
+				}
+			}
+		}
+
+		for _, op := range ops {
+			row, found, err := findRowById(tx, op.C, op.Id)
+			if err != nil {
+				return err
+			}
+			switch {
+			case op.Insert != nil:
+				if found {
+					continue
+				}
+				doc, err := toDoc(op.Insert)
+				if err != nil {
+					return err
+				}
+				doc["_id"] = op.Id
+				if err := insertRow(tx, op.C, doc); err != nil {
+					// document with same id is inserted by concurrent transaction
+					if e, ok := err.(*pq.Error); ok && e.Code == pgCodeUniqueViolation && e.Constraint == op.C+"_pkey" {
+						return txn.ErrAborted
+					}
+					return err
+				}
+			case op.Update != nil:
+				if !found {
+					continue
+				}
+				if err := updateRow(tx, op.C, row, op.Update); err != nil {
+					return err
+				}
+			case op.Remove:
+				if !found {
+					continue
+				}
+				if err := deleteRow(tx, op.C, row.id); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// apply ops in one sql transaction, every op is applied in it's own savepoint,
+// so duplicate key error of op rolls back only that op
+func (s *postgresStore) Bulk(collection string, ops []BulkOp) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	return s.withTx(func(tx *sql.Tx) error {
+		for _, op := range ops {
+			if _, err := tx.Exec("SAVEPOINT bulk_op"); err != nil {
+				return err
+			}
+			err := bulkWrite(tx, collection, op)
+			if err != nil && mgo.IsDup(err) {
+				_, err = tx.Exec("ROLLBACK TO SAVEPOINT bulk_op")
+			}
+			if err == nil {
+				_, err = tx.Exec("RELEASE SAVEPOINT bulk_op")
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// create table of collection with id, doc and data columns,
+// relational columns of tables are defined by script/postgres.sql
+func (s *postgresStore) EnsureCollection(collection string) error {
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %v (id text PRIMARY KEY, doc bytea NOT NULL, data jsonb NOT NULL)",
+		quoteIdent(collection))
+	_, err := s.db.Exec(query)
+	return err
+}
+
+// create expression index on fields of data, it's named by collection and fields like script/postgres.sql
+func (s *postgresStore) EnsureIndex(collection string, index Index) error {
+	query, err := createIndexSql(collection, index)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(query)
+	return err
+}
+
+func (s *postgresStore) DropIndex(collection string, index Index) error {
+	_, err := s.db.Exec(fmt.Sprintf("DROP INDEX IF EXISTS %v", quoteIdent(indexName(collection, index))))
+	return err
+}
+
+func (s *postgresStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// find rows which match selector and lock them until transaction ends
+func findRows(tx *sql.Tx, collection string, selector interface{}, limit int) ([]pgRow, error) {
+	q, err := toDoc(selector)
+	if err != nil {
+		return nil, err
+	}
+	b := &sqlBuilder{}
+	where, err := b.where(q, b.dataField)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("SELECT t.id, t.doc FROM %v t WHERE %v", quoteIdent(collection), where)
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return queryRows(tx, query+" FOR UPDATE", b.args...)
+}
+
+func bulkWrite(tx *sql.Tx, collection string, op BulkOp) error {
+	rows, err := findRows(tx, collection, op.Selector, 1)
+	if err != nil {
+		return err
+	}
+	switch {
+	case op.Remove:
+		if len(rows) > 0 {
+			return deleteRow(tx, collection, rows[0].id)
+		}
+	case len(rows) > 0:
+		return updateRow(tx, collection, rows[0], op.Update)
+	case op.Upsert:
+		doc, err := upsertDoc(op.Selector, op.Update)
+		if err != nil {
+			return err
+		}
+		return insertRow(tx, collection, doc)
+	}
+	return nil
+}
+
+func findRowById(tx *sql.Tx, collection string, id interface{}) (pgRow, bool, error) {
+	query := fmt.Sprintf("SELECT t.id, t.doc FROM %v t WHERE t.id = $1 FOR UPDATE", quoteIdent(collection))
+	rows, err := queryRows(tx, query, idKey(id))
+	if err != nil || len(rows) == 0 {
+		return pgRow{}, false, err
+	}
+	return rows[0], true, nil
+}
+
+func queryRows(ex sqlExecutor, query string, args ...interface{}) ([]pgRow, error) {
+	rows, err := ex.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []pgRow
+	for rows.Next() {
+		var (
+			row  pgRow
+			data []byte
+		)
+		if err := rows.Scan(&row.id, &data); err != nil {
+			return nil, err
+		}
+		if err := bson.Unmarshal(data, &row.doc); err != nil {
+			return nil, err
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+func insertRow(ex sqlExecutor, collection string, v interface{}) error {
+	doc, err := toDoc(v)
+	if err != nil {
+		return err
+	}
+	if id, ok := doc["_id"]; !ok || id == nil {
+		doc["_id"] = bson.NewObjectId()
+	}
+	raw, data, err := encodeRow(doc)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %v (id, doc, data) VALUES ($1, $2, $3)", quoteIdent(collection))
+	_, err = ex.Exec(query, idKey(doc["_id"]), raw, string(data))
+	return dupError(err)
+}
+
+// apply update to document of row and write it back
+func updateRow(ex sqlExecutor, collection string, row pgRow, update interface{}) error {
+	doc, err := applyUpdate(row.doc, update)
+	if err != nil {
+		return err
+	}
+	raw, data, err := encodeRow(doc)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %v SET doc = $2, data = $3 WHERE id = $1", quoteIdent(collection))
+	_, err = ex.Exec(query, row.id, raw, string(data))
+	return dupError(err)
+}
+
+func deleteRow(ex sqlExecutor, collection string, id string) error {
+	_, err := ex.Exec(fmt.Sprintf("DELETE FROM %v WHERE id = $1", quoteIdent(collection)), id)
+	return err
+}
+
+// encode document into bson and json
+func encodeRow(doc bson.M) ([]byte, []byte, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := toJSON(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, data, nil
+}
+
+// unique violation is converted to duplicate key error of mgo, so callers can check it by mgo.IsDup
+func dupError(err error) error {
+	if e, ok := err.(*pq.Error); ok && e.Code == pgCodeUniqueViolation {
+		return &mgo.LastError{Code: 11000, Err: e.Message}
+	}
+	return err
+}
+
+// pgQuery holds options of query, it's executed when One, All or Count is called
+type pgQuery struct {
+	store      *postgresStore
+	collection string
+	selector   interface{}
+	sort       []string
+	fields     interface{}
+	skip       int
+	limit      int
+}
+
+func (q *pgQuery) Sort(fields ...string) Query {
+	q.sort = fields
+	return q
+}
+
+func (q *pgQuery) Select(fields interface{}) Query {
+	q.fields = fields
+	return q
+}
+
+func (q *pgQuery) Skip(n int) Query {
+	q.skip = n
+	return q
+}
+
+func (q *pgQuery) Limit(n int) Query {
+	q.limit = n
+	return q
+}
+
+func (q *pgQuery) One(result interface{}) error {
+	q.limit = 1
+	docs, err := q.run()
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return ErrNotFound
+	}
+	return decodeDoc(docs[0], result)
+}
+
+func (q *pgQuery) All(result interface{}) error {
+	docs, err := q.run()
+	if err != nil {
+		return err
+	}
+	return decodeDocs(docs, result)
+}
+
+func (q *pgQuery) Count() (int, error) {
+	b := &sqlBuilder{}
+	query, err := b.find(q.collection, q.selector, nil, q.skip, q.limit)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	err = q.store.db.QueryRow(fmt.Sprintf("SELECT count(*) FROM (%v) c", query), b.args...).Scan(&n)
+	return n, err
+}
+
+func (q *pgQuery) run() ([]bson.M, error) {
+	b := &sqlBuilder{}
+	query, err := b.find(q.collection, q.selector, q.sort, q.skip, q.limit)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := queryRows(q.store.db, query, b.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields bson.M
+	if q.fields != nil {
+		if fields, err = toDoc(q.fields); err != nil {
+			return nil, err
+		}
+	}
+	docs := make([]bson.M, 0, len(rows))
+	for _, row := range rows {
+		docs = append(docs, projectDoc(row.doc, fields))
+	}
+	return docs, nil
+}
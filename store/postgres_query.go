@@ -0,0 +1,651 @@
+// translate selector, sort and aggregation pipeline of mongodb syntax into sql on data column of postgres tables,
+// only operators used by documents are supported
+
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// format of time in data column, it's in utc and has fixed length, so times can be compared as strings
+	pgTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+)
+
+var pathPartRegexp = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// sqlBuilder collects args of sql, args are referenced by $n in sql
+type sqlBuilder struct {
+	args []interface{}
+	// paths unwound by pipeline and alias of their elements
+	unwinds map[string]string
+	// order of documents before $group stage, it's used by $first, $last and $push
+	preOrder string
+}
+
+func (b *sqlBuilder) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+func (b *sqlBuilder) jsonArg(v interface{}) (string, error) {
+	data, err := toJSON(v)
+	if err != nil {
+		return "", err
+	}
+	return b.arg(string(data)) + "::jsonb", nil
+}
+
+// expression of field of document in table
+func (b *sqlBuilder) dataField(path string) (string, error) {
+	return fieldExpr("t.data", path)
+}
+
+// expression of field of document in pipeline, fields of unwound array are read from it's elements
+func (b *sqlBuilder) pipelineField(path string) (string, error) {
+	var prefix string
+	for p := range b.unwinds {
+		if (path == p || strings.HasPrefix(path, p+".")) && len(p) > len(prefix) {
+			prefix = p
+		}
+	}
+	if prefix == "" {
+		return fieldExpr("t.data", path)
+	}
+	elem := b.unwinds[prefix] + ".elem"
+	if path == prefix {
+		return elem, nil
+	}
+	return fieldExpr(elem, strings.TrimPrefix(path, prefix+"."))
+}
+
+// expression of field of grouped document
+func (b *sqlBuilder) groupField(path string) (string, error) {
+	return fieldExpr("g.row", path)
+}
+
+func fieldExpr(column, path string) (string, error) {
+	parts := strings.Split(path, ".")
+	for _, p := range parts {
+		if !pathPartRegexp.MatchString(p) {
+			return "", fmt.Errorf("invalid field path %v", path)
+		}
+	}
+	return fmt.Sprintf("(%v #> '{%v}')", column, strings.Join(parts, ",")), nil
+}
+
+// build sql to find documents of collection
+func (b *sqlBuilder) find(collection string, selector interface{}, sortFields []string, skip, limit int) (string, error) {
+	q, err := toDoc(selector)
+	if err != nil {
+		return "", err
+	}
+	where, err := b.where(q, b.dataField)
+	if err != nil {
+		return "", err
+	}
+
+	query := fmt.Sprintf("SELECT t.id, t.doc FROM %v t WHERE %v", quoteIdent(collection), where)
+	if len(sortFields) > 0 {
+		var keys []bson.DocElem
+		for _, f := range sortFields {
+			if strings.HasPrefix(f, "-") {
+				keys = append(keys, bson.DocElem{Name: f[1:], Value: -1})
+			} else {
+				keys = append(keys, bson.DocElem{Name: strings.TrimPrefix(f, "+"), Value: 1})
+			}
+		}
+		order, err := b.orderBy(keys, b.dataField)
+		if err != nil {
+			return "", err
+		}
+		query += " ORDER BY " + order
+	}
+	return query + pageClause(skip, limit), nil
+}
+
+// translate query into condition, query should be converted by toDoc
+func (b *sqlBuilder) where(query bson.M, field func(string) (string, error)) (string, error) {
+	var conds []string
+	for _, k := range sortedKeys(query) {
+		cond := query[k]
+		switch k {
+		case "$or", "$and", "$nor":
+			subs, _ := cond.([]interface{})
+			var subConds []string
+			for _, v := range subs {
+				sub, ok := v.(bson.M)
+				if !ok {
+					return "", fmt.Errorf("%v should be list of documents", k)
+				}
+				c, err := b.where(sub, field)
+				if err != nil {
+					return "", err
+				}
+				subConds = append(subConds, c)
+			}
+			switch {
+			case len(subConds) == 0:
+				return "", fmt.Errorf("%v should have at least one document", k)
+			case k == "$and":
+				conds = append(conds, "("+strings.Join(subConds, " AND ")+")")
+			case k == "$or":
+				conds = append(conds, "("+strings.Join(subConds, " OR ")+")")
+			default:
+				conds = append(conds, "NOT ("+strings.Join(subConds, " OR ")+")")
+			}
+		default:
+			expr, err := field(k)
+			if err != nil {
+				return "", err
+			}
+			c, err := b.fieldCond(expr, cond)
+			if err != nil {
+				return "", err
+			}
+			conds = append(conds, c)
+		}
+	}
+	if len(conds) == 0 {
+		return "true", nil
+	}
+	return strings.Join(conds, " AND "), nil
+}
+
+func (b *sqlBuilder) fieldCond(expr string, cond interface{}) (string, error) {
+	ops, ok := cond.(bson.M)
+	if !ok || !isOperatorDoc(ops) {
+		return b.eqCond(expr, cond)
+	}
+
+	var conds []string
+	for _, op := range sortedKeys(ops) {
+		arg := ops[op]
+		var (
+			c   string
+			err error
+		)
+		switch op {
+		case "$eq":
+			c, err = b.eqCond(expr, arg)
+		case "$ne":
+			if c, err = b.eqCond(expr, arg); err == nil {
+				c = "NOT " + c
+			}
+		case "$in", "$nin":
+			list, ok := arg.([]interface{})
+			if !ok {
+				return "", fmt.Errorf("%v should be list", op)
+			}
+			var eqs []string
+			for _, v := range list {
+				eq, err := b.eqCond(expr, v)
+				if err != nil {
+					return "", err
+				}
+				eqs = append(eqs, eq)
+			}
+			c = "false"
+			if len(eqs) > 0 {
+				c = "(" + strings.Join(eqs, " OR ") + ")"
+			}
+			if op == "$nin" {
+				c = "NOT " + c
+			}
+		case "$gt", "$gte", "$lt", "$lte":
+			var v string
+			if v, err = b.jsonArg(arg); err == nil {
+				sym := map[string]string{"$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<="}[op]
+				c = fmt.Sprintf("COALESCE(jsonb_typeof(%v) = jsonb_typeof(%v) AND %v %v %v, false)", expr, v, expr, sym, v)
+			}
+		case "$exists":
+			c = expr + " IS NULL"
+			if truthy(arg) {
+				c = expr + " IS NOT NULL"
+			}
+		default:
+			return "", fmt.Errorf("unsupported operator %v", op)
+		}
+		if err != nil {
+			return "", err
+		}
+		conds = append(conds, c)
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", nil
+}
+
+// missing field equals to nil, array field equals to value which is equal to any of it's elements
+func (b *sqlBuilder) eqCond(expr string, v interface{}) (string, error) {
+	if v == nil {
+		return fmt.Sprintf("(%v IS NULL OR %v = 'null'::jsonb)", expr, expr), nil
+	}
+	value, err := b.jsonArg(v)
+	if err != nil {
+		return "", err
+	}
+	elems, err := b.jsonArg([]interface{}{v})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("COALESCE(%v = %v OR (jsonb_typeof(%v) = 'array' AND %v @> %v), false)",
+		expr, value, expr, expr, elems), nil
+}
+
+// translate sort keys into order by clause, value of key is 1 or -1.
+// missing field is less than any value like mongodb
+func (b *sqlBuilder) orderBy(keys []bson.DocElem, field func(string) (string, error)) (string, error) {
+	var orders []string
+	for _, k := range keys {
+		expr, err := field(k.Name)
+		if err != nil {
+			return "", err
+		}
+		if n, ok := toFloat(k.Value); ok && n < 0 {
+			orders = append(orders, expr+" DESC NULLS LAST")
+		} else {
+			orders = append(orders, expr+" ASC NULLS FIRST")
+		}
+	}
+	return strings.Join(orders, ", "), nil
+}
+
+// translate aggregation pipeline into sql which returns documents in json.
+// pipeline should have exactly one $group stage, $match, $unwind and $sort are supported before it,
+// $match, $sort, $skip and $limit are supported after it
+func (b *sqlBuilder) pipeline(collection string, pipeline interface{}) (string, error) {
+	stages := reflect.ValueOf(pipeline)
+	if stages.Kind() != reflect.Slice {
+		return "", fmt.Errorf("pipeline should be slice of stages")
+	}
+
+	var (
+		from    = quoteIdent(collection) + " t"
+		wheres  []string
+		group   *bson.DocElem
+		outer   []string
+		orderBy string
+		skip    int
+		limit   int
+	)
+	b.unwinds = make(map[string]string)
+	for i := 0; i < stages.Len(); i++ {
+		elems := docElems(stages.Index(i).Interface())
+		if len(elems) != 1 {
+			return "", fmt.Errorf("stage should have exactly one field")
+		}
+		stage := elems[0]
+
+		if group == nil {
+			switch stage.Name {
+			case "$match":
+				q, err := toDoc(stage.Value)
+				if err != nil {
+					return "", err
+				}
+				c, err := b.where(q, b.pipelineField)
+				if err != nil {
+					return "", err
+				}
+				wheres = append(wheres, c)
+			case "$unwind":
+				path, _ := stage.Value.(string)
+				path = strings.TrimPrefix(path, "$")
+				expr, err := b.pipelineField(path)
+				if err != nil {
+					return "", err
+				}
+				alias := fmt.Sprintf("u%d", len(b.unwinds)+1)
+				from += fmt.Sprintf(" CROSS JOIN LATERAL jsonb_array_elements(CASE WHEN jsonb_typeof(%v) = 'array' THEN %v ELSE '[]'::jsonb END) AS %v(elem)",
+					expr, expr, alias)
+				b.unwinds[path] = alias
+			case "$sort":
+				order, err := b.orderBy(docElems(stage.Value), b.pipelineField)
+				if err != nil {
+					return "", err
+				}
+				b.preOrder = order
+			case "$group":
+				g := stage
+				group = &g
+			default:
+				return "", fmt.Errorf("unsupported pipeline stage %v before $group", stage.Name)
+			}
+			continue
+		}
+
+		switch stage.Name {
+		case "$match":
+			q, err := toDoc(stage.Value)
+			if err != nil {
+				return "", err
+			}
+			c, err := b.where(q, b.groupField)
+			if err != nil {
+				return "", err
+			}
+			outer = append(outer, c)
+		case "$sort":
+			order, err := b.orderBy(docElems(stage.Value), b.groupField)
+			if err != nil {
+				return "", err
+			}
+			orderBy = order
+		case "$skip", "$limit":
+			n, _ := toFloat(stage.Value)
+			if stage.Name == "$skip" {
+				skip = int(n)
+			} else {
+				limit = int(n)
+			}
+		default:
+			return "", fmt.Errorf("unsupported pipeline stage %v after $group", stage.Name)
+		}
+	}
+	if group == nil {
+		return "", fmt.Errorf("pipeline should have $group stage")
+	}
+
+	row, groupBy, err := b.group(group.Value)
+	if err != nil {
+		return "", err
+	}
+	inner := fmt.Sprintf("SELECT %v AS row FROM %v", row, from)
+	if len(wheres) > 0 {
+		inner += " WHERE " + strings.Join(wheres, " AND ")
+	}
+	if groupBy != "" {
+		inner += " GROUP BY " + groupBy
+	} else {
+		// there is no group when there is no document like mongodb
+		inner += " HAVING count(*) > 0"
+	}
+
+	query := fmt.Sprintf("SELECT g.row FROM (%v) g", inner)
+	if len(outer) > 0 {
+		query += " WHERE " + strings.Join(outer, " AND ")
+	}
+	if orderBy != "" {
+		query += " ORDER BY " + orderBy
+	}
+	return query + pageClause(skip, limit), nil
+}
+
+// build json object of grouped document and group by expression,
+// group by expression is empty when _id is constant
+func (b *sqlBuilder) group(spec interface{}) (string, string, error) {
+	fields := docElems(spec)
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Name < fields[j].Name
+	})
+
+	var (
+		pairs   []string
+		groupBy string
+	)
+	for _, f := range fields {
+		if f.Name != "_id" {
+			continue
+		}
+		id, constant, err := b.expr(f.Value)
+		if err != nil {
+			return "", "", err
+		}
+		if !constant {
+			groupBy = id
+		}
+		pairs = append(pairs, "'_id', "+id)
+	}
+	if len(pairs) == 0 {
+		return "", "", fmt.Errorf("$group should have _id")
+	}
+
+	for _, f := range fields {
+		if f.Name == "_id" {
+			continue
+		}
+		acc := docElems(f.Value)
+		if len(acc) != 1 {
+			return "", "", fmt.Errorf("accumulator of %v should have exactly one field", f.Name)
+		}
+		v, err := b.accumulator(acc[0].Name, acc[0].Value)
+		if err != nil {
+			return "", "", err
+		}
+		pairs = append(pairs, fmt.Sprintf("'%v', %v", f.Name, v))
+	}
+	return "jsonb_build_object(" + strings.Join(pairs, ", ") + ")", groupBy, nil
+}
+
+// translate accumulator of $group, supported accumulators are $sum, $max, $min, $avg, $first, $last and $push
+func (b *sqlBuilder) accumulator(name string, arg interface{}) (string, error) {
+	e, constant, err := b.expr(arg)
+	if err != nil {
+		return "", err
+	}
+
+	var order string
+	if b.preOrder != "" {
+		order = " ORDER BY " + b.preOrder
+	}
+	switch name {
+	case "$sum":
+		if constant {
+			if n, ok := toFloat(arg); ok {
+				return fmt.Sprintf("count(*) * %v", strconv.FormatFloat(n, 'f', -1, 64)), nil
+			}
+			return "0", nil
+		}
+		return fmt.Sprintf("COALESCE(sum((%v)::numeric) FILTER (WHERE jsonb_typeof(%v) = 'number'), 0)", e, e), nil
+	case "$avg":
+		return fmt.Sprintf("avg((%v)::numeric) FILTER (WHERE jsonb_typeof(%v) = 'number')", e, e), nil
+	case "$max", "$min":
+		dir := "DESC"
+		if name == "$min" {
+			dir = "ASC"
+		}
+		return fmt.Sprintf("(array_agg(%v ORDER BY %v %v) FILTER (WHERE %v IS NOT NULL AND %v <> 'null'::jsonb))[1]",
+			e, e, dir, e, e), nil
+	case "$first":
+		return fmt.Sprintf("(array_agg(%v%v))[1]", e, order), nil
+	case "$last":
+		return fmt.Sprintf("(array_agg(%v%v))[count(*)]", e, order), nil
+	case "$push":
+		return fmt.Sprintf("COALESCE(jsonb_agg(%v%v) FILTER (WHERE %v IS NOT NULL), '[]'::jsonb)", e, order, e), nil
+	}
+	return "", fmt.Errorf("unsupported accumulator %v", name)
+}
+
+// translate expression of pipeline, "$field" is value of field in document.
+// constant is true when expression doesn't reference any field
+func (b *sqlBuilder) expr(expr interface{}) (string, bool, error) {
+	switch e := expr.(type) {
+	case nil:
+		return "'null'::jsonb", true, nil
+	case string:
+		if strings.HasPrefix(e, "$") {
+			f, err := b.pipelineField(e[1:])
+			if err != nil {
+				return "", false, err
+			}
+			return fmt.Sprintf("COALESCE(%v, 'null'::jsonb)", f), false, nil
+		}
+	case bson.M, bson.D:
+		elems := docElems(e)
+		sort.Slice(elems, func(i, j int) bool {
+			return elems[i].Name < elems[j].Name
+		})
+		var (
+			pairs    []string
+			constant = true
+		)
+		for _, v := range elems {
+			if !pathPartRegexp.MatchString(v.Name) {
+				return "", false, fmt.Errorf("invalid field name %v", v.Name)
+			}
+			s, c, err := b.expr(v.Value)
+			if err != nil {
+				return "", false, err
+			}
+			constant = constant && c
+			pairs = append(pairs, fmt.Sprintf("'%v', %v", v.Name, s))
+		}
+		return "jsonb_build_object(" + strings.Join(pairs, ", ") + ")", constant, nil
+	}
+	v, err := b.jsonArg(expr)
+	return v, true, err
+}
+
+func createIndexSql(collection string, index Index) (string, error) {
+	var exprs []string
+	for _, k := range index.Key {
+		path := strings.TrimLeft(k, "+-")
+		expr, err := fieldExpr("data", path)
+		if err != nil {
+			return "", err
+		}
+		if strings.HasPrefix(k, "-") {
+			expr += " DESC"
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 0 {
+		return "", fmt.Errorf("index of %v should have at least one field", collection)
+	}
+
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %vINDEX IF NOT EXISTS %v ON %v (%v)", unique, quoteIdent(indexName(collection, index)),
+		quoteIdent(collection), strings.Join(exprs, ", ")), nil
+}
+
+// index is named by collection and fields, e.g. tx_msg_hash_index
+func indexName(collection string, index Index) string {
+	names := []string{collection}
+	for _, k := range index.Key {
+		names = append(names, strings.Replace(strings.TrimLeft(k, "+-"), ".", "_", -1))
+	}
+	return strings.Join(names, "_")
+}
+
+func pageClause(skip, limit int) string {
+	var s string
+	if skip > 0 {
+		s += fmt.Sprintf(" OFFSET %d", skip)
+	}
+	if limit > 0 {
+		s += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return s
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func sortedKeys(doc bson.M) []string {
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// key of document in id column, object id is stored in hex
+func idKey(id interface{}) string {
+	switch v := normalize(id).(type) {
+	case bson.ObjectId:
+		return v.Hex()
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// encode value into json of data column, object id is encoded in hex, time is encoded by pgTimeFormat
+// and decimal128 is encoded as number
+func toJSON(v interface{}) ([]byte, error) {
+	return json.Marshal(jsonValue(normalize(v)))
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.M:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		arr := make([]interface{}, len(t))
+		for i, e := range t {
+			arr[i] = jsonValue(e)
+		}
+		return arr
+	case bson.ObjectId:
+		return t.Hex()
+	case time.Time:
+		return t.UTC().Format(pgTimeFormat)
+	case bson.Decimal128:
+		// decimal is kept exactly as numeric of jsonb
+		return json.Number(t.String())
+	}
+	return v
+}
+
+// decode json returned by sql, integer is decoded into int64 like bson,
+// integer out of range of int64 is decoded into decimal128 to keep it exactly
+func fromJSON(data []byte) (bson.M, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	doc, ok := fromJSONValue(v).(bson.M)
+	if !ok {
+		return nil, fmt.Errorf("result should be document")
+	}
+	return doc, nil
+}
+
+func fromJSONValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		doc := make(bson.M, len(t))
+		for k, e := range t {
+			doc[k] = fromJSONValue(e)
+		}
+		return doc
+	case []interface{}:
+		for i, e := range t {
+			t[i] = fromJSONValue(e)
+		}
+		return t
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n
+		}
+		if !strings.ContainsAny(t.String(), ".eE") {
+			if d, err := bson.ParseDecimal128(t.String()); err == nil {
+				return d
+			}
+		}
+		f, _ := t.Float64()
+		return f
+	}
+	return v
+}
@@ -0,0 +1,216 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestSqlBuilder_where(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector bson.M
+		want     string
+		args     []interface{}
+	}{
+		{
+			name:     "empty",
+			selector: nil,
+			want:     "true",
+		},
+		{
+			name:     "range",
+			selector: bson.M{"height": bson.M{"$gt": 1, "$lte": 100}},
+			want: "(COALESCE(jsonb_typeof((t.data #> '{height}')) = jsonb_typeof($1::jsonb) AND (t.data #> '{height}') > $1::jsonb, false)" +
+				" AND COALESCE(jsonb_typeof((t.data #> '{height}')) = jsonb_typeof($2::jsonb) AND (t.data #> '{height}') <= $2::jsonb, false))",
+			args: []interface{}{"1", "100"},
+		},
+		{
+			name:     "nil and in",
+			selector: bson.M{"lease_expire_time": bson.M{"$in": []interface{}{nil}}, "status": "underway"},
+			want: "((((t.data #> '{lease_expire_time}') IS NULL OR (t.data #> '{lease_expire_time}') = 'null'::jsonb)))" +
+				" AND COALESCE((t.data #> '{status}') = $1::jsonb OR (jsonb_typeof((t.data #> '{status}')) = 'array' AND (t.data #> '{status}') @> $2::jsonb), false)",
+			args: []interface{}{`"underway"`, `["underway"]`},
+		},
+		{
+			name: "or and exists",
+			selector: bson.M{"$or": []bson.M{
+				{"meta.header.chain_id": bson.M{"$exists": false}},
+				{"time": bson.M{"$lt": time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)}},
+			}},
+			want: "(((t.data #> '{meta,header,chain_id}') IS NULL)" +
+				" OR (COALESCE(jsonb_typeof((t.data #> '{time}')) = jsonb_typeof($1::jsonb) AND (t.data #> '{time}') < $1::jsonb, false)))",
+			args: []interface{}{`"2019-01-02T03:04:05.000Z"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := toDoc(tt.selector)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := &sqlBuilder{}
+			got, err := b.where(q, b.dataField)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("where() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(b.args, tt.args) {
+				t.Errorf("args = %v, want %v", b.args, tt.args)
+			}
+		})
+	}
+
+	b := &sqlBuilder{}
+	if _, err := b.where(bson.M{"height'; drop table block; --": 1}, b.dataField); err == nil {
+		t.Error("invalid field path should be rejected")
+	}
+}
+
+func TestSqlBuilder_find(t *testing.T) {
+	b := &sqlBuilder{}
+	got, err := b.find("block", nil, []string{"-height"}, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT t.id, t.doc FROM "block" t WHERE true ORDER BY (t.data #> '{height}') DESC NULLS LAST LIMIT 1`
+	if got != want {
+		t.Errorf("find() = %v, want %v", got, want)
+	}
+}
+
+// pipelines are same with them used by uptime, tx gas and validator history tasks
+func TestSqlBuilder_pipeline(t *testing.T) {
+	heightRange := bson.M{"$match": bson.M{"height": bson.M{"$gt": 10, "$lte": 20}}}
+	matchSql := "WHERE (COALESCE(jsonb_typeof((t.data #> '{height}')) = jsonb_typeof($1::jsonb) AND (t.data #> '{height}') > $1::jsonb, false)" +
+		" AND COALESCE(jsonb_typeof((t.data #> '{height}')) = jsonb_typeof($2::jsonb) AND (t.data #> '{height}') <= $2::jsonb, false))"
+
+	tests := []struct {
+		name       string
+		collection string
+		pipeline   []bson.M
+		want       string
+	}{
+		{
+			name:       "precommits of validators",
+			collection: "block",
+			pipeline: []bson.M{
+				heightRange,
+				{"$unwind": "$block.last_commit.precommits"},
+				{"$group": bson.M{
+					"_id": "$block.last_commit.precommits.validator_address",
+					"num": bson.M{"$sum": 1},
+				}},
+				{"$sort": bson.M{"num": -1}},
+			},
+			want: "SELECT g.row FROM (SELECT jsonb_build_object('_id', COALESCE((u1.elem #> '{validator_address}'), 'null'::jsonb), 'num', count(*) * 1) AS row" +
+				` FROM "block" t CROSS JOIN LATERAL jsonb_array_elements(CASE WHEN jsonb_typeof((t.data #> '{block,last_commit,precommits}')) = 'array'` +
+				" THEN (t.data #> '{block,last_commit,precommits}') ELSE '[]'::jsonb END) AS u1(elem) " + matchSql +
+				" GROUP BY COALESCE((u1.elem #> '{validator_address}'), 'null'::jsonb)) g ORDER BY (g.row #> '{num}') DESC NULLS LAST",
+		},
+		{
+			name:       "stat of blocks",
+			collection: "block",
+			pipeline: []bson.M{
+				heightRange,
+				{"$group": bson.M{
+					"_id":        nil,
+					"num_blocks": bson.M{"$sum": 1},
+					"num_txs":    bson.M{"$sum": "$num_txs"},
+				}},
+			},
+			want: "SELECT g.row FROM (SELECT jsonb_build_object('_id', 'null'::jsonb, 'num_blocks', count(*) * 1," +
+				" 'num_txs', COALESCE(sum((COALESCE((t.data #> '{num_txs}'), 'null'::jsonb))::numeric) FILTER (WHERE jsonb_typeof(COALESCE((t.data #> '{num_txs}'), 'null'::jsonb)) = 'number'), 0)) AS row" +
+				` FROM "block" t ` + matchSql + " HAVING count(*) > 0) g",
+		},
+		{
+			name:       "num of txs by height",
+			collection: "tx_common",
+			pipeline: []bson.M{
+				heightRange,
+				{"$group": bson.M{
+					"_id": "$height",
+					"num": bson.M{"$sum": 1},
+				}},
+			},
+			want: "SELECT g.row FROM (SELECT jsonb_build_object('_id', COALESCE((t.data #> '{height}'), 'null'::jsonb), 'num', count(*) * 1) AS row" +
+				` FROM "tx_common" t ` + matchSql + " GROUP BY COALESCE((t.data #> '{height}'), 'null'::jsonb)) g",
+		},
+		{
+			name:       "max height of tasks",
+			collection: "sync_task",
+			pipeline: []bson.M{
+				{"$group": bson.M{
+					"_id": nil,
+					"max": bson.M{"$max": "$end_height"},
+				}},
+			},
+			want: "SELECT g.row FROM (SELECT jsonb_build_object('_id', 'null'::jsonb, 'max', (array_agg(COALESCE((t.data #> '{end_height}'), 'null'::jsonb)" +
+				" ORDER BY COALESCE((t.data #> '{end_height}'), 'null'::jsonb) DESC) FILTER (WHERE COALESCE((t.data #> '{end_height}'), 'null'::jsonb) IS NOT NULL" +
+				` AND COALESCE((t.data #> '{end_height}'), 'null'::jsonb) <> 'null'::jsonb))[1]) AS row FROM "sync_task" t HAVING count(*) > 0) g`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &sqlBuilder{}
+			got, err := b.pipeline(tt.collection, tt.pipeline)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("pipeline() = %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+
+	b := &sqlBuilder{}
+	if _, err := b.pipeline("block", []bson.M{heightRange}); err == nil {
+		t.Error("pipeline without $group should be rejected")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	id := bson.NewObjectId()
+	doc := bson.M{
+		"_id":    id,
+		"height": int64(1 << 60),
+		"time":   time.Date(2019, 1, 2, 3, 4, 5, 6000000, time.FixedZone("", 3600)),
+		"votes":  []interface{}{bson.M{"voter": "a", "power": 1.5}},
+	}
+	data, err := toJSON(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := fromJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bson.M{
+		"_id":    id.Hex(),
+		"height": int64(1 << 60),
+		"time":   "2019-01-02T02:04:05.006Z",
+		"votes":  []interface{}{bson.M{"voter": "a", "power": 1.5}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fromJSON(toJSON()) = %v, want %v", got, want)
+	}
+	if idKey(id) != id.Hex() || idKey("abc") != "abc" {
+		t.Errorf("idKey() of %v = %v", id, idKey(id))
+	}
+}
+
+func TestCreateIndexSql(t *testing.T) {
+	got, err := createIndexSql("stake_role_delegator", Index{Key: []string{"address", "-validator_addr"}, Unique: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `CREATE UNIQUE INDEX IF NOT EXISTS "stake_role_delegator_address_validator_addr" ON "stake_role_delegator"` +
+		` ((data #> '{address}'), (data #> '{validator_addr}') DESC)`
+	if got != want {
+		t.Errorf("createIndexSql() = %v, want %v", got, want)
+	}
+}
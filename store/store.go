@@ -1,161 +1,176 @@
-// init mongodb session and provide common functions
+// storage backend of documents and common functions
 
 package store
 
 import (
-	"strings"
-	"time"
-
 	conf "github.com/irisnet/irishub-sync/conf/db"
-	"github.com/irisnet/irishub-sync/logger"
 
-	"errors"
-	"fmt"
 	"gopkg.in/mgo.v2"
-	"gopkg.in/mgo.v2/bson"
 	"gopkg.in/mgo.v2/txn"
 )
 
 var (
-	session *mgo.Session
+	backend Store
 	docs    []Docs
 )
 
+// ErrNotFound is returned by every backend when no document matches,
+// it's same with mgo.ErrNotFound so callers can keep comparing with either of them
+var ErrNotFound = mgo.ErrNotFound
+
+// Store is storage backend of documents. documents are read and written only through it,
+// so handlers and tasks don't depend on which db is used.
+// selector, update and pipeline are written in mongodb syntax, txn ops follow
+// semantics of mgo txn, every backend should support them.
+type Store interface {
+	// insert document, return error when document which has same primary key exists
+	Save(h Docs) error
+	// insert document if it doesn't exist, otherwise replace it
+	SaveOrUpdate(h Docs) error
+	// replace document which has same primary key with h
+	Update(h Docs) error
+	// delete document which has same primary key with h
+	Delete(h Docs) error
+
+	// insert documents into collection
+	Insert(collection string, docs ...interface{}) error
+	// query documents of collection which match selector
+	Find(collection string, selector interface{}) Query
+	// update first document which matches selector, update is operators or replacement document
+	UpdateOne(collection string, selector, update interface{}) error
+	// remove all documents which match selector, return num of removed documents
+	RemoveAll(collection string, selector interface{}) (int, error)
+	// run aggregation pipeline on collection, result should be pointer of slice
+	Aggregate(collection string, pipeline interface{}, result interface{}) error
+	// apply ops in one transaction, return txn.ErrAborted when assertion of any op fails
+	Txn(ops []txn.Op) error
+	// apply ops on collection in any order, duplicate key errors are ignored,
+	// so ops should be idempotent. ops are not atomic, some of them may be applied when error is returned
+	Bulk(collection string, ops []BulkOp) error
+
+	// create collection if it doesn't exist
+	EnsureCollection(collection string) error
+	// create index of collection if it doesn't exist
+	EnsureIndex(collection string, index Index) error
+	// drop index of collection which has same key with index, nothing is done when it doesn't exist
+	DropIndex(collection string, index Index) error
+
+	Close()
+}
+
+// Query is built by Store.Find, methods have same meaning with them of mgo.Query
+type Query interface {
+	Sort(fields ...string) Query
+	Select(fields interface{}) Query
+	Skip(n int) Query
+	Limit(n int) Query
+
+	One(result interface{}) error
+	All(result interface{}) error
+	Count() (int, error)
+}
+
+// BulkOp is one write of Store.Bulk on first document which matches selector.
+// update is update operators, when upsert is set and no document matches,
+// document which has equality fields of selector, $set and $setOnInsert fields of update is inserted
+type BulkOp struct {
+	Selector interface{}
+	Update   interface{}
+	Upsert   bool
+	Remove   bool
+}
+
+// Index is index of collection, key is list of fields,
+// field prefixed with "-" is in descending order like key of mgo.Index
+type Index struct {
+	Key    []string
+	Unique bool
+}
+
 func RegisterDocs(d Docs) {
 	docs = append(docs, d)
 }
 
+// start store with backend configured by env, default backend is mongodb
 func Start() {
-	addrs := strings.Split(conf.Addrs, ",")
-	dialInfo := &mgo.DialInfo{
-		Addrs:     addrs,
-		Database:  conf.Database,
-		Username:  conf.User,
-		Password:  conf.Passwd,
-		Direct:    true,
-		Timeout:   time.Second * 10,
-		PoolLimit: 4096, // Session.SetPoolLimit
+	switch conf.Backend {
+	case conf.BackendPostgres:
+		Use(newPostgresStore())
+	default:
+		Use(newMongoStore())
 	}
-
-	var err error
-	session, err = mgo.DialWithInfo(dialInfo)
-	if err != nil {
-		logger.Error(err.Error())
-	}
-	session.SetMode(mgo.Monotonic, true)
-}
-
-func Stop() {
-	logger.Info("release resource :mongoDb")
-	session.Close()
 }
 
-func getSession() *mgo.Session {
-	// max session num is 4096
-	return session.Clone()
+// use s as backend of store, handlers and tasks read and write documents through it.
+// it should be called before sync engine is started
+func Use(s Store) {
+	backend = s
 }
 
-// get collection object
-func ExecCollection(collection string, s func(*mgo.Collection) error) error {
-	session := getSession()
-	defer session.Close()
-	c := session.DB(conf.Database).C(collection)
-	return s(c)
+// get backend in use
+func Backend() Store {
+	return backend
 }
 
-func Find(collection string, query interface{}) *mgo.Query {
-	session := getSession()
-	defer session.Close()
-	c := session.DB(conf.Database).C(collection)
-	return c.Find(query)
+func Stop() {
+	backend.Close()
 }
 
 func Save(h Docs) error {
-	save := func(c *mgo.Collection) error {
-		pk := h.PkKvPair()
-		n, _ := c.Find(pk).Count()
-		if n >= 1 {
-			errMsg := fmt.Sprintf("Record exists")
-			return errors.New(errMsg)
-		}
-		logger.Debug("Save document", logger.String("table", h.Name()), logger.Any("content", h))
-		return c.Insert(h)
-	}
-	return ExecCollection(h.Name(), save)
+	return backend.Save(h)
 }
 
 func SaveAll(collectionName string, docs []interface{}) error {
-	session := getSession()
-	defer session.Close()
-
-	c := session.DB(conf.Database).C(collectionName)
-	return c.Insert(docs...)
+	return backend.Insert(collectionName, docs...)
 }
 
 func SaveOrUpdate(h Docs) error {
-	save := func(c *mgo.Collection) error {
-		n, err := c.Find(h.PkKvPair()).Count()
-		if err != nil {
-			logger.Error("Store Find error", logger.String("err", err.Error()))
-		}
-
-		if n >= 1 {
-			return Update(h)
-		}
-		logger.Debug("Save document", logger.String("table", h.Name()), logger.Any("content", h))
-		return c.Insert(h)
-	}
-
-	return ExecCollection(h.Name(), save)
+	return backend.SaveOrUpdate(h)
 }
 
 func Update(h Docs) error {
-	update := func(c *mgo.Collection) error {
-		key := h.PkKvPair()
-		logger.Debug("update document", logger.String("table", h.Name()), logger.Any("conditions", h.PkKvPair()))
-		return c.Update(key, h)
-	}
-	return ExecCollection(h.Name(), update)
+	return backend.Update(h)
 }
 
 func Delete(h Docs) error {
-	remove := func(c *mgo.Collection) error {
-		key := h.PkKvPair()
-		logger.Debug("delete document", logger.String("table", h.Name()), logger.Any("conditions", h.PkKvPair()))
-		return c.Remove(key)
-	}
-	return ExecCollection(h.Name(), remove)
+	return backend.Delete(h)
 }
 
-func Query(collectionName string, query bson.M, sort string, fields bson.M, skip int, limit int) (results []interface{}, err error) {
-	callback := func(c *mgo.Collection) error {
-		logger.Debug("query document", logger.String("table", collectionName), logger.Any("conditions", query))
-		return c.Find(query).Sort(sort).Select(fields).Skip(skip).Limit(limit).All(&results)
-	}
-	return results, ExecCollection(collectionName, callback)
+func Find(collection string, selector interface{}) Query {
+	return backend.Find(collection, selector)
+}
+
+func UpdateOne(collection string, selector, update interface{}) error {
+	return backend.UpdateOne(collection, selector, update)
+}
+
+func RemoveAll(collection string, selector interface{}) (int, error) {
+	return backend.RemoveAll(collection, selector)
+}
+
+func Aggregate(collection string, pipeline interface{}, result interface{}) error {
+	return backend.Aggregate(collection, pipeline, result)
 }
 
-// mgo transaction method
+// transaction method, all ops are applied or none of them is applied
 // detail to see: https://godoc.org/gopkg.in/mgo.v2/txn
 func Txn(ops []txn.Op) error {
-	session := getSession()
-	defer session.Close()
-
-	c := session.DB(conf.Database).C(CollectionNameTxn)
-	runner := txn.NewRunner(c)
-
-	txObjectId := bson.NewObjectId()
-	err := runner.Run(ops, txObjectId, nil)
-	if err != nil {
-		if err == txn.ErrAborted {
-			err = runner.Resume(txObjectId)
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
-	}
+	return backend.Txn(ops)
+}
+
+// write documents of collection in bulk, it's much faster than writing documents one by one
+func Bulk(collection string, ops []BulkOp) error {
+	return backend.Bulk(collection, ops)
+}
+
+func EnsureCollection(collection string) error {
+	return backend.EnsureCollection(collection)
+}
+
+func EnsureIndex(collection string, index Index) error {
+	return backend.EnsureIndex(collection, index)
+}
 
-	return nil
+func DropIndex(collection string, index Index) error {
+	return backend.DropIndex(collection, index)
 }
@@ -14,8 +14,8 @@ type Docs interface {
 }
 
 type Coin struct {
-	Denom  string  `json:"denom"`
-	Amount float64 `json:"amount"`
+	Denom  string `json:"denom"`
+	Amount Dec    `json:"amount"`
 }
 
 type Coins []Coin
@@ -26,8 +26,8 @@ type Fee struct {
 }
 
 type ActualFee struct {
-	Denom  string  `json:"denom"`
-	Amount float64 `json:"amount"`
+	Denom  string `json:"denom"`
+	Amount Dec    `json:"amount"`
 }
 
 type Msg interface {
@@ -0,0 +1,179 @@
+// codec schedule of chain, amino registrations may change at software upgrade,
+// so data of block is decoded by codec of protocol version which is used at height of block
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/irisnet/irishub-sync/conf/server"
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/util/constant"
+	"github.com/irisnet/irishub/app"
+	"github.com/irisnet/irishub/app/v0"
+	"github.com/irisnet/irishub/codec"
+)
+
+const (
+	// codec of latest protocol version of irishub dependency
+	CodecVersionLatest = "latest"
+)
+
+// codec version is used from height, until height of next upgrade
+type CodecUpgrade struct {
+	Height  int64
+	Version string
+}
+
+type scheduledCodec struct {
+	height  int64
+	version string
+	cdc     *codec.Codec
+}
+
+var (
+	// codecs of protocol versions which are provided by irishub dependency
+	codecMakers = map[string]func() *codec.Codec{
+		CodecVersionLatest: app.MakeLatestCodec,
+		CodecVersion(0):    v0.MakeCodec,
+	}
+	codecs     []scheduledCodec
+	codecsOnce sync.Once
+	codecsLock sync.RWMutex
+)
+
+// register maker of codec of protocol version, it's called in init of package which provides codec,
+// so codec can be used in CODEC_SCHEDULE
+func RegisterCodecVersion(version string, maker func() *codec.Codec) {
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+	if _, ok := codecMakers[version]; ok {
+		panic(fmt.Sprintf("codec %v is registered twice", version))
+	}
+	codecMakers[version] = maker
+}
+
+// whether maker of codec of version is registered
+func IsCodecVersionRegistered(version string) bool {
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+	_, ok := codecMakers[version]
+	return ok
+}
+
+// name of codec of protocol version, which is version of software upgrade proposal
+func CodecVersion(version uint64) string {
+	return fmt.Sprintf("v%d", version)
+}
+
+// parse codec schedule like "1:v0,2000000:v1", version without height is used from height 1.
+// empty schedule means latest codec is used at all heights
+func ParseCodecSchedule(schedule string) ([]CodecUpgrade, error) {
+	var upgrades []CodecUpgrade
+
+	for _, item := range strings.Split(schedule, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		upgrade := CodecUpgrade{Height: 1, Version: item}
+		if i := strings.Index(item, ":"); i >= 0 {
+			height, err := strconv.ParseInt(item[:i], 10, 64)
+			if err != nil || height <= 0 {
+				return nil, fmt.Errorf("invalid height of codec %q", item)
+			}
+			upgrade = CodecUpgrade{Height: height, Version: item[i+1:]}
+		}
+		upgrades = append(upgrades, upgrade)
+	}
+	if len(upgrades) == 0 {
+		upgrades = []CodecUpgrade{{Height: 1, Version: CodecVersionLatest}}
+	}
+
+	sort.SliceStable(upgrades, func(i, j int) bool {
+		return upgrades[i].Height < upgrades[j].Height
+	})
+	for i := 1; i < len(upgrades); i++ {
+		if upgrades[i].Height == upgrades[i-1].Height {
+			return nil, fmt.Errorf("codecs %v and %v are used from same height %v",
+				upgrades[i-1].Version, upgrades[i].Version, upgrades[i].Height)
+		}
+	}
+	return upgrades, nil
+}
+
+// use codecs of schedule instead of CODEC_SCHEDULE, codec of first upgrade is also used before it's height.
+// codecs of versions in current schedule are reused
+func SetCodecSchedule(upgrades []CodecUpgrade) error {
+	codecsOnce.Do(func() {})
+	return setCodecSchedule(upgrades)
+}
+
+func setCodecSchedule(upgrades []CodecUpgrade) error {
+	codecsLock.Lock()
+	defer codecsLock.Unlock()
+
+	var scheduled []scheduledCodec
+	built := make(map[string]*codec.Codec)
+	for _, v := range codecs {
+		built[v.version] = v.cdc
+	}
+	for _, v := range upgrades {
+		if _, ok := built[v.Version]; !ok {
+			maker, ok := codecMakers[v.Version]
+			if !ok {
+				return fmt.Errorf("codec %v isn't registered", v.Version)
+			}
+			built[v.Version] = maker()
+		}
+		scheduled = append(scheduled, scheduledCodec{height: v.Height, version: v.Version, cdc: built[v.Version]})
+	}
+	if len(scheduled) == 0 {
+		return fmt.Errorf("codec schedule is empty")
+	}
+	codecs = scheduled
+	return nil
+}
+
+// codecs are built from CODEC_SCHEDULE when codec is used first time,
+// so codecs which are registered in init of other packages can be used
+func loadCodecSchedule() {
+	codecsOnce.Do(func() {
+		upgrades, err := ParseCodecSchedule(server.CodecSchedule)
+		if err == nil {
+			err = setCodecSchedule(upgrades)
+		}
+		if err != nil {
+			logger.Fatal("Env Value", logger.String(constant.EnvNameCodecSchedule, server.CodecSchedule),
+				logger.String("err", err.Error()))
+		}
+	})
+}
+
+// get codec which is used at height, it switches to codec of next protocol version at upgrade height
+func GetCodecAt(height int64) *codec.Codec {
+	loadCodecSchedule()
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+
+	i := sort.Search(len(codecs), func(i int) bool {
+		return codecs[i].height > height
+	})
+	if i == 0 {
+		return codecs[0].cdc
+	}
+	return codecs[i-1].cdc
+}
+
+// get codec of latest protocol version in schedule, it's used to decode current state of chain
+func GetCodec() *codec.Codec {
+	loadCodecSchedule()
+	codecsLock.RLock()
+	defer codecsLock.RUnlock()
+
+	return codecs[len(codecs)-1].cdc
+}
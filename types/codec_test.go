@@ -0,0 +1,74 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/irisnet/irishub/codec"
+)
+
+func TestParseCodecSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		want     []CodecUpgrade
+		wantErr  bool
+	}{
+		{schedule: "", want: []CodecUpgrade{{1, CodecVersionLatest}}},
+		{schedule: "v0", want: []CodecUpgrade{{1, "v0"}}},
+		{schedule: "2000:v1, 1:v0", want: []CodecUpgrade{{1, "v0"}, {2000, "v1"}}},
+		{schedule: "v0,1:v1", wantErr: true},
+		{schedule: "a:v1", wantErr: true},
+		{schedule: "0:v1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			got, err := ParseCodecSchedule(tt.schedule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCodecSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseCodecSchedule() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ParseCodecSchedule() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetCodecAt(t *testing.T) {
+	v0, v1 := codec.New(), codec.New()
+	RegisterCodecVersion("test-v0", func() *codec.Codec { return v0 })
+	RegisterCodecVersion("test-v1", func() *codec.Codec { return v1 })
+	defer func() {
+		delete(codecMakers, "test-v0")
+		delete(codecMakers, "test-v1")
+	}()
+
+	if err := SetCodecSchedule([]CodecUpgrade{{100, "unknown"}}); err == nil {
+		t.Error("codec which isn't registered is used")
+	}
+	if err := SetCodecSchedule([]CodecUpgrade{{10, "test-v0"}, {100, "test-v1"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetCodecSchedule([]CodecUpgrade{{1, CodecVersionLatest}})
+
+	tests := []struct {
+		height int64
+		want   *codec.Codec
+	}{
+		{height: 1, want: v0},
+		{height: 99, want: v0},
+		{height: 100, want: v1},
+		{height: 2000, want: v1},
+	}
+	for _, tt := range tests {
+		if got := GetCodecAt(tt.height); got != tt.want {
+			t.Errorf("GetCodecAt(%v) returns wrong codec", tt.height)
+		}
+	}
+	if GetCodec() != v1 {
+		t.Error("GetCodec() isn't codec of latest version")
+	}
+}
@@ -30,3 +30,8 @@ func NewBeginRedelegate(msg MsgBeginRedelegate) BeginRedelegate {
 		SharesAmount:     shares,
 	}
 }
+
+func UnmarshalBeginRedelegate(str string) (beginRedelegate BeginRedelegate) {
+	json.Unmarshal([]byte(str), &beginRedelegate)
+	return
+}
@@ -6,9 +6,7 @@ import (
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/util/constant"
-	"github.com/irisnet/irishub/app"
 	"github.com/irisnet/irishub/client/utils"
-	"github.com/irisnet/irishub/codec"
 	"github.com/irisnet/irishub/modules/auth"
 	"github.com/irisnet/irishub/modules/bank"
 	"github.com/irisnet/irishub/modules/distribution"
@@ -22,12 +20,14 @@ import (
 	staketypes "github.com/irisnet/irishub/modules/stake/types"
 	"github.com/irisnet/irishub/types"
 	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/multisig"
 	cmn "github.com/tendermint/tendermint/libs/common"
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/state"
 	tm "github.com/tendermint/tendermint/types"
 	"regexp"
-	"strconv"
 	"strings"
 )
 
@@ -55,9 +55,12 @@ type (
 	Proposal                         = gov.Proposal
 	SdkVote                          = gov.Vote
 
-	ResponseDeliverTx = abci.ResponseDeliverTx
+	ResponseDeliverTx  = abci.ResponseDeliverTx
+	ResponseEndBlock   = abci.ResponseEndBlock
+	ResponseBeginBlock = abci.ResponseBeginBlock
 
 	StdTx      = auth.StdTx
+	SdkMsg     = types.Msg
 	SdkCoins   = types.Coins
 	KVPair     = types.KVPair
 	AccAddress = types.AccAddress
@@ -67,13 +70,24 @@ type (
 	Tx         = tm.Tx
 	Block      = tm.Block
 	BlockMeta  = tm.BlockMeta
+	Commit     = tm.Commit
 	HexBytes   = cmn.HexBytes
 	TmKVPair   = cmn.KVPair
 
-	ABCIQueryOptions = rpcclient.ABCIQueryOptions
-	Client           = rpcclient.Client
-	HTTP             = rpcclient.HTTP
-	ResultStatus     = ctypes.ResultStatus
+	EventDataNewBlock = tm.EventDataNewBlock
+
+	StdSignature            = auth.StdSignature
+	PubKey                  = crypto.PubKey
+	PubKeyMultisigThreshold = multisig.PubKeyMultisigThreshold
+
+	ABCIQueryOptions   = rpcclient.ABCIQueryOptions
+	Client             = rpcclient.Client
+	HTTP               = rpcclient.HTTP
+	ResultStatus       = ctypes.ResultStatus
+	ResultBlockResults = ctypes.ResultBlockResults
+	ResultBlock        = ctypes.ResultBlock
+	ResultValidators   = ctypes.ResultValidators
+	ABCIResponses      = state.ABCIResponses
 )
 
 var (
@@ -92,6 +106,7 @@ var (
 	MustUnmarshalUBD        = staketypes.MustUnmarshalUBD
 
 	Bech32ifyValPub      = types.Bech32ifyValPub
+	Bech32ifyAccPub      = types.Bech32ifyAccPub
 	RegisterCodec        = types.RegisterCodec
 	AccAddressFromBech32 = types.AccAddressFromBech32
 	BondStatusToString   = types.BondStatusToString
@@ -106,6 +121,8 @@ var (
 
 	NewHTTP = rpcclient.NewHTTP
 
+	EventQueryNewBlock = tm.EventQueryNewBlock
+
 	//tags
 	TagGovProposalID                   = tags.ProposalID
 	TagDistributionReward              = dtags.Reward
@@ -113,8 +130,6 @@ var (
 	TagStakeDelegator                  = stags.Delegator
 	TagStakeSrcValidator               = stags.SrcValidator
 	TagAction                          = types.TagAction
-
-	cdc *codec.Codec
 )
 
 // 初始化账户地址前缀
@@ -122,11 +137,6 @@ func init() {
 	if server.Network == constant.NetworkMainnet {
 		types.SetNetworkType(types.Mainnet)
 	}
-	cdc = app.MakeLatestCodec()
-}
-
-func GetCodec() *codec.Codec {
-	return cdc
 }
 
 //
@@ -161,7 +171,7 @@ func ParseCoin(coinStr string) (coin store.Coin) {
 	}
 	denom, amount := matches[2], matches[1]
 
-	amt, err := strconv.ParseFloat(amount, 64)
+	amt, err := store.ParseDec(amount)
 	if err != nil {
 		logger.Error("Convert str to int failed", logger.Any("amount", amount))
 		return coin
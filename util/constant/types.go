@@ -19,10 +19,14 @@ const (
 	TxTypeDeposit                     = "Deposit"
 	TxTypeVote                        = "Vote"
 
-	EnvNameDbAddr     = "DB_ADDR"
-	EnvNameDbUser     = "DB_USER"
-	EnvNameDbPassWd   = "DB_PASSWD"
-	EnvNameDbDataBase = "DB_DATABASE"
+	EnvNameDbAddr        = "DB_ADDR"
+	EnvNameDbUser        = "DB_USER"
+	EnvNameDbPassWd      = "DB_PASSWD"
+	EnvNameDbDataBase    = "DB_DATABASE"
+	EnvNameDbBackend     = "DB_BACKEND"
+	EnvNameDbPgUrl       = "DB_PG_URL"
+	EnvNameDbAutoMigrate = "DB_AUTO_MIGRATE"
+	EnvNameDbArchiveDir  = "DB_ARCHIVE_DIR"
 
 	EnvNameSerNetworkFullNode       = "SER_BC_FULL_NODE"
 	EnvNameSerNetworkChainId        = "SER_BC_CHAIN_ID"
@@ -34,9 +38,15 @@ const (
 	EnvNameCronSaveValidatorHistory = "CRON_SAVE_VALIDATOR_HISTORY"
 	EnvNameWorkerNumCreateTask      = "WORKER_NUM_CREATE_TASK"
 	EnvNameWorkerNumExecuteTask     = "WORKER_NUM_EXECUTE_TASK"
+	EnvNameArchiveKeepBlocks        = "ARCHIVE_KEEP_BLOCKS"
+	EnvNameArchiveKeepDays          = "ARCHIVE_KEEP_DAYS"
+	EnvNameCompactPrecommits        = "COMPACT_PRECOMMITS"
+	EnvNameCodecSchedule            = "CODEC_SCHEDULE"
 
 	EnvNameNetwork = "NETWORK"
 
+	EnvNameChainConfFile = "CHAIN_CONF_FILE"
+
 	EnvLogFileName    = "LOG_FILE_NAME"
 	EnvLogFileMaxSize = "LOG_FILE_MAX_SIZE"
 	EnvLogFileMaxAge  = "LOG_FILE_MAX_AGE"
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
 	"reflect"
 	"strconv"
 	"strings"
@@ -22,6 +23,16 @@ func ParseStrToFloat(s string) (float64, error) {
 	return strconv.ParseFloat(s, 64)
 }
 
+// parse decimal string of chain into exact decimal, return 0 when it's invalid
+func ParseDec(s string) store.Dec {
+	d, err := store.ParseDec(s)
+	if err != nil {
+		logger.Error("common.ParseDec error", logger.String("value", s))
+		return store.Dec{}
+	}
+	return d
+}
+
 func ParseFloat(s string, bit ...int) float64 {
 	f, err := strconv.ParseFloat(s, 64)
 	if err != nil {
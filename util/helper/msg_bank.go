@@ -0,0 +1,22 @@
+package helper
+
+import (
+	"github.com/irisnet/irishub-sync/store/document"
+	itypes "github.com/irisnet/irishub-sync/types"
+	"github.com/irisnet/irishub-sync/util/constant"
+)
+
+func init() {
+	RegisterMsgDecoder(itypes.MsgTransfer{}, MsgDecoder{
+		Type: constant.TxTypeTransfer,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgTransfer)
+
+			docMsg.From = m.Inputs[0].Address.String()
+			docMsg.To = m.Outputs[0].Address.String()
+			docMsg.Amount = itypes.ParseCoins(m.Inputs[0].Coins.String())
+			docMsg.Accounts = []string{docMsg.From, docMsg.To}
+			return
+		},
+	})
+}
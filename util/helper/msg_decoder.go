@@ -0,0 +1,94 @@
+// registry of msg decoders, each module registers decoders of it's msgs in init,
+// so msgs of new module can be parsed without changing ParseTx
+
+package helper
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	itypes "github.com/irisnet/irishub-sync/types"
+)
+
+// decoder of one msg type
+type MsgDecoder struct {
+	// type of msg document, e.g. constant.TxTypeTransfer
+	Type string
+	// parse msg into msg document, accounts which msg touches are set in Accounts of document
+	Decode func(ctx *MsgContext, msg itypes.SdkMsg) document.CommonMsg
+	// rebuild typed msg from content of tx_msg document, it's optional
+	Unmarshal func(content string) store.Msg
+}
+
+// context of msgs in same tx
+type MsgContext struct {
+	TxHash string
+	tags   map[string][]string
+}
+
+var (
+	msgDecoders    = make(map[reflect.Type]MsgDecoder)
+	msgUnmarshaler = make(map[string]func(content string) store.Msg)
+)
+
+func NewMsgContext(txHash string, result itypes.ResponseDeliverTx) *MsgContext {
+	ctx := &MsgContext{
+		TxHash: txHash,
+		tags:   make(map[string][]string),
+	}
+	for _, tag := range result.Tags {
+		key := string(tag.Key)
+		ctx.tags[key] = append(ctx.tags[key], string(tag.Value))
+	}
+	return ctx
+}
+
+// get next value of tag, tags of multiple msgs are appended to tx result in order of msgs,
+// so the nth value of a tag belongs to the nth msg which produce this tag
+func (c *MsgContext) NextTag(key string) (string, bool) {
+	values := c.tags[key]
+	if len(values) == 0 {
+		return "", false
+	}
+	c.tags[key] = values[1:]
+	return values[0], true
+}
+
+// register decoder of msg type, it panics when msg type is registered twice
+func RegisterMsgDecoder(msg itypes.SdkMsg, decoder MsgDecoder) {
+	t := reflect.TypeOf(msg)
+	if _, ok := msgDecoders[t]; ok {
+		panic(fmt.Sprintf("decoder of msg %v is registered twice", t))
+	}
+	if decoder.Type == "" || decoder.Decode == nil {
+		panic(fmt.Sprintf("decoder of msg %v has no type or decode func", t))
+	}
+	if decoder.Unmarshal != nil {
+		if _, ok := msgUnmarshaler[decoder.Type]; ok {
+			panic(fmt.Sprintf("unmarshaler of msg type %v is registered twice", decoder.Type))
+		}
+		msgUnmarshaler[decoder.Type] = decoder.Unmarshal
+	}
+	msgDecoders[t] = decoder
+}
+
+// parse msg with decoder of it's type, return false when msg type isn't registered
+func DecodeMsg(ctx *MsgContext, msg itypes.SdkMsg) (document.CommonMsg, bool) {
+	decoder, ok := msgDecoders[reflect.TypeOf(msg)]
+	if !ok {
+		return document.CommonMsg{}, false
+	}
+	docMsg := decoder.Decode(ctx, msg)
+	docMsg.Type = decoder.Type
+	return docMsg, true
+}
+
+// rebuild typed msg from content of tx_msg document, return nil when msg type has no unmarshaler
+func UnmarshalMsg(txType, content string) store.Msg {
+	if unmarshal, ok := msgUnmarshaler[txType]; ok {
+		return unmarshal(content)
+	}
+	return nil
+}
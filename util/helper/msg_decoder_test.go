@@ -0,0 +1,126 @@
+package helper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	itypes "github.com/irisnet/irishub-sync/types"
+	"github.com/irisnet/irishub-sync/util/constant"
+)
+
+// msg of module which is added in test
+type testMsg struct {
+	itypes.MsgUnjail
+}
+
+func TestRegisterMsgDecoder(t *testing.T) {
+	ctx := NewMsgContext("hash", itypes.ResponseDeliverTx{})
+	if _, ok := DecodeMsg(ctx, testMsg{}); ok {
+		t.Fatal("msg which isn't registered is decoded")
+	}
+
+	RegisterMsgDecoder(testMsg{}, MsgDecoder{
+		Type: "Test",
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) document.CommonMsg {
+			return document.CommonMsg{From: ctx.TxHash, Accounts: []string{"a", "b", "a"}}
+		},
+	})
+	defer delete(msgDecoders, testMsgType())
+
+	docMsg, ok := DecodeMsg(ctx, testMsg{})
+	if !ok || docMsg.Type != "Test" || docMsg.From != "hash" {
+		t.Errorf("DecodeMsg() = %+v, %v", docMsg, ok)
+	}
+	tx := document.CommonTx{Msgs: []document.CommonMsg{docMsg, {Accounts: []string{"c", ""}}}}
+	if got := tx.Accounts(); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("Accounts() = %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("msg which is registered twice doesn't panic")
+		}
+	}()
+	RegisterMsgDecoder(testMsg{}, MsgDecoder{Type: "Test", Decode: msgDecoders[testMsgType()].Decode})
+}
+
+func TestDecodeMsg_tags(t *testing.T) {
+	result := itypes.ResponseDeliverTx{Tags: []itypes.TmKVPair{
+		{Key: []byte(itypes.TagDistributionReward), Value: []byte("1iris")},
+		{Key: []byte(itypes.TagGovProposalID), Value: []byte("7")},
+		{Key: []byte(itypes.TagDistributionReward), Value: []byte("2iris")},
+	}}
+	ctx := NewMsgContext("hash", result)
+
+	msgs := []itypes.SdkMsg{
+		itypes.MsgWithdrawDelegatorReward{},
+		itypes.MsgSubmitProposal{},
+		itypes.MsgWithdrawValidatorRewardsAll{},
+		itypes.MsgWithdrawDelegatorRewardsAll{},
+	}
+	var docMsgs []document.CommonMsg
+	for _, msg := range msgs {
+		docMsg, ok := DecodeMsg(ctx, msg)
+		if !ok {
+			t.Fatalf("decoder of %T isn't registered", msg)
+		}
+		docMsgs = append(docMsgs, docMsg)
+	}
+
+	if docMsgs[0].Type != constant.TxTypeWithdrawDelegatorReward || coinString(docMsgs[0].Amount) != "1iris" {
+		t.Errorf("msg 0 = %+v", docMsgs[0])
+	}
+	if docMsgs[1].Type != constant.TxTypeSubmitProposal || docMsgs[1].ProposalId != 7 {
+		t.Errorf("msg 1 = %+v", docMsgs[1])
+	}
+	if coinString(docMsgs[2].Amount) != "2iris" || len(docMsgs[3].Amount) != 0 {
+		t.Errorf("msg 2 = %+v, msg 3 = %+v", docMsgs[2], docMsgs[3])
+	}
+
+	if UnmarshalMsg(constant.TxTypeVote, itypes.NewVote(itypes.MsgVote{ProposalID: 3}).String()) == nil {
+		t.Error("vote msg isn't rebuilt")
+	}
+	if UnmarshalMsg(constant.TxTypeTransfer, "{}") != nil {
+		t.Error("transfer msg has no unmarshaler")
+	}
+}
+
+func TestDecodeMsg_govTags(t *testing.T) {
+	// proposal id tag is produced by every gov msg in order of msgs
+	result := itypes.ResponseDeliverTx{Tags: []itypes.TmKVPair{
+		{Key: []byte(itypes.TagGovProposalID), Value: []byte("3")},
+		{Key: []byte(itypes.TagGovProposalID), Value: []byte("5")},
+		{Key: []byte(itypes.TagGovProposalID), Value: []byte("8")},
+	}}
+	ctx := NewMsgContext("hash", result)
+
+	msgs := []itypes.SdkMsg{
+		itypes.MsgDeposit{ProposalID: 3},
+		itypes.MsgVote{ProposalID: 5},
+		itypes.MsgSubmitProposal{},
+	}
+	var ids []uint64
+	for _, msg := range msgs {
+		docMsg, ok := DecodeMsg(ctx, msg)
+		if !ok {
+			t.Fatalf("decoder of %T isn't registered", msg)
+		}
+		ids = append(ids, docMsg.ProposalId)
+	}
+	if want := []uint64{3, 5, 8}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("proposal ids = %v, want %v", ids, want)
+	}
+}
+
+func testMsgType() reflect.Type {
+	return reflect.TypeOf(testMsg{})
+}
+
+func coinString(coins store.Coins) string {
+	if len(coins) != 1 {
+		return ""
+	}
+	return coins[0].Amount.String() + coins[0].Denom
+}
@@ -0,0 +1,64 @@
+package helper
+
+import (
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	itypes "github.com/irisnet/irishub-sync/types"
+	"github.com/irisnet/irishub-sync/util/constant"
+)
+
+func init() {
+	RegisterMsgDecoder(itypes.MsgSetWithdrawAddress{}, MsgDecoder{
+		Type: constant.TxTypeSetWithdrawAddress,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgSetWithdrawAddress)
+
+			docMsg.From = m.DelegatorAddr.String()
+			docMsg.To = m.WithdrawAddr.String()
+			docMsg.Msg = itypes.NewSetWithdrawAddressMsg(m)
+			return
+		},
+	})
+	RegisterMsgDecoder(itypes.MsgWithdrawDelegatorReward{}, MsgDecoder{
+		Type: constant.TxTypeWithdrawDelegatorReward,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgWithdrawDelegatorReward)
+
+			docMsg.From = m.DelegatorAddr.String()
+			docMsg.To = m.ValidatorAddr.String()
+			docMsg.Amount = nextReward(ctx)
+			docMsg.Msg = itypes.NewWithdrawDelegatorRewardMsg(m)
+			return
+		},
+	})
+	RegisterMsgDecoder(itypes.MsgWithdrawDelegatorRewardsAll{}, MsgDecoder{
+		Type: constant.TxTypeWithdrawDelegatorRewardsAll,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgWithdrawDelegatorRewardsAll)
+
+			docMsg.From = m.DelegatorAddr.String()
+			docMsg.Amount = nextReward(ctx)
+			docMsg.Msg = itypes.NewWithdrawDelegatorRewardsAllMsg(m)
+			return
+		},
+	})
+	RegisterMsgDecoder(itypes.MsgWithdrawValidatorRewardsAll{}, MsgDecoder{
+		Type: constant.TxTypeWithdrawValidatorRewardsAll,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgWithdrawValidatorRewardsAll)
+
+			docMsg.From = m.ValidatorAddr.String()
+			docMsg.Amount = nextReward(ctx)
+			docMsg.Msg = itypes.NewWithdrawValidatorRewardsAllMsg(m)
+			return
+		},
+	})
+}
+
+// amount of withdraw msg is parsed from reward tag of tx result
+func nextReward(ctx *MsgContext) store.Coins {
+	if reward, ok := ctx.NextTag(itypes.TagDistributionReward); ok {
+		return itypes.ParseCoins(reward)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"strconv"
+
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	itypes "github.com/irisnet/irishub-sync/types"
+	"github.com/irisnet/irishub-sync/util/constant"
+)
+
+func init() {
+	RegisterMsgDecoder(itypes.MsgSubmitProposal{}, MsgDecoder{
+		Type: constant.TxTypeSubmitProposal,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgSubmitProposal)
+
+			docMsg.From = m.Proposer.String()
+			docMsg.Amount = itypes.ParseCoins(m.InitialDeposit.String())
+			docMsg.ProposalId = nextProposalId(ctx)
+			docMsg.Msg = itypes.NewSubmitProposal(m)
+			return
+		},
+	})
+	RegisterMsgDecoder(itypes.MsgSubmitSoftwareUpgradeProposal{}, MsgDecoder{
+		Type: constant.TxTypeSubmitProposal,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgSubmitSoftwareUpgradeProposal)
+
+			docMsg.From = m.Proposer.String()
+			docMsg.Amount = itypes.ParseCoins(m.InitialDeposit.String())
+			docMsg.ProposalId = nextProposalId(ctx)
+			docMsg.Msg = itypes.NewSubmitSoftwareUpgradeProposal(m)
+			return
+		},
+	})
+	RegisterMsgDecoder(itypes.MsgDeposit{}, MsgDecoder{
+		Type: constant.TxTypeDeposit,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgDeposit)
+
+			docMsg.From = m.Depositor.String()
+			docMsg.Amount = itypes.ParseCoins(m.Amount.String())
+			docMsg.ProposalId = m.ProposalID
+			docMsg.Msg = itypes.NewDeposit(m)
+			skipProposalIdTag(ctx)
+			return
+		},
+	})
+	RegisterMsgDecoder(itypes.MsgVote{}, MsgDecoder{
+		Type: constant.TxTypeVote,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgVote)
+
+			docMsg.From = m.Voter.String()
+			docMsg.Amount = []store.Coin{}
+			docMsg.ProposalId = m.ProposalID
+			docMsg.Msg = itypes.NewVote(m)
+			skipProposalIdTag(ctx)
+			return
+		},
+		Unmarshal: func(content string) store.Msg {
+			return itypes.UnmarshalVote(content)
+		},
+	})
+}
+
+// id of submitted proposal is parsed from proposal id tag of tx result
+func nextProposalId(ctx *MsgContext) uint64 {
+	if id, ok := ctx.NextTag(itypes.TagGovProposalID); ok {
+		if proposalId, err := strconv.ParseUint(id, 10, 64); err == nil {
+			return proposalId
+		}
+	}
+	return 0
+}
+
+// deposit and vote msgs produce proposal id tag too, it's consumed
+// so that tags of following submit msgs in same tx belong to them
+func skipProposalIdTag(ctx *MsgContext) {
+	ctx.NextTag(itypes.TagGovProposalID)
+}
@@ -0,0 +1,19 @@
+package helper
+
+import (
+	"github.com/irisnet/irishub-sync/store/document"
+	itypes "github.com/irisnet/irishub-sync/types"
+	"github.com/irisnet/irishub-sync/util/constant"
+)
+
+func init() {
+	RegisterMsgDecoder(itypes.MsgUnjail{}, MsgDecoder{
+		Type: constant.TxTypeUnjail,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgUnjail)
+
+			docMsg.From = m.ValidatorAddr.String()
+			return
+		},
+	})
+}
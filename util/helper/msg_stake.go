@@ -0,0 +1,107 @@
+package helper
+
+import (
+	"github.com/irisnet/irishub-sync/logger"
+	"github.com/irisnet/irishub-sync/store"
+	"github.com/irisnet/irishub-sync/store/document"
+	itypes "github.com/irisnet/irishub-sync/types"
+	"github.com/irisnet/irishub-sync/util/constant"
+)
+
+func init() {
+	RegisterMsgDecoder(itypes.MsgStakeCreate{}, MsgDecoder{
+		Type:   constant.TxTypeStakeCreateValidator,
+		Decode: decodeStakeCreate,
+	})
+	RegisterMsgDecoder(itypes.MsgStakeEdit{}, MsgDecoder{
+		Type:   constant.TxTypeStakeEditValidator,
+		Decode: decodeStakeEdit,
+	})
+	RegisterMsgDecoder(itypes.MsgStakeDelegate{}, MsgDecoder{
+		Type: constant.TxTypeStakeDelegate,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgStakeDelegate)
+
+			docMsg.From = m.DelegatorAddr.String()
+			docMsg.To = m.ValidatorAddr.String()
+			docMsg.Amount = []store.Coin{itypes.ParseCoin(m.Delegation.String())}
+			docMsg.Accounts = []string{docMsg.From, docMsg.To}
+			return
+		},
+	})
+	RegisterMsgDecoder(itypes.MsgStakeBeginUnbonding{}, MsgDecoder{
+		Type: constant.TxTypeStakeBeginUnbonding,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgStakeBeginUnbonding)
+
+			docMsg.From = m.DelegatorAddr.String()
+			docMsg.To = m.ValidatorAddr.String()
+			docMsg.Amount = []store.Coin{{Amount: ParseDec(m.SharesAmount.String())}}
+			docMsg.Accounts = []string{docMsg.From, docMsg.To}
+			return
+		},
+	})
+	RegisterMsgDecoder(itypes.MsgBeginRedelegate{}, MsgDecoder{
+		Type: constant.TxTypeBeginRedelegate,
+		Decode: func(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+			m := msg.(itypes.MsgBeginRedelegate)
+
+			docMsg.From = m.DelegatorAddr.String()
+			docMsg.To = m.ValidatorDstAddr.String()
+			docMsg.Amount = []store.Coin{{Amount: ParseDec(m.SharesAmount.String())}}
+			docMsg.Msg = itypes.NewBeginRedelegate(m)
+			return
+		},
+		Unmarshal: func(content string) store.Msg {
+			return itypes.UnmarshalBeginRedelegate(content)
+		},
+	})
+}
+
+func decodeStakeCreate(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+	m := msg.(itypes.MsgStakeCreate)
+
+	docMsg.From = m.DelegatorAddr.String()
+	docMsg.To = m.ValidatorAddr.String()
+	docMsg.Amount = []store.Coin{itypes.ParseCoin(m.Delegation.String())}
+	docMsg.Accounts = []string{docMsg.From}
+
+	// struct of createValidator
+	valDes := document.ValDescription{
+		Moniker:  m.Moniker,
+		Identity: m.Identity,
+		Website:  m.Website,
+		Details:  m.Details,
+	}
+	pubKey, err := itypes.Bech32ifyValPub(m.PubKey)
+	if err != nil {
+		logger.Error("Can't get pubKey", logger.String("txHash", ctx.TxHash))
+		pubKey = ""
+	}
+	docMsg.StakeCreateValidator = document.StakeCreateValidator{
+		PubKey:      pubKey,
+		Description: valDes,
+	}
+	return
+}
+
+func decodeStakeEdit(ctx *MsgContext, msg itypes.SdkMsg) (docMsg document.CommonMsg) {
+	m := msg.(itypes.MsgStakeEdit)
+
+	docMsg.From = m.ValidatorAddr.String()
+	docMsg.To = ""
+	docMsg.Amount = []store.Coin{}
+	docMsg.Accounts = []string{docMsg.From}
+
+	// struct of editValidator
+	valDes := document.ValDescription{
+		Moniker:  m.Moniker,
+		Identity: m.Identity,
+		Website:  m.Website,
+		Details:  m.Details,
+	}
+	docMsg.StakeEditValidator = document.StakeEditValidator{
+		Description: valDes,
+	}
+	return
+}
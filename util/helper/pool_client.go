@@ -4,6 +4,7 @@
 package helper
 
 import (
+	conf "github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/types"
 	"time"
@@ -15,6 +16,29 @@ type Client struct {
 	Id string
 }
 
+// NodeClient is client of node which is borrowed from Nodes, it should be released after use.
+// it's implemented by *Client, so tests can replace node by stub
+type NodeClient interface {
+	types.Client
+	Release()
+}
+
+// Nodes lends clients of nodes to tasks
+type Nodes interface {
+	GetClient() NodeClient
+}
+
+type poolNodes struct{}
+
+func (poolNodes) GetClient() NodeClient {
+	return GetClient()
+}
+
+// nodes whose clients are borrowed from client pool
+func PoolNodes() Nodes {
+	return poolNodes{}
+}
+
 func newClient(addr string) *Client {
 	return &Client{
 		Client: types.NewHTTP(addr, "/websocket"),
@@ -37,6 +61,14 @@ func GetClient() *Client {
 	return c.(*Client)
 }
 
+// create client which is used to subscribe events of blockchain,
+// client isn't borrowed from pool because subscription should be kept alive
+// and client should be stopped by caller
+func NewEventClient() *Client {
+	endPoint := factory.GetEndPoint()
+	return newClient(endPoint.Address)
+}
+
 // release client
 func (c *Client) Release() {
 	err := pool.ReturnObject(ctx, c)
@@ -47,6 +79,39 @@ func (c *Client) Release() {
 	logger.Debug("debug=======================Release return=======================debug")
 }
 
+// get network of node which client connects to, it should be same as chain id
+func (c *Client) Network() (string, error) {
+	status, err := c.Status()
+	if err != nil {
+		return "", err
+	}
+	return status.NodeInfo.Network, nil
+}
+
+// verify network of every configured node is chain id, unreachable node is skipped.
+// return error when any node belongs to other network or no node is reachable
+func VerifyNodesNetwork(chainId string) error {
+	var verified int
+
+	for _, addr := range conf.BlockChainMonitorUrl {
+		network, err := newClient(addr).Network()
+		if err != nil {
+			logger.Warn("node is unreachable, skip verifying it's network", logger.String("node", addr),
+				logger.String("err", err.Error()))
+			continue
+		}
+		if network != chainId {
+			return fmt.Errorf("network of node %v is %v, mismatch chain id %v", addr, network, chainId)
+		}
+		verified++
+	}
+
+	if verified == 0 {
+		return fmt.Errorf("no node is reachable to verify network")
+	}
+	return nil
+}
+
 func (c *Client) HeartBeat() error {
 	http := c.Client.(*types.HTTP)
 	_, err := http.Health()
@@ -2,6 +2,7 @@ package helper
 
 import (
 	"context"
+	"fmt"
 	conf "github.com/irisnet/irishub-sync/conf/server"
 	"github.com/irisnet/irishub-sync/logger"
 	gcp "github.com/jolestar/go-commons-pool"
@@ -72,7 +73,23 @@ func ClosePool() {
 func (f *PoolFactory) MakeObject(ctx context.Context) (*gcp.PooledObject, error) {
 	endpoint := f.GetEndPoint()
 	logger.Debug("PoolFactory MakeObject peer", logger.Any("endpoint", endpoint))
-	return gcp.NewPooledObject(newClient(endpoint.Address)), nil
+	c := newClient(endpoint.Address)
+
+	// connection to node of other network is never created,
+	// and the node won't be selected again
+	network, err := c.Network()
+	if err != nil {
+		return nil, err
+	}
+	if network != conf.ChainId {
+		endpoint.Available = false
+		f.peersMap.Store(c.Id, endpoint)
+		logger.Error("network of node mismatch chain id", logger.String("node", endpoint.Address),
+			logger.String("network", network), logger.String("chain_id", conf.ChainId))
+		return nil, fmt.Errorf("network of node %v is %v, mismatch chain id %v", endpoint.Address, network, conf.ChainId)
+	}
+
+	return gcp.NewPooledObject(c), nil
 }
 
 func (f *PoolFactory) DestroyObject(ctx context.Context, object *gcp.PooledObject) error {
@@ -4,66 +4,69 @@ package helper
 
 import (
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/irisnet/irishub-sync/logger"
 	"github.com/irisnet/irishub-sync/store"
 	"github.com/irisnet/irishub-sync/store/document"
 	itypes "github.com/irisnet/irishub-sync/types"
-	"github.com/irisnet/irishub-sync/util/constant"
-	"strconv"
 	"strings"
+	"time"
 )
 
-func ParseTx(txBytes itypes.Tx, block *itypes.Block) document.CommonTx {
+// parse tx with it's result which is in block results at same index of tx in block,
+// error is returned when tx or any msg of tx can't be decoded by current codec
+func ParseTx(txBytes itypes.Tx, block *itypes.Block, result itypes.ResponseDeliverTx) (document.CommonTx, error) {
+	return parseTx(txBytes, block.Height, block.Time, result)
+}
+
+func parseTx(txBytes itypes.Tx, height int64, txTime time.Time, result itypes.ResponseDeliverTx) (document.CommonTx, error) {
 	var (
-		authTx     itypes.StdTx
-		methodName = "ParseTx"
-		docTx      document.CommonTx
-		gasPrice   float64
-		actualFee  store.ActualFee
+		authTx    itypes.StdTx
+		docTx     document.CommonTx
+		gasPrice  store.Dec
+		actualFee store.ActualFee
 	)
 
-	cdc := itypes.GetCodec()
+	// tx is decoded by codec which is used at height of tx
+	cdc := itypes.GetCodecAt(height)
 
 	err := cdc.UnmarshalBinaryLengthPrefixed(txBytes, &authTx)
 	if err != nil {
-		logger.Error(err.Error())
-		return docTx
+		return docTx, err
 	}
 
-	height := block.Height
-	time := block.Time
 	txHash := BuildHex(txBytes.Hash())
 	fee := itypes.BuildFee(authTx.Fee)
 	memo := authTx.Memo
 
 	// get tx status, gasUsed, gasPrice and actualFee from tx result
-	status, result, err := QueryTxResult(txBytes.Hash())
-	if err != nil {
-		logger.Error("get txResult err", logger.String("method", methodName), logger.String("err", err.Error()))
+	status := document.TxStatusSuccess
+	if result.Code != 0 {
+		status = document.TxStatusFail
 	}
 	log := result.Log
 	gasUsed := Min(result.GasUsed, fee.Gas)
-	if len(fee.Amount) > 0 {
-		gasPrice = fee.Amount[0].Amount / float64(fee.Gas)
+	if len(fee.Amount) > 0 && fee.Gas > 0 {
+		gasPrice = fee.Amount[0].Amount.QuoInt64(fee.Gas)
+		// actual fee is computed from fee amount instead of rounded gas price, so it's exact
 		actualFee = store.ActualFee{
 			Denom:  fee.Amount[0].Denom,
-			Amount: float64(gasUsed) * gasPrice,
+			Amount: fee.Amount[0].Amount.MulInt64(gasUsed).QuoInt64(fee.Gas),
 		}
 	} else {
-		gasPrice = 0
+		gasPrice = store.Dec{}
 		actualFee = store.ActualFee{}
 	}
 
 	msgs := authTx.GetMsgs()
 	if len(msgs) <= 0 {
-		logger.Error("can't get msgs", logger.String("method", methodName))
-		return docTx
+		return docTx, errors.New("can't get msgs")
 	}
-	msg := msgs[0]
 
 	docTx = document.CommonTx{
 		Height:    height,
-		Time:      time,
+		Time:      txTime,
 		TxHash:    txHash,
 		Fee:       fee,
 		Memo:      memo,
@@ -74,220 +77,97 @@ func ParseTx(txBytes itypes.Tx, block *itypes.Block) document.CommonTx {
 		GasPrice:  gasPrice,
 		ActualFee: actualFee,
 		Tags:      parseTags(result),
+		Signers:   parseSigners(authTx.Signatures),
 	}
 
-	switch msg.(type) {
-	case itypes.MsgTransfer:
-		msg := msg.(itypes.MsgTransfer)
-
-		docTx.From = msg.Inputs[0].Address.String()
-		docTx.To = msg.Outputs[0].Address.String()
-		docTx.Amount = itypes.ParseCoins(msg.Inputs[0].Coins.String())
-		docTx.Type = constant.TxTypeTransfer
-		return docTx
-	case itypes.MsgStakeCreate:
-		msg := msg.(itypes.MsgStakeCreate)
-
-		docTx.From = msg.DelegatorAddr.String()
-		docTx.To = msg.ValidatorAddr.String()
-		docTx.Amount = []store.Coin{itypes.ParseCoin(msg.Delegation.String())}
-		docTx.Type = constant.TxTypeStakeCreateValidator
-
-		// struct of createValidator
-		valDes := document.ValDescription{
-			Moniker:  msg.Moniker,
-			Identity: msg.Identity,
-			Website:  msg.Website,
-			Details:  msg.Details,
-		}
-		pubKey, err := itypes.Bech32ifyValPub(msg.PubKey)
-		if err != nil {
-			logger.Error("Can't get pubKey", logger.String("txHash", txHash))
-			pubKey = ""
-		}
-		docTx.StakeCreateValidator = document.StakeCreateValidator{
-			PubKey:      pubKey,
-			Description: valDes,
-		}
-
-		return docTx
-	case itypes.MsgStakeEdit:
-		msg := msg.(itypes.MsgStakeEdit)
-
-		docTx.From = msg.ValidatorAddr.String()
-		docTx.To = ""
-		docTx.Amount = []store.Coin{}
-		docTx.Type = constant.TxTypeStakeEditValidator
-
-		// struct of editValidator
-		valDes := document.ValDescription{
-			Moniker:  msg.Moniker,
-			Identity: msg.Identity,
-			Website:  msg.Website,
-			Details:  msg.Details,
-		}
-		docTx.StakeEditValidator = document.StakeEditValidator{
-			Description: valDes,
-		}
-
-		return docTx
-	case itypes.MsgStakeDelegate:
-		msg := msg.(itypes.MsgStakeDelegate)
-
-		docTx.From = msg.DelegatorAddr.String()
-		docTx.To = msg.ValidatorAddr.String()
-		docTx.Amount = []store.Coin{itypes.ParseCoin(msg.Delegation.String())}
-		docTx.Type = constant.TxTypeStakeDelegate
-
-		return docTx
-	case itypes.MsgStakeBeginUnbonding:
-		msg := msg.(itypes.MsgStakeBeginUnbonding)
-
-		shares := ParseFloat(msg.SharesAmount.String())
-		docTx.From = msg.DelegatorAddr.String()
-		docTx.To = msg.ValidatorAddr.String()
-
-		coin := store.Coin{
-			Amount: shares,
-		}
-		docTx.Amount = []store.Coin{coin}
-		docTx.Type = constant.TxTypeStakeBeginUnbonding
-		return docTx
-	case itypes.MsgBeginRedelegate:
-		msg := msg.(itypes.MsgBeginRedelegate)
-
-		shares := ParseFloat(msg.SharesAmount.String())
-		docTx.From = msg.DelegatorAddr.String()
-		docTx.To = msg.ValidatorDstAddr.String()
-		coin := store.Coin{
-			Amount: shares,
+	// msgs are parsed by decoders which are registered by modules
+	ctx := NewMsgContext(txHash, result)
+	for i, msg := range msgs {
+		docMsg, ok := DecodeMsg(ctx, msg)
+		if !ok {
+			return document.CommonTx{}, fmt.Errorf("unknown msg type %T at index %v", msg, i)
 		}
-		docTx.Amount = []store.Coin{coin}
-		docTx.Type = constant.TxTypeBeginRedelegate
-		docTx.Msg = itypes.NewBeginRedelegate(msg)
-		return docTx
-	case itypes.MsgUnjail:
-		msg := msg.(itypes.MsgUnjail)
+		docMsg.Index = i
 
-		docTx.From = msg.ValidatorAddr.String()
-		docTx.Type = constant.TxTypeUnjail
-	case itypes.MsgSetWithdrawAddress:
-		msg := msg.(itypes.MsgSetWithdrawAddress)
-
-		docTx.From = msg.DelegatorAddr.String()
-		docTx.To = msg.WithdrawAddr.String()
-		docTx.Type = constant.TxTypeSetWithdrawAddress
-		docTx.Msg = itypes.NewSetWithdrawAddressMsg(msg)
-	case itypes.MsgWithdrawDelegatorReward:
-		msg := msg.(itypes.MsgWithdrawDelegatorReward)
-
-		docTx.From = msg.DelegatorAddr.String()
-		docTx.To = msg.ValidatorAddr.String()
-		docTx.Type = constant.TxTypeWithdrawDelegatorReward
-		docTx.Msg = itypes.NewWithdrawDelegatorRewardMsg(msg)
-
-		for _, tag := range result.Tags {
-			key := string(tag.Key)
-			if key == itypes.TagDistributionReward {
-				reward := string(tag.Value)
-				docTx.Amount = itypes.ParseCoins(reward)
-				break
-			}
-		}
-	case itypes.MsgWithdrawDelegatorRewardsAll:
-		msg := msg.(itypes.MsgWithdrawDelegatorRewardsAll)
-
-		docTx.From = msg.DelegatorAddr.String()
-		docTx.Type = constant.TxTypeWithdrawDelegatorRewardsAll
-		docTx.Msg = itypes.NewWithdrawDelegatorRewardsAllMsg(msg)
-		for _, tag := range result.Tags {
-			key := string(tag.Key)
-			if key == itypes.TagDistributionReward {
-				reward := string(tag.Value)
-				docTx.Amount = itypes.ParseCoins(reward)
-				break
-			}
-		}
-	case itypes.MsgWithdrawValidatorRewardsAll:
-		msg := msg.(itypes.MsgWithdrawValidatorRewardsAll)
-
-		docTx.From = msg.ValidatorAddr.String()
-		docTx.Type = constant.TxTypeWithdrawValidatorRewardsAll
-		docTx.Msg = itypes.NewWithdrawValidatorRewardsAllMsg(msg)
-		for _, tag := range result.Tags {
-			key := string(tag.Key)
-			if key == itypes.TagDistributionReward {
-				reward := string(tag.Value)
-				docTx.Amount = itypes.ParseCoins(reward)
-				break
-			}
-		}
-	case itypes.MsgSubmitProposal:
-		msg := msg.(itypes.MsgSubmitProposal)
+		docTx.Msgs = append(docTx.Msgs, docMsg)
+	}
 
-		docTx.From = msg.Proposer.String()
-		docTx.To = ""
-		docTx.Amount = itypes.ParseCoins(msg.InitialDeposit.String())
-		docTx.Type = constant.TxTypeSubmitProposal
-		docTx.Msg = itypes.NewSubmitProposal(msg)
+	// fields of first msg are kept in tx for compatibility
+	firstMsg := docTx.Msgs[0]
+	docTx.Type = firstMsg.Type
+	docTx.From = firstMsg.From
+	docTx.To = firstMsg.To
+	docTx.Amount = firstMsg.Amount
+	docTx.ProposalId = firstMsg.ProposalId
+	docTx.StakeCreateValidator = firstMsg.StakeCreateValidator
+	docTx.StakeEditValidator = firstMsg.StakeEditValidator
+	docTx.Msg = firstMsg.Msg
+
+	return docTx, nil
+}
 
-		//query proposal_id
-		for _, tag := range result.Tags {
-			key := string(tag.Key)
-			if key == itypes.TagGovProposalID {
-				proposalId, err := strconv.ParseInt(string(tag.Value), 10, 0)
-				if err == nil {
-					docTx.ProposalId = uint64(proposalId)
-					break
-				}
-			}
+// parse signers from signatures of tx, signers are in same order as signatures
+func parseSigners(signatures []itypes.StdSignature) []document.Signer {
+	var signers []document.Signer
+	for _, sig := range signatures {
+		signer := document.Signer{
+			Sequence:      sig.Sequence,
+			AccountNumber: sig.AccountNumber,
 		}
-		return docTx
-	case itypes.MsgSubmitSoftwareUpgradeProposal:
-		msg := msg.(itypes.MsgSubmitSoftwareUpgradeProposal)
-
-		docTx.From = msg.Proposer.String()
-		docTx.To = ""
-		docTx.Amount = itypes.ParseCoins(msg.InitialDeposit.String())
-		docTx.Type = constant.TxTypeSubmitProposal
-		docTx.Msg = itypes.NewSubmitSoftwareUpgradeProposal(msg)
-
-		//query proposal_id
-		for _, tag := range result.Tags {
-			key := string(tag.Key)
-			if key == itypes.TagGovProposalID {
-				proposalId, err := strconv.ParseInt(string(tag.Value), 10, 0)
-				if err == nil {
-					docTx.ProposalId = uint64(proposalId)
-					break
+		if sig.PubKey != nil {
+			signer.Address = itypes.AccAddress(sig.PubKey.Address()).String()
+			signer.PubKey = bech32ifyAccPub(sig.PubKey)
+			if multisig, ok := sig.PubKey.(itypes.PubKeyMultisigThreshold); ok {
+				signer.MultisigThreshold = multisig.K
+				for _, pubKey := range multisig.PubKeys {
+					signer.MultisigPubKeys = append(signer.MultisigPubKeys, bech32ifyAccPub(pubKey))
 				}
 			}
 		}
-		return docTx
-	case itypes.MsgDeposit:
-		msg := msg.(itypes.MsgDeposit)
-
-		docTx.From = msg.Depositor.String()
-		docTx.Amount = itypes.ParseCoins(msg.Amount.String())
-		docTx.Type = constant.TxTypeDeposit
-		docTx.Msg = itypes.NewDeposit(msg)
-		docTx.ProposalId = msg.ProposalID
-		return docTx
-	case itypes.MsgVote:
-		msg := msg.(itypes.MsgVote)
+		signers = append(signers, signer)
+	}
+	return signers
+}
 
-		docTx.From = msg.Voter.String()
-		docTx.Amount = []store.Coin{}
-		docTx.Type = constant.TxTypeVote
-		docTx.Msg = itypes.NewVote(msg)
-		docTx.ProposalId = msg.ProposalID
-		return docTx
+func bech32ifyAccPub(pubKey itypes.PubKey) string {
+	pub, err := itypes.Bech32ifyAccPub(pubKey)
+	if err != nil {
+		logger.Error("Can't get pubKey", logger.String("err", err.Error()))
+		return ""
+	}
+	return pub
+}
 
-	default:
-		logger.Warn("unknown msg type")
+// build undecoded tx document which keeps raw bytes and result of tx
+func BuildUndecodedTx(txBytes itypes.Tx, block *itypes.Block, result itypes.ResponseDeliverTx, err error) document.UndecodedTx {
+	var tags []document.KvPair
+	for _, tag := range result.Tags {
+		tags = append(tags, document.KvPair{Key: string(tag.Key), Value: string(tag.Value)})
 	}
+	return document.UndecodedTx{
+		TxHash:  BuildHex(txBytes.Hash()),
+		Height:  block.Height,
+		Time:    block.Time,
+		Tx:      txBytes,
+		Code:    result.Code,
+		Log:     result.Log,
+		GasUsed: result.GasUsed,
+		Tags:    tags,
+		Error:   err.Error(),
+	}
+}
 
-	return docTx
+// decode undecoded tx again with current codec
+func ParseUndecodedTx(tx document.UndecodedTx) (document.CommonTx, error) {
+	result := itypes.ResponseDeliverTx{
+		Code:    tx.Code,
+		Log:     tx.Log,
+		GasUsed: tx.GasUsed,
+	}
+	for _, tag := range tx.Tags {
+		result.Tags = append(result.Tags, itypes.TmKVPair{Key: []byte(tag.Key), Value: []byte(tag.Value)})
+	}
+	return parseTx(tx.Tx, tx.Height, tx.Time, result)
 }
 
 func parseTags(result itypes.ResponseDeliverTx) map[string]string {
@@ -302,23 +182,3 @@ func parseTags(result itypes.ResponseDeliverTx) map[string]string {
 func BuildHex(bytes []byte) string {
 	return strings.ToUpper(hex.EncodeToString(bytes))
 }
-
-// get tx status and log by query txHash
-func QueryTxResult(txHash []byte) (string, itypes.ResponseDeliverTx, error) {
-	var resDeliverTx itypes.ResponseDeliverTx
-	status := document.TxStatusSuccess
-
-	client := GetClient()
-	defer client.Release()
-
-	res, err := client.Tx(txHash, false)
-	if err != nil {
-		return "unknown", resDeliverTx, err
-	}
-	result := res.TxResult
-	if result.Code != 0 {
-		status = document.TxStatusFail
-	}
-
-	return status, result, nil
-}
@@ -28,9 +28,17 @@ func TestParseTx(t *testing.T) {
 		logger.Panic(err.Error())
 	}
 
+	blockResults, err := client.Client.BlockResults(&height)
+	if err != nil {
+		logger.Panic(err.Error())
+	}
+
 	if block.BlockMeta.Header.NumTxs > 0 {
 		txs := block.Block.Data.Txs
-		tx := ParseTx(txs[0], block.Block)
+		tx, err := ParseTx(txs[0], block.Block, *blockResults.Results.DeliverTx[0])
+		if err != nil {
+			t.Fatal(err)
+		}
 		fmt.Println(tx)
 	}
 